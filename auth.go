@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+)
+
+// Token scopes. A read token may only call tools that do not modify the
+// knowledge base; a write token may call every tool.
+const (
+	scopeRead  = "read"
+	scopeWrite = "write"
+)
+
+// loadTokens returns the tokens configured inline in a together with those
+// listed in its key file. Each key file line holds a token, optionally
+// followed by a scope and a name; blank lines and lines starting with # are
+// ignored. Token names must be unique, including the generated "token-N"
+// names of unnamed tokens.
+func loadTokens(a AuthConfig) ([]TokenConfig, error) {
+	tokens := append([]TokenConfig(nil), a.Tokens...)
+
+	if a.KeyFile != "" {
+		f, err := os.Open(a.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open key file: %w", err)
+		}
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for line := 1; scanner.Scan(); line++ {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+				continue
+			}
+			if len(fields) > 3 {
+				return nil, fmt.Errorf("%s:%d: expected \"<token> [scope] [name]\"", a.KeyFile, line)
+			}
+			t := TokenConfig{Token: fields[0]}
+			if len(fields) > 1 {
+				t.Scope = fields[1]
+			}
+			if len(fields) > 2 {
+				t.Name = fields[2]
+			}
+			tokens = append(tokens, t)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+	}
+
+	names := make(map[string]int)
+	for i := range tokens {
+		t := &tokens[i]
+		if t.Token == "" {
+			return nil, fmt.Errorf("token %d has no value", i+1)
+		}
+		switch t.Scope {
+		case "":
+			t.Scope = scopeRead
+		case scopeRead, scopeWrite:
+		default:
+			return nil, fmt.Errorf("token %d has unknown scope %q (use %s or %s)", i+1, t.Scope, scopeRead, scopeWrite)
+		}
+		if t.Name == "" {
+			t.Name = fmt.Sprintf("token-%d", i+1)
+		}
+		if j, ok := names[t.Name]; ok {
+			return nil, fmt.Errorf("tokens %d and %d have the same name %q", j, i+1, t.Name)
+		}
+		names[t.Name] = i + 1
+	}
+	return tokens, nil
+}
+
+// newTokenVerifier returns an auth.TokenVerifier that accepts exactly the given
+// tokens. Write tokens are granted both the read and write scopes. It returns
+// nil when tokens is empty, meaning authentication is disabled.
+//
+// The SDK ties each streamable HTTP session to the UserID of the token that
+// opened it, so the UserID is a hash of the token itself rather than its name:
+// another token can never pass for it, whatever the configuration.
+func newTokenVerifier(tokens []TokenConfig) auth.TokenVerifier {
+	if len(tokens) == 0 {
+		return nil
+	}
+	return func(ctx context.Context, token string, req *http.Request) (*auth.TokenInfo, error) {
+		var match *TokenConfig
+		for i := range tokens {
+			// Compare against every token so timing does not reveal which matched.
+			if subtle.ConstantTimeCompare([]byte(tokens[i].Token), []byte(token)) == 1 {
+				match = &tokens[i]
+			}
+		}
+		if match == nil {
+			return nil, fmt.Errorf("%w: unknown token", auth.ErrInvalidToken)
+		}
+
+		scopes := []string{scopeRead}
+		if match.Scope == scopeWrite {
+			scopes = append(scopes, scopeWrite)
+		}
+		return &auth.TokenInfo{
+			Scopes: scopes,
+			// Static tokens do not expire, but the SDK requires an expiration.
+			Expiration: time.Now().Add(24 * time.Hour),
+			UserID:     tokenUserID(match.Token),
+		}, nil
+	}
+}
+
+// tokenUserID returns the hex SHA-256 of token, identifying its holder
+// without revealing it.
+func tokenUserID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// checkOrigin wraps handler so that browser requests whose Origin header is
+// not in allowed are rejected with 403 Forbidden, protecting local servers
+// from DNS rebinding. Requests without an Origin header, such as those from
+// non-browser MCP clients, are always accepted. An empty list or an entry of
+// "*" allows every origin.
+func checkOrigin(allowed []string, handler http.Handler) http.Handler {
+	if len(allowed) == 0 || slices.Contains(allowed, "*") {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if origin := r.Header.Get("Origin"); origin != "" && !slices.Contains(allowed, origin) {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
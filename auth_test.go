@@ -0,0 +1,316 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/auth"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+func TestLoadTokens_Inline(t *testing.T) {
+	tokens, err := loadTokens(AuthConfig{Tokens: []TokenConfig{
+		{Token: "abc"},
+		{Name: "ci", Token: "def", Scope: "write"},
+	}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("expected 2 tokens, got %d", len(tokens))
+	}
+	if tokens[0].Scope != scopeRead {
+		t.Errorf("default scope = %q, want %q", tokens[0].Scope, scopeRead)
+	}
+	if tokens[0].Name == "" {
+		t.Error("expected a generated name for unnamed token")
+	}
+	if tokens[1].Name != "ci" || tokens[1].Scope != scopeWrite {
+		t.Errorf("unexpected second token: %+v", tokens[1])
+	}
+}
+
+func TestLoadTokens_KeyFile(t *testing.T) {
+	keyFile := filepath.Join(t.TempDir(), "keys")
+	content := "# comment\n\nreadtoken\nwritetoken write deploy\n"
+	if err := os.WriteFile(keyFile, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens, err := loadTokens(AuthConfig{KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tokens) != 2 {
+		t.Fatalf("expected 2 tokens, got %d", len(tokens))
+	}
+	if tokens[0].Token != "readtoken" || tokens[0].Scope != scopeRead {
+		t.Errorf("unexpected first token: %+v", tokens[0])
+	}
+	if tokens[1].Token != "writetoken" || tokens[1].Scope != scopeWrite || tokens[1].Name != "deploy" {
+		t.Errorf("unexpected second token: %+v", tokens[1])
+	}
+}
+
+func TestLoadTokens_Errors(t *testing.T) {
+	if _, err := loadTokens(AuthConfig{Tokens: []TokenConfig{{Token: "x", Scope: "admin"}}}); err == nil {
+		t.Error("expected error for unknown scope")
+	}
+	if _, err := loadTokens(AuthConfig{Tokens: []TokenConfig{{Name: "empty"}}}); err == nil {
+		t.Error("expected error for empty token")
+	}
+	if _, err := loadTokens(AuthConfig{KeyFile: "/nonexistent/ydrag-keys"}); err == nil {
+		t.Error("expected error for missing key file")
+	}
+	if _, err := loadTokens(AuthConfig{Tokens: []TokenConfig{{Name: "ci", Token: "a"}, {Name: "ci", Token: "b", Scope: "write"}}}); err == nil {
+		t.Error("expected error for duplicate names")
+	}
+	if _, err := loadTokens(AuthConfig{Tokens: []TokenConfig{{Token: "a"}, {Name: "token-1", Token: "b"}}}); err == nil {
+		t.Error("expected error for a name clashing with a generated one")
+	}
+}
+
+func TestNewTokenVerifier(t *testing.T) {
+	if newTokenVerifier(nil) != nil {
+		t.Fatal("expected nil verifier when no tokens are configured")
+	}
+
+	verify := newTokenVerifier([]TokenConfig{
+		{Name: "reader", Token: "r", Scope: scopeRead},
+		{Name: "writer", Token: "w", Scope: scopeWrite},
+	})
+
+	info, err := verify(context.Background(), "r", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info.UserID != tokenUserID("r") || slices.Contains(info.Scopes, scopeWrite) {
+		t.Errorf("unexpected read token info: %+v", info)
+	}
+
+	info, err = verify(context.Background(), "w", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !slices.Contains(info.Scopes, scopeRead) || !slices.Contains(info.Scopes, scopeWrite) {
+		t.Errorf("expected write token to have read and write scopes, got %v", info.Scopes)
+	}
+	if info.UserID == tokenUserID("r") || strings.Contains(info.UserID, "writer") {
+		t.Errorf("write token UserID = %q, want a hash of the token", info.UserID)
+	}
+
+	if _, err := verify(context.Background(), "bogus", nil); !errors.Is(err, auth.ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+// bearerTransport adds an Authorization header to every request.
+type bearerTransport struct {
+	token string
+}
+
+func (b bearerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+b.token)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+// newAuthTestServer starts a streamable HTTP server with one read and one write token.
+func newAuthTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	m := NewMCPServer(nil)
+	m.auth = AuthConfig{Tokens: []TokenConfig{
+		{Token: "read-token", Scope: scopeRead},
+		{Token: "write-token", Scope: scopeWrite},
+	}}
+	handler, err := m.httpHandler("streamable-http")
+	if err != nil {
+		t.Fatalf("httpHandler failed: %v", err)
+	}
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+// listToolNames connects to url with token and returns the names of the tools offered.
+func listToolNames(t *testing.T, url, token string) []string {
+	t.Helper()
+	ctx := context.Background()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	session, err := client.Connect(ctx, &mcp.StreamableClientTransport{
+		Endpoint:   url,
+		HTTPClient: &http.Client{Transport: bearerTransport{token: token}},
+		MaxRetries: -1,
+	}, nil)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	defer session.Close()
+
+	res, err := session.ListTools(ctx, nil)
+	if err != nil {
+		t.Fatalf("ListTools failed: %v", err)
+	}
+	var names []string
+	for _, tool := range res.Tools {
+		names = append(names, tool.Name)
+	}
+	return names
+}
+
+func TestAuth_RejectsMissingToken(t *testing.T) {
+	ts := newAuthTestServer(t)
+
+	resp, err := http.Post(ts.URL, "application/json", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL, nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+}
+
+func TestAuth_ToolScopes(t *testing.T) {
+	ts := newAuthTestServer(t)
+
+	readTools := listToolNames(t, ts.URL, "read-token")
+	for _, name := range []string{"query_documents", "find_similar", "list_documents", "kb_stats"} {
+		if !slices.Contains(readTools, name) {
+			t.Errorf("read token missing tool %q (got %v)", name, readTools)
+		}
+	}
+	for _, name := range []string{"add_document", "add_file", "delete_document"} {
+		if slices.Contains(readTools, name) {
+			t.Errorf("read token should not see tool %q", name)
+		}
+	}
+
+	writeTools := listToolNames(t, ts.URL, "write-token")
+	for _, name := range []string{"query_documents", "find_similar", "list_documents", "kb_stats", "add_document", "add_file", "delete_document"} {
+		if !slices.Contains(writeTools, name) {
+			t.Errorf("write token missing tool %q (got %v)", name, writeTools)
+		}
+	}
+}
+
+func TestAuth_SessionBoundToToken(t *testing.T) {
+	// Build the handler without loadTokens, which would reject the clashing
+	// names, to check that sessions do not rest on token names.
+	m := NewMCPServer(nil)
+	tokens := []TokenConfig{
+		{Name: "shared", Token: "read-token", Scope: scopeRead},
+		{Name: "shared", Token: "write-token", Scope: scopeWrite},
+	}
+	ts := httptest.NewServer(authenticate(tokens)(mcp.NewStreamableHTTPHandler(m.serverFor, nil)))
+	t.Cleanup(ts.Close)
+
+	post := func(token, sessionID, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPost, ts.URL, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/json, text/event-stream")
+		req.Header.Set("Authorization", "Bearer "+token)
+		if sessionID != "" {
+			req.Header.Set("Mcp-Session-Id", sessionID)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := post("write-token", "", `{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {"protocolVersion": "2025-06-18", "capabilities": {}, "clientInfo": {"name": "test", "version": "1.0"}}}`)
+	sessionID := resp.Header.Get("Mcp-Session-Id")
+	if resp.StatusCode != http.StatusOK || sessionID == "" {
+		t.Fatalf("initialize: status %d, session %q", resp.StatusCode, sessionID)
+	}
+
+	resp = post("read-token", sessionID, `{"jsonrpc": "2.0", "id": 2, "method": "tools/call", "params": {"name": "delete_document", "arguments": {"id": "doc1"}}}`)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("read token on a write session: status %d, want %d", resp.StatusCode, http.StatusForbidden)
+	}
+}
+
+func TestAuth_RejectsSSE(t *testing.T) {
+	m := NewMCPServer(nil)
+	m.auth = AuthConfig{Tokens: []TokenConfig{{Token: "read-token", Scope: scopeRead}}}
+	if _, err := m.httpHandler("sse"); err == nil || !strings.Contains(err.Error(), "streamable-http") {
+		t.Errorf("httpHandler(sse) with tokens: err = %v", err)
+	}
+
+	m.auth = AuthConfig{}
+	if _, err := m.httpHandler("sse"); err != nil {
+		t.Errorf("httpHandler(sse) without tokens failed: %v", err)
+	}
+}
+
+func TestCheckOrigin(t *testing.T) {
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	handler := checkOrigin([]string{"http://allowed.example"}, ok)
+
+	tests := []struct {
+		origin string
+		want   int
+	}{
+		{"", http.StatusOK},
+		{"http://allowed.example", http.StatusOK},
+		{"http://evil.example", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != tt.want {
+			t.Errorf("Origin %q: status = %d, want %d", tt.origin, rec.Code, tt.want)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	req.Header.Set("Origin", "http://evil.example")
+	rec := httptest.NewRecorder()
+	checkOrigin([]string{"*"}, ok).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Errorf("wildcard: status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestReadOnlyServer(t *testing.T) {
+	m := NewMCPServer(nil)
+	m.readOnly = true
+	m.auth = AuthConfig{Tokens: []TokenConfig{{Token: "write-token", Scope: scopeWrite}}}
+	handler, err := m.httpHandler("streamable-http")
+	if err != nil {
+		t.Fatalf("httpHandler failed: %v", err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	tools := listToolNames(t, ts.URL, "write-token")
+	if slices.Contains(tools, "add_document") || slices.Contains(tools, "delete_document") {
+		t.Errorf("read-only server exposed write tools: %v", tools)
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"math/rand"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// BenchLatency summarizes a set of timings in milliseconds.
+type BenchLatency struct {
+	Mean float64 `json:"mean_ms"`
+	P50  float64 `json:"p50_ms"`
+	P90  float64 `json:"p90_ms"`
+	P99  float64 `json:"p99_ms"`
+	Max  float64 `json:"max_ms"`
+}
+
+// BenchEmbedding reports embedding throughput over the whole corpus and the
+// latency of embedding each query.
+type BenchEmbedding struct {
+	Documents     int          `json:"documents"`
+	Bytes         int          `json:"bytes"`
+	Seconds       float64      `json:"seconds"`
+	DocsPerSecond float64      `json:"docs_per_second"`
+	Latency       BenchLatency `json:"latency"`
+	QueryLatency  BenchLatency `json:"query_latency"`
+}
+
+// BenchRun reports insert throughput and search latency for one corpus size,
+// with or without a vector index.
+type BenchRun struct {
+	Size          int          `json:"size"`
+	Index         string       `json:"index"` // "none" or "hnsw"
+	InsertSeconds float64      `json:"insert_seconds"`
+	InsertsPerSec float64      `json:"inserts_per_second"`
+	IndexSeconds  float64      `json:"index_seconds,omitempty"`
+	Queries       int          `json:"queries"`
+	SearchLatency BenchLatency `json:"search_latency"`
+	QueriesPerSec float64      `json:"queries_per_second"`
+}
+
+// BenchReport is the result of a bench run.
+type BenchReport struct {
+	Settings     RunSettings    `json:"settings"`
+	Corpus       string         `json:"corpus"`
+	EmbeddingDim int            `json:"embedding_dim"`
+	TopK         int            `json:"top_k"`
+	Embedding    BenchEmbedding `json:"embedding"`
+	Runs         []BenchRun     `json:"runs"`
+	IndexError   string         `json:"index_error,omitempty"`
+}
+
+// benchWords is the vocabulary of the synthetic corpus.
+var benchWords = strings.Fields(`
+	system data model query index vector search document storage network
+	memory process thread server client request response cache latency
+	throughput database table column row schema transaction commit log
+	file disk block page buffer stream batch token embedding context layer
+	weight tensor matrix gradient training inference accuracy recall metric
+	report user account session permission policy security certificate key
+	cluster node replica shard partition leader follower consensus quorum
+	deploy release version build test coverage benchmark profile trace span
+	error failure retry timeout backoff limit quota budget cost price invoice
+	customer order product catalog inventory warehouse shipment delivery route
+	river mountain forest ocean desert island valley city village harbor
+	morning evening winter summer spring autumn weather storm rain snow
+`)
+
+// syntheticCorpus returns n pseudo-random documents of about words words each,
+// generated deterministically from seed.
+func syntheticCorpus(n, words int, seed int64) []string {
+	rng := rand.New(rand.NewSource(seed))
+	docs := make([]string, n)
+	for i := range docs {
+		w := make([]string, words)
+		for j := range w {
+			w[j] = benchWords[rng.Intn(len(benchWords))]
+		}
+		docs[i] = strings.Join(w, " ")
+	}
+	return docs
+}
+
+// syntheticQueries returns n short queries drawn from the synthetic vocabulary.
+func syntheticQueries(n int, seed int64) []string {
+	return syntheticCorpus(n, 6, seed+1)
+}
+
+// loadBenchCorpus reads a corpus file with one document per non-empty line.
+func loadBenchCorpus(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open corpus: %w", err)
+	}
+	defer f.Close()
+
+	var docs []string
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			docs = append(docs, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read corpus: %w", err)
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("corpus %s is empty", path)
+	}
+	return docs, nil
+}
+
+// embedAll embeds every text with embed, returning the vectors and the time
+// each embedding took.
+func embedAll(ctx context.Context, embed func(context.Context, string) ([]float32, error), texts []string) ([][]float32, []time.Duration, error) {
+	vecs := make([][]float32, len(texts))
+	times := make([]time.Duration, len(texts))
+	for i, text := range texts {
+		start := time.Now()
+		vec, err := embed(ctx, text)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to embed text %d: %w", i, err)
+		}
+		times[i] = time.Since(start)
+		vecs[i] = vec
+	}
+	return vecs, times, nil
+}
+
+// benchSizes runs the insert and search benchmarks for each corpus size, using
+// precomputed document and query embeddings so that only DuckDB is measured.
+// Each size is run without an index and, if withIndex is set, again with an
+// HNSW index. If the index cannot be created or is not used by the search
+// plan, the indexed runs are skipped and the reason is returned.
+func benchSizes(ctx context.Context, dim int32, docs []string, docVecs, queryVecs [][]float32, sizes []int, topK int, withIndex bool) ([]BenchRun, string, error) {
+	var runs []BenchRun
+	indexErr := ""
+	for _, size := range sizes {
+		if size > len(docs) {
+			return nil, "", fmt.Errorf("corpus size %d exceeds the %d available documents", size, len(docs))
+		}
+		variants := []string{"none"}
+		if withIndex && indexErr == "" {
+			variants = append(variants, "hnsw")
+		}
+		for _, index := range variants {
+			run, err := benchRun(ctx, dim, docs[:size], docVecs[:size], queryVecs, topK, index)
+			if err != nil && index == "hnsw" {
+				indexErr = err.Error()
+				continue
+			}
+			if err != nil {
+				return nil, "", err
+			}
+			runs = append(runs, run)
+		}
+	}
+	return runs, indexErr, nil
+}
+
+// benchRun loads docs into a fresh in-memory database, optionally indexes it,
+// and times a search for each query vector. Unindexed runs use the regular
+// search; indexed runs use hnswSearchQuery.
+func benchRun(ctx context.Context, dim int32, docs []string, docVecs, queryVecs [][]float32, topK int, index string) (BenchRun, error) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		return BenchRun{}, fmt.Errorf("failed to open scratch database: %w", err)
+	}
+	defer db.Close()
+
+	scratch := &RAGSystem{db: db, embeddingDim: dim, dbPath: ":memory:"}
+	if err := scratch.initDB(); err != nil {
+		return BenchRun{}, err
+	}
+
+	run := BenchRun{Size: len(docs), Index: index, Queries: len(queryVecs)}
+	start := time.Now()
+	for i, doc := range docs {
+		if err := scratch.insertDocument(ctx, fmt.Sprintf("bench-%d", i), doc, docVecs[i]); err != nil {
+			return BenchRun{}, err
+		}
+	}
+	run.InsertSeconds = time.Since(start).Seconds()
+	if run.InsertSeconds > 0 {
+		run.InsertsPerSec = float64(len(docs)) / run.InsertSeconds
+	}
+
+	search := func(vec []float32) error {
+		_, err := scratch.searchByVector(ctx, vec, topK)
+		return err
+	}
+	if index == "hnsw" {
+		start := time.Now()
+		if err := createHNSWIndex(ctx, db); err != nil {
+			return BenchRun{}, err
+		}
+		run.IndexSeconds = time.Since(start).Seconds()
+		if len(queryVecs) > 0 {
+			if err := checkHNSWPlan(ctx, db, hnswSearchQuery(queryVecs[0], dim, topK)); err != nil {
+				return BenchRun{}, err
+			}
+		}
+		search = func(vec []float32) error {
+			rows, err := db.QueryContext(ctx, hnswSearchQuery(vec, dim, topK))
+			if err != nil {
+				return fmt.Errorf("failed to query documents: %w", err)
+			}
+			defer rows.Close()
+			for rows.Next() {
+				// Fetch every result, as the regular search does.
+			}
+			return rows.Err()
+		}
+	}
+
+	times := make([]time.Duration, len(queryVecs))
+	start = time.Now()
+	for i, vec := range queryVecs {
+		qstart := time.Now()
+		if err := search(vec); err != nil {
+			return BenchRun{}, err
+		}
+		times[i] = time.Since(qstart)
+	}
+	if elapsed := time.Since(start).Seconds(); elapsed > 0 {
+		run.QueriesPerSec = float64(len(queryVecs)) / elapsed
+	}
+	run.SearchLatency = summarizeLatency(times)
+	return run, nil
+}
+
+// createHNSWIndex builds an HNSW cosine index on the embeddings using DuckDB's
+// vss extension, which is downloaded on first use.
+func createHNSWIndex(ctx context.Context, db *sql.DB) error {
+	for _, stmt := range []string{
+		`INSTALL vss`,
+		`LOAD vss`,
+		`CREATE INDEX documents_hnsw ON documents USING HNSW (embedding) WITH (metric = 'cosine')`,
+	} {
+		if _, err := db.ExecContext(ctx, stmt); err != nil {
+			return fmt.Errorf("HNSW index unavailable: %w", err)
+		}
+	}
+	return nil
+}
+
+// hnswSearchQuery returns a top-k search in the only form the vss extension
+// answers from an HNSW index: ordered by array_cosine_distance to a constant
+// vector with a constant LIMIT and no other predicates.
+func hnswSearchQuery(vec []float32, dim int32, topK int) string {
+	return fmt.Sprintf(`
+		SELECT id, content
+		FROM documents
+		ORDER BY array_cosine_distance(embedding, %s::FLOAT[%d])
+		LIMIT %d
+	`, floatArrayToSQL(vec), dim, topK)
+}
+
+// checkHNSWPlan returns an error unless DuckDB plans query with an HNSW index
+// scan, so that an indexed run never silently measures a full scan.
+func checkHNSWPlan(ctx context.Context, db *sql.DB, query string) error {
+	rows, err := db.QueryContext(ctx, "EXPLAIN "+query)
+	if err != nil {
+		return fmt.Errorf("failed to explain search: %w", err)
+	}
+	defer rows.Close()
+	var plan strings.Builder
+	for rows.Next() {
+		var key, value sql.NullString
+		if err := rows.Scan(&key, &value); err != nil {
+			return fmt.Errorf("failed to read search plan: %w", err)
+		}
+		plan.WriteString(value.String)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read search plan: %w", err)
+	}
+	if !strings.Contains(plan.String(), "HNSW_INDEX_SCAN") {
+		return fmt.Errorf("HNSW index unavailable: search plan does not use the index")
+	}
+	return nil
+}
+
+// summarizeLatency returns the mean, nearest-rank percentiles, and maximum of
+// times in milliseconds.
+func summarizeLatency(times []time.Duration) BenchLatency {
+	if len(times) == 0 {
+		return BenchLatency{}
+	}
+	sorted := slices.Clone(times)
+	slices.Sort(sorted)
+
+	ms := func(d time.Duration) float64 { return float64(d) / float64(time.Millisecond) }
+	percentile := func(p float64) float64 {
+		rank := int(math.Ceil(p*float64(len(sorted)))) - 1
+		return ms(sorted[max(0, rank)])
+	}
+
+	var total time.Duration
+	for _, d := range sorted {
+		total += d
+	}
+	return BenchLatency{
+		Mean: ms(total) / float64(len(sorted)),
+		P50:  percentile(0.50),
+		P90:  percentile(0.90),
+		P99:  percentile(0.99),
+		Max:  ms(sorted[len(sorted)-1]),
+	}
+}
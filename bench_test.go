@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSyntheticCorpus(t *testing.T) {
+	a := syntheticCorpus(5, 20, 42)
+	b := syntheticCorpus(5, 20, 42)
+	if len(a) != 5 {
+		t.Fatalf("expected 5 documents, got %d", len(a))
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("corpus not deterministic at %d: %q vs %q", i, a[i], b[i])
+		}
+		if n := len(strings.Fields(a[i])); n != 20 {
+			t.Errorf("document %d has %d words, want 20", i, n)
+		}
+	}
+	if c := syntheticCorpus(5, 20, 43); c[0] == a[0] {
+		t.Error("different seeds produced the same document")
+	}
+}
+
+func TestSummarizeLatency(t *testing.T) {
+	var times []time.Duration
+	for i := 100; i >= 1; i-- {
+		times = append(times, time.Duration(i)*time.Millisecond)
+	}
+	got := summarizeLatency(times)
+	if got.P50 != 50 || got.P90 != 90 || got.P99 != 99 || got.Max != 100 {
+		t.Errorf("unexpected percentiles: %+v", got)
+	}
+	if !approxEqual(got.Mean, 50.5) {
+		t.Errorf("Mean = %v, want 50.5", got.Mean)
+	}
+	if times[0] != 100*time.Millisecond {
+		t.Error("summarizeLatency reordered its input")
+	}
+	if (summarizeLatency(nil) != BenchLatency{}) {
+		t.Error("expected zero summary for no timings")
+	}
+}
+
+func TestParseSizes(t *testing.T) {
+	sizes, err := parseSizes("10, 100,1000")
+	if err != nil {
+		t.Fatalf("parseSizes failed: %v", err)
+	}
+	if len(sizes) != 3 || sizes[2] != 1000 {
+		t.Errorf("sizes = %v", sizes)
+	}
+	for _, bad := range []string{"", "10,x", "0", "-5"} {
+		if _, err := parseSizes(bad); err == nil {
+			t.Errorf("parseSizes(%q): expected error", bad)
+		}
+	}
+}
+
+func TestLoadBenchCorpus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "corpus.txt")
+	if err := os.WriteFile(path, []byte("first doc\n\n  second doc  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := loadBenchCorpus(path)
+	if err != nil {
+		t.Fatalf("loadBenchCorpus failed: %v", err)
+	}
+	if len(docs) != 2 || docs[1] != "second doc" {
+		t.Errorf("docs = %q", docs)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.txt")
+	os.WriteFile(empty, []byte("\n"), 0644)
+	if _, err := loadBenchCorpus(empty); err == nil {
+		t.Error("expected error for empty corpus")
+	}
+}
+
+func TestEmbedAll(t *testing.T) {
+	embed := func(ctx context.Context, text string) ([]float32, error) {
+		if text == "bad" {
+			return nil, errors.New("boom")
+		}
+		return []float32{float32(len(text)), 0, 0}, nil
+	}
+	vecs, times, err := embedAll(context.Background(), embed, []string{"a", "abc"})
+	if err != nil {
+		t.Fatalf("embedAll failed: %v", err)
+	}
+	if len(vecs) != 2 || vecs[1][0] != 3 || len(times) != 2 {
+		t.Errorf("unexpected output: %v %v", vecs, times)
+	}
+	if _, _, err := embedAll(context.Background(), embed, []string{"a", "bad"}); err == nil {
+		t.Error("expected embedding error to be returned")
+	}
+}
+
+func TestCheckHNSWPlan_FullScan(t *testing.T) {
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "a", "alpha", "", "", []float32{1, 0, 0})
+
+	err := checkHNSWPlan(context.Background(), rag.db, hnswSearchQuery([]float32{1, 0, 0}, 3, 1))
+	if err == nil || !strings.Contains(err.Error(), "does not use the index") {
+		t.Errorf("expected full-scan plan to be rejected, got %v", err)
+	}
+}
+
+func TestBenchSizes(t *testing.T) {
+	docs := syntheticCorpus(20, 5, 1)
+	docVecs := make([][]float32, len(docs))
+	for i := range docVecs {
+		docVecs[i] = normalizeVector([]float32{float32(i + 1), 1, float32(i % 3)})
+	}
+	queryVecs := [][]float32{{1, 0, 0}, {0, 1, 0}, {0, 0, 1}}
+
+	// The HNSW runs download DuckDB's vss extension, so they only run when
+	// YDRAG_TEST_HNSW is set; then they must either use the index or report
+	// why they were skipped.
+	withIndex := os.Getenv("YDRAG_TEST_HNSW") != ""
+	runs, indexErr, err := benchSizes(context.Background(), 3, docs, docVecs, queryVecs, []int{5, 20}, 3, withIndex)
+	if err != nil {
+		t.Fatalf("benchSizes failed: %v", err)
+	}
+
+	var plain int
+	for _, run := range runs {
+		if run.Index == "none" {
+			plain++
+		}
+		if run.Queries != 3 || run.InsertsPerSec <= 0 || run.SearchLatency.Max <= 0 {
+			t.Errorf("incomplete run: %+v", run)
+		}
+	}
+	if plain != 2 {
+		t.Errorf("expected 2 unindexed runs, got %d: %+v", plain, runs)
+	}
+	switch {
+	case !withIndex && (len(runs) != 2 || indexErr != ""):
+		t.Errorf("unexpected indexed runs without withIndex: %+v, %q", runs, indexErr)
+	case withIndex && indexErr != "":
+		t.Logf("indexed runs skipped: %s", indexErr)
+	case withIndex && len(runs) != 4:
+		t.Errorf("indexed runs missing without an index error: %+v", runs)
+	}
+	if runs[0].Size != 5 {
+		t.Errorf("first run size = %d, want 5", runs[0].Size)
+	}
+
+	if _, _, err := benchSizes(context.Background(), 3, docs, docVecs, queryVecs, []int{50}, 3, false); err == nil {
+		t.Error("expected error for size larger than the corpus")
+	}
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io"
 	"strings"
+	"unicode/utf8"
 )
 
 func init() {
@@ -24,23 +28,81 @@ func (c *AddCommand) Description() string {
 
 // Usage returns the usage string showing expected arguments for the add command.
 func (c *AddCommand) Usage() string {
-	return "add <id> <content>"
+	return "add <id> <content> | add <id> -"
+}
+
+// Flags returns the add command's flag set, which defines no flags.
+func (c *AddCommand) Flags() *flag.FlagSet {
+	return flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+}
+
+// NeedsModel reports that the add command embeds text and requires a model.
+func (c *AddCommand) NeedsModel() bool {
+	return true
 }
 
 // Run executes the add command, parsing the document ID and content from args
-// and storing them in the RAG system's knowledge base.
+// and storing them in the RAG system's knowledge base. When the content is
+// "-", it is read from standard input and split into chunks if it is longer
+// than the configured chunk size.
 func (c *AddCommand) Run(rag *RAGSystem, args []string) error {
 	if len(args) < 2 {
 		return fmt.Errorf("usage: %s", c.Usage())
 	}
 
 	id := args[0]
+	if len(args) == 2 && args[1] == "-" {
+		return c.addStdin(rag, id)
+	}
 	content := strings.Join(args[1:], " ")
 
-	if err := rag.AddDocument(id, content); err != nil {
+	if err := rag.AddDocument(context.Background(), id, content); err != nil {
+		return fmt.Errorf("failed to add document: %w", err)
+	}
+
+	return output.Print(Result{
+		Value: StatusResult{ID: id, Status: "added"},
+		Text: func(w io.Writer) {
+			fmt.Fprintf(w, "Document '%s' added successfully\n", id)
+		},
+	})
+}
+
+// addStdin stores the text read from standard input under id.
+func (c *AddCommand) addStdin(rag *RAGSystem, id string) error {
+	r := input
+	limit := ingestConfig().MaxFileSize
+	if limit > 0 {
+		r = io.LimitReader(input, limit+1)
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("failed to read standard input: %w", err)
+	}
+	if limit > 0 && int64(len(data)) > limit {
+		return fmt.Errorf("input exceeds maximum size of %d bytes", limit)
+	}
+	if !utf8.Valid(data) {
+		return fmt.Errorf("input is not valid UTF-8 text")
+	}
+	content := strings.TrimSpace(string(data))
+	if content == "" {
+		return fmt.Errorf("no content on standard input")
+	}
+
+	ids, err := rag.AddText(context.Background(), id, content, nil)
+	if err != nil {
 		return fmt.Errorf("failed to add document: %w", err)
 	}
 
-	fmt.Printf("Document '%s' added successfully\n", id)
-	return nil
+	return output.Print(Result{
+		Value: StatusResult{ID: id, Status: "added"},
+		Text: func(w io.Writer) {
+			if len(ids) > 1 {
+				fmt.Fprintf(w, "Document '%s' added successfully (%d chunks)\n", id, len(ids))
+			} else {
+				fmt.Fprintf(w, "Document '%s' added successfully\n", id)
+			}
+		},
+	})
 }
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	RegisterCommand(&BenchCommand{})
+}
+
+// BenchCommand implements the "bench" CLI command, which measures embedding
+// throughput, insert throughput, and search latency on a scratch database.
+type BenchCommand struct {
+	sizes   string
+	queries int
+	topK    int
+	words   int
+	corpus  string
+	seed    int64
+	index   bool
+}
+
+// Name returns the command name "bench".
+func (c *BenchCommand) Name() string {
+	return "bench"
+}
+
+// Description returns a short summary of what the bench command does.
+func (c *BenchCommand) Description() string {
+	return "Benchmark embedding, insert, and search performance on a scratch database"
+}
+
+// Usage returns the usage string showing available flags for the bench command.
+func (c *BenchCommand) Usage() string {
+	return "bench [--sizes N,N,...] [--queries N] [--top-k N] [--words N] [--corpus FILE] [--seed N] [--index]"
+}
+
+// Flags returns the bench command's flag set.
+func (c *BenchCommand) Flags() *flag.FlagSet {
+	*c = BenchCommand{}
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	fs.StringVar(&c.sizes, "sizes", "100,1000", "comma-separated corpus sizes to benchmark")
+	fs.IntVar(&c.queries, "queries", 100, "number of search queries per corpus size")
+	fs.IntVar(&c.topK, "top-k", 5, "results requested per search")
+	fs.IntVar(&c.words, "words", 100, "words per synthetic document")
+	fs.StringVar(&c.corpus, "corpus", "", "file with one document per line (default: synthetic corpus)")
+	fs.Int64Var(&c.seed, "seed", 1, "seed for the synthetic corpus and queries")
+	fs.BoolVar(&c.index, "index", false, "also benchmark search with an HNSW index (installs DuckDB's vss extension)")
+	return fs
+}
+
+// NeedsModel reports that the bench command embeds text and requires a model.
+func (c *BenchCommand) NeedsModel() bool {
+	return true
+}
+
+// Run embeds the corpus and queries with the loaded model, then loads each
+// corpus size into a fresh in-memory database and times inserts and searches.
+// The configured knowledge base is not touched.
+func (c *BenchCommand) Run(rag *RAGSystem, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %s\nusage: %s", strings.Join(args, " "), c.Usage())
+	}
+	sizes, err := parseSizes(c.sizes)
+	if err != nil {
+		return err
+	}
+	if c.queries <= 0 || c.topK <= 0 || c.words <= 0 {
+		return fmt.Errorf("--queries, --top-k, and --words must be positive")
+	}
+	largest := slices.Max(sizes)
+
+	report := BenchReport{
+		Settings: runSettings(cfg),
+		Corpus:   "synthetic",
+		TopK:     c.topK,
+	}
+	var docs []string
+	if c.corpus != "" {
+		if docs, err = loadBenchCorpus(c.corpus); err != nil {
+			return err
+		}
+		if largest > len(docs) {
+			return fmt.Errorf("corpus has %d documents, fewer than size %d", len(docs), largest)
+		}
+		report.Corpus = c.corpus
+	} else {
+		docs = syntheticCorpus(largest, c.words, c.seed)
+	}
+	docs = docs[:largest]
+	queries := syntheticQueries(c.queries, c.seed)
+
+	ctx := context.Background()
+	slog.Info("embedding corpus", "documents", len(docs))
+	start := time.Now()
+	docVecs, docTimes, err := embedAll(ctx, rag.GenerateEmbedding, docs)
+	if err != nil {
+		return err
+	}
+	elapsed := time.Since(start).Seconds()
+	report.Embedding = BenchEmbedding{
+		Documents: len(docs),
+		Seconds:   elapsed,
+		Latency:   summarizeLatency(docTimes),
+	}
+	for _, d := range docs {
+		report.Embedding.Bytes += len(d)
+	}
+	if elapsed > 0 {
+		report.Embedding.DocsPerSecond = float64(len(docs)) / elapsed
+	}
+
+	queryVecs, queryTimes, err := embedAll(ctx, rag.GenerateEmbedding, queries)
+	if err != nil {
+		return err
+	}
+	report.Embedding.QueryLatency = summarizeLatency(queryTimes)
+	dim := int32(len(queryVecs[0]))
+	report.EmbeddingDim = int(dim)
+
+	slog.Info("benchmarking search", "sizes", sizes, "queries", len(queries))
+	report.Runs, report.IndexError, err = benchSizes(ctx, dim, docs, docVecs, queryVecs, sizes, c.topK, c.index)
+	if err != nil {
+		return err
+	}
+
+	return output.Print(Result{
+		Value: report,
+		Rows:  report.Runs,
+		Text:  func(w io.Writer) { printBenchReport(w, report) },
+	})
+}
+
+// parseSizes parses a comma-separated list of positive corpus sizes.
+func parseSizes(s string) ([]int, error) {
+	var sizes []int
+	for _, item := range splitList(s) {
+		n, err := strconv.Atoi(item)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid corpus size %q", item)
+		}
+		sizes = append(sizes, n)
+	}
+	if len(sizes) == 0 {
+		return nil, fmt.Errorf("no corpus sizes given")
+	}
+	return sizes, nil
+}
+
+// printBenchReport prints the report as aligned tables.
+func printBenchReport(w io.Writer, r BenchReport) {
+	s := r.Settings
+	fmt.Fprintf(w, "\nModel: %s (dim %d), context_size %d, batch_size %d\n", s.Model, r.EmbeddingDim, s.ContextSize, s.BatchSize)
+	fmt.Fprintf(w, "Corpus: %s, top_k %d\n\n", r.Corpus, r.TopK)
+
+	e := r.Embedding
+	fmt.Fprintf(w, "Embedding: %d documents (%d bytes) in %.2fs, %.1f docs/s\n", e.Documents, e.Bytes, e.Seconds, e.DocsPerSecond)
+	fmt.Fprintf(w, "  per document  p50 %.2fms  p90 %.2fms  p99 %.2fms\n", e.Latency.P50, e.Latency.P90, e.Latency.P99)
+	fmt.Fprintf(w, "  per query     p50 %.2fms  p90 %.2fms  p99 %.2fms\n\n", e.QueryLatency.P50, e.QueryLatency.P90, e.QueryLatency.P99)
+
+	fmt.Fprintf(w, "%8s  %-5s  %10s  %8s  %9s  %9s  %9s  %9s\n", "size", "index", "inserts/s", "index_s", "p50_ms", "p90_ms", "p99_ms", "queries/s")
+	for _, run := range r.Runs {
+		fmt.Fprintf(w, "%8d  %-5s  %10.1f  %8.2f  %9.3f  %9.3f  %9.3f  %9.1f\n",
+			run.Size, run.Index, run.InsertsPerSec, run.IndexSeconds,
+			run.SearchLatency.P50, run.SearchLatency.P90, run.SearchLatency.P99, run.QueriesPerSec)
+	}
+	if r.IndexError != "" {
+		fmt.Fprintf(w, "\nIndexed runs skipped: %s\n", r.IndexError)
+	}
+}
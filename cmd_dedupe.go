@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterCommand(&DedupeCommand{})
+}
+
+// DedupeCommand implements the "dedupe" CLI command, which finds clusters of
+// near-duplicate documents and optionally removes all but the oldest.
+type DedupeCommand struct {
+	threshold float64
+	delete    bool
+	merge     bool
+}
+
+// DedupeReport is the result of a dedupe run. Action is "report", "delete",
+// or "merge".
+type DedupeReport struct {
+	Threshold float64            `json:"threshold"`
+	Action    string             `json:"action"`
+	Clusters  []DuplicateCluster `json:"clusters"`
+	Removed   int                `json:"removed"`
+}
+
+// Name returns the command name "dedupe".
+func (c *DedupeCommand) Name() string {
+	return "dedupe"
+}
+
+// Description returns a short summary of what the dedupe command does.
+func (c *DedupeCommand) Description() string {
+	return "Find near-duplicate documents and optionally remove them"
+}
+
+// Usage returns the usage string for the dedupe command.
+func (c *DedupeCommand) Usage() string {
+	return "dedupe [--threshold S] [--delete | --merge]"
+}
+
+// Flags returns the dedupe command's flag set.
+func (c *DedupeCommand) Flags() *flag.FlagSet {
+	*c = DedupeCommand{}
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	fs.Float64Var(&c.threshold, "threshold", 0.95, "minimum cosine similarity for documents to count as duplicates")
+	fs.BoolVar(&c.delete, "delete", false, "delete duplicates, keeping the oldest document of each cluster")
+	fs.BoolVar(&c.merge, "merge", false, "like --delete, and record the removed IDs in the kept document's aliases metadata")
+	return fs
+}
+
+// NeedsModel reports that the dedupe command does not require a model; it
+// compares stored embeddings.
+func (c *DedupeCommand) NeedsModel() bool {
+	return false
+}
+
+// Run reports the near-duplicate clusters in the selected collection, and
+// removes the duplicates when --delete or --merge is given.
+func (c *DedupeCommand) Run(rag *RAGSystem, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: %s", c.Usage())
+	}
+	if c.threshold <= 0 || c.threshold > 1 {
+		return fmt.Errorf("--threshold must be in (0, 1], got %g", c.threshold)
+	}
+	if c.delete && c.merge {
+		return fmt.Errorf("--delete and --merge are mutually exclusive")
+	}
+
+	ctx := context.Background()
+	clusters, err := rag.FindDuplicates(ctx, c.threshold)
+	if err != nil {
+		return err
+	}
+	report := DedupeReport{Threshold: c.threshold, Action: "report", Clusters: clusters}
+	if report.Clusters == nil {
+		report.Clusters = []DuplicateCluster{}
+	}
+
+	if c.delete || c.merge {
+		report.Action = "delete"
+		if c.merge {
+			report.Action = "merge"
+		}
+		report.Removed, err = rag.RemoveDuplicates(ctx, clusters, c.merge)
+		if err != nil {
+			return err
+		}
+	}
+
+	return output.Print(Result{
+		Value: report,
+		Rows:  report.Clusters,
+		Text:  func(w io.Writer) { printDedupeReport(w, report) },
+	})
+}
+
+// printDedupeReport writes the clusters and what was done with them.
+func printDedupeReport(w io.Writer, r DedupeReport) {
+	if len(r.Clusters) == 0 {
+		fmt.Fprintf(w, "No near-duplicates found (similarity >= %g)\n", r.Threshold)
+		return
+	}
+	fmt.Fprintf(w, "%d clusters of near-duplicates (similarity >= %g):\n\n", len(r.Clusters), r.Threshold)
+	for _, c := range r.Clusters {
+		fmt.Fprintf(w, "  [%.4f] keep %s, duplicates: %s\n", c.Similarity, c.Keep, strings.Join(c.Duplicates, ", "))
+	}
+	switch r.Action {
+	case "delete":
+		fmt.Fprintf(w, "\nDeleted %d documents\n", r.Removed)
+	case "merge":
+		fmt.Fprintf(w, "\nMerged %d documents into the kept ones\n", r.Removed)
+	default:
+		fmt.Fprintln(w, "\nRun with --delete or --merge to remove the duplicates")
+	}
+}
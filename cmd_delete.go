@@ -1,6 +1,10 @@
 package main
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+	"io"
+)
 
 func init() {
 	RegisterCommand(&DeleteCommand{})
@@ -24,6 +28,16 @@ func (c *DeleteCommand) Usage() string {
 	return "delete <id>"
 }
 
+// Flags returns the delete command's flag set, which defines no flags.
+func (c *DeleteCommand) Flags() *flag.FlagSet {
+	return flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+}
+
+// NeedsModel reports that the delete command does not require a model.
+func (c *DeleteCommand) NeedsModel() bool {
+	return false
+}
+
 // Run executes the delete command, removing the document identified by the first
 // argument from the RAG system's knowledge base.
 func (c *DeleteCommand) Run(rag *RAGSystem, args []string) error {
@@ -37,6 +51,10 @@ func (c *DeleteCommand) Run(rag *RAGSystem, args []string) error {
 		return fmt.Errorf("failed to delete document: %w", err)
 	}
 
-	fmt.Printf("Document '%s' deleted successfully\n", id)
-	return nil
+	return output.Print(Result{
+		Value: StatusResult{ID: id, Status: "deleted"},
+		Text: func(w io.Writer) {
+			fmt.Fprintf(w, "Document '%s' deleted successfully\n", id)
+		},
+	})
 }
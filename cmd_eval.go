@@ -0,0 +1,129 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterCommand(&EvalCommand{})
+}
+
+// EvalCommand implements the "eval" CLI command, which measures retrieval
+// quality against a file of queries with known relevant documents.
+type EvalCommand struct {
+	k         int
+	baseline  string
+	tolerance float64
+}
+
+// Name returns the command name "eval".
+func (c *EvalCommand) Name() string {
+	return "eval"
+}
+
+// Description returns a short summary of what the eval command does.
+func (c *EvalCommand) Description() string {
+	return "Evaluate retrieval quality (recall, precision, MRR, nDCG) against a qrels file"
+}
+
+// Usage returns the usage string showing expected arguments for the eval command.
+func (c *EvalCommand) Usage() string {
+	return "eval [--k N] [--baseline REPORT] [--tolerance T] <qrels.jsonl>"
+}
+
+// Flags returns the eval command's flag set.
+func (c *EvalCommand) Flags() *flag.FlagSet {
+	*c = EvalCommand{}
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	fs.IntVar(&c.k, "k", 5, "number of results to retrieve and score per query")
+	fs.StringVar(&c.baseline, "baseline", "", "report from a previous run with -format json; fail if any metric regresses")
+	fs.Float64Var(&c.tolerance, "tolerance", 0, "how far a metric may fall below the baseline before failing")
+	return fs
+}
+
+// NeedsModel reports that the eval command embeds text and requires a model.
+func (c *EvalCommand) NeedsModel() bool {
+	return true
+}
+
+// Run evaluates every query in the qrels file, prints the report, and returns
+// an error if a baseline is given and any metric regressed beyond the
+// tolerance.
+func (c *EvalCommand) Run(rag *RAGSystem, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s", c.Usage())
+	}
+
+	queries, err := loadQrels(args[0])
+	if err != nil {
+		return err
+	}
+	var baseline *EvalReport
+	if c.baseline != "" {
+		b, err := loadEvalReport(c.baseline)
+		if err != nil {
+			return err
+		}
+		if b.K != c.k {
+			return fmt.Errorf("baseline was evaluated at k=%d, not k=%d", b.K, c.k)
+		}
+		baseline = &b
+	}
+
+	search := func(ctx context.Context, query string, topK int) ([]SearchResult, error) {
+		return rag.Query(ctx, query, topK, nil)
+	}
+	report, err := evaluate(context.Background(), search, queries, c.k)
+	if err != nil {
+		return fmt.Errorf("evaluation failed: %w", err)
+	}
+	report.Settings = runSettings(cfg)
+
+	err = output.Print(Result{
+		Value: report,
+		Rows:  report.PerQuery,
+		Text:  func(w io.Writer) { printEvalReport(w, report) },
+	})
+	if err != nil {
+		return err
+	}
+
+	if baseline != nil {
+		if regressed := regressions(report.Metrics, baseline.Metrics, c.tolerance); len(regressed) > 0 {
+			return fmt.Errorf("metrics regressed: %s", strings.Join(regressed, ", "))
+		}
+	}
+	return nil
+}
+
+// printEvalReport prints the mean metrics and lists queries that missed some
+// of their relevant documents.
+func printEvalReport(w io.Writer, r EvalReport) {
+	fmt.Fprintf(w, "\nEvaluated %d queries at k=%d\n\n", r.Queries, r.K)
+	fmt.Fprintf(w, "  %-14s %.4f\n", fmt.Sprintf("recall@%d", r.K), r.Metrics.Recall)
+	fmt.Fprintf(w, "  %-14s %.4f\n", fmt.Sprintf("precision@%d", r.K), r.Metrics.Precision)
+	fmt.Fprintf(w, "  %-14s %.4f\n", "MRR", r.Metrics.MRR)
+	fmt.Fprintf(w, "  %-14s %.4f\n", fmt.Sprintf("nDCG@%d", r.K), r.Metrics.NDCG)
+
+	var missed []QueryEval
+	for _, q := range r.PerQuery {
+		if q.Metrics.Recall < 1 {
+			missed = append(missed, q)
+		}
+	}
+	if len(missed) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\nQueries missing relevant documents:\n")
+	for _, q := range missed {
+		name := q.ID
+		if name == "" {
+			name = truncate(q.Query, 60)
+		}
+		fmt.Fprintf(w, "  [recall %.2f] %s\n", q.Metrics.Recall, name)
+	}
+}
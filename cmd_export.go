@@ -0,0 +1,95 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+)
+
+func init() {
+	RegisterCommand(&ExportCommand{})
+}
+
+// ExportCommand implements the "export" CLI command, which writes the
+// documents of the selected collection as JSON Lines.
+type ExportCommand struct {
+	out        string
+	embeddings bool
+}
+
+// exportRecord is one line of export output. Embedding is omitted unless
+// requested.
+type exportRecord struct {
+	Document
+	Embedding []float32 `json:"embedding,omitempty"`
+}
+
+// Name returns the command name "export".
+func (c *ExportCommand) Name() string {
+	return "export"
+}
+
+// Description returns a short summary of what the export command does.
+func (c *ExportCommand) Description() string {
+	return "Export documents as JSON Lines"
+}
+
+// Usage returns the usage string for the export command.
+func (c *ExportCommand) Usage() string {
+	return "export [--o FILE] [--embeddings]"
+}
+
+// Flags returns the export command's flag set.
+func (c *ExportCommand) Flags() *flag.FlagSet {
+	*c = ExportCommand{}
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	fs.StringVar(&c.out, "o", "", "file to write (default: standard output)")
+	fs.BoolVar(&c.embeddings, "embeddings", false, "include each document's embedding vector")
+	return fs
+}
+
+// NeedsModel reports that the export command does not require a model.
+func (c *ExportCommand) NeedsModel() bool {
+	return false
+}
+
+// Run writes one JSON object per document, with full content and metadata,
+// to the output file or standard output. The output is always JSON Lines,
+// whatever the -format flag.
+func (c *ExportCommand) Run(rag *RAGSystem, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: %s", c.Usage())
+	}
+
+	var w io.Writer = output.W
+	if c.out != "" {
+		f, err := os.Create(c.out)
+		if err != nil {
+			return fmt.Errorf("failed to create export file: %w", err)
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+
+	n := 0
+	err := rag.ExportDocuments(c.embeddings, func(doc Document) error {
+		n++
+		return enc.Encode(exportRecord{Document: doc, Embedding: doc.Embedding})
+	})
+	if err != nil {
+		return err
+	}
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("failed to write export: %w", err)
+	}
+	if c.out != "" {
+		slog.Info("exported documents", "documents", n, "file", c.out)
+	}
+	return nil
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+)
+
+func init() {
+	RegisterCommand(&GetDocumentCommand{})
+}
+
+// GetDocumentCommand implements the "get" CLI command for showing a stored document in full.
+type GetDocumentCommand struct{}
+
+// Name returns the command name "get".
+func (c *GetDocumentCommand) Name() string {
+	return "get"
+}
+
+// Description returns a short summary of what the get command does.
+func (c *GetDocumentCommand) Description() string {
+	return "Show a document, or the chunks of an ingested file"
+}
+
+// Usage returns the usage string showing expected arguments for the get command.
+func (c *GetDocumentCommand) Usage() string {
+	return "get <id>"
+}
+
+// Flags returns the get command's flag set, which defines no flags.
+func (c *GetDocumentCommand) Flags() *flag.FlagSet {
+	return flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+}
+
+// NeedsModel reports that the get command does not require a model.
+func (c *GetDocumentCommand) NeedsModel() bool {
+	return false
+}
+
+// Run executes the get command, printing the full content and metadata of the
+// document identified by the first argument, or of each of its chunks.
+func (c *GetDocumentCommand) Run(rag *RAGSystem, args []string) error {
+	if len(args) < 1 {
+		return fmt.Errorf("usage: %s", c.Usage())
+	}
+
+	docs, err := rag.GetDocument(args[0])
+	if err != nil {
+		return err
+	}
+
+	return output.Print(Result{
+		Value: docs,
+		Text: func(w io.Writer) {
+			for i, doc := range docs {
+				if i > 0 {
+					fmt.Fprintln(w)
+				}
+				fmt.Fprintf(w, "== %s", doc.ID)
+				if doc.ParentID != "" {
+					fmt.Fprintf(w, " (chunk %d of %s)", doc.ChunkIndex, doc.ParentID)
+				}
+				fmt.Fprintln(w)
+				for _, k := range slices.Sorted(maps.Keys(doc.Metadata)) {
+					fmt.Fprintf(w, "%s: %s\n", k, doc.Metadata[k])
+				}
+				fmt.Fprintln(w, doc.Content)
+			}
+		},
+	})
+}
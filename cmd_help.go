@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+)
+
+func init() {
+	RegisterCommand(&HelpCommand{})
+}
+
+// HelpCommand implements the "help" CLI command for describing commands and their flags.
+type HelpCommand struct{}
+
+// Name returns the command name "help".
+func (c *HelpCommand) Name() string {
+	return "help"
+}
+
+// Description returns a short summary of what the help command does.
+func (c *HelpCommand) Description() string {
+	return "Show general usage or the flags of a command"
+}
+
+// Usage returns the usage string showing expected arguments for the help command.
+func (c *HelpCommand) Usage() string {
+	return "help [command]"
+}
+
+// Flags returns the help command's flag set, which defines no flags.
+func (c *HelpCommand) Flags() *flag.FlagSet {
+	return flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+}
+
+// NeedsModel reports that the help command does not require a model.
+func (c *HelpCommand) NeedsModel() bool {
+	return false
+}
+
+// Run prints the full usage message, or the help for the command named by the
+// first argument. It does not use the RAG system.
+func (c *HelpCommand) Run(rag *RAGSystem, args []string) error {
+	if len(args) == 0 {
+		showUsage()
+		return nil
+	}
+
+	cmd, ok := GetCommand(args[0])
+	if !ok {
+		return fmt.Errorf("unknown command: %s", args[0])
+	}
+	PrintCommandHelp(os.Stdout, cmd)
+	return nil
+}
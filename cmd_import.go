@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/template"
+)
+
+func init() {
+	RegisterCommand(&ImportCommand{})
+}
+
+// ImportCommand implements the "import" CLI command, which stores each row of
+// a CSV, JSON, or JSONL file as a document.
+type ImportCommand struct {
+	format   string
+	id       string
+	content  string
+	template string
+	metadata string
+	batch    int
+}
+
+// ImportReport is the result of an import run.
+type ImportReport struct {
+	Source   string     `json:"source"`
+	Rows     int        `json:"rows"`
+	Imported int        `json:"imported"`
+	Failed   []RowError `json:"failed"`
+}
+
+// Name returns the command name "import".
+func (c *ImportCommand) Name() string {
+	return "import"
+}
+
+// Description returns a short summary of what the import command does.
+func (c *ImportCommand) Description() string {
+	return "Add each row of a CSV, JSON, or JSONL file as a document"
+}
+
+// Usage returns the usage string for the import command.
+func (c *ImportCommand) Usage() string {
+	return "import [--format csv|json|jsonl] [--id col] [--content col | --template text] [--metadata cols] [--batch n] <file>"
+}
+
+// Flags returns the import command's flag set.
+func (c *ImportCommand) Flags() *flag.FlagSet {
+	*c = ImportCommand{}
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	fs.StringVar(&c.format, "format", "", "file format: csv, json (an array of objects), or jsonl; detected from the extension by default")
+	fs.StringVar(&c.id, "id", "", `column holding the document ID (default "id" if present, else "<file>:<row>")`)
+	fs.StringVar(&c.content, "content", "content", "column holding the document content")
+	fs.StringVar(&c.template, "template", "", `Go template building the content from columns, e.g. "{{.question}}\n\n{{.answer}}"`)
+	fs.StringVar(&c.metadata, "metadata", "", `comma-separated columns to store as metadata, or "*" for all others`)
+	fs.IntVar(&c.batch, "batch", defaultBatchSize, "rows to embed and store per transaction")
+	return fs
+}
+
+// NeedsModel reports that the import command embeds text and requires a model.
+func (c *ImportCommand) NeedsModel() bool {
+	return true
+}
+
+// Run reads the file in args, maps each row to a document, and stores the
+// documents in batches; content longer than the chunk size is stored as
+// chunks. Rows that cannot be read, mapped, or embedded are reported and
+// skipped.
+func (c *ImportCommand) Run(rag *RAGSystem, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: %s", c.Usage())
+	}
+	path := args[0]
+	if c.batch <= 0 {
+		return fmt.Errorf("--batch must be positive, got %d", c.batch)
+	}
+
+	mapping := importMapping{id: c.id, content: c.content, source: path}
+	if c.template != "" {
+		// Let "\n" in a template given on the command line stand for a newline.
+		text := strings.ReplaceAll(c.template, `\n`, "\n")
+		t, err := template.New("content").Option("missingkey=zero").Parse(text)
+		if err != nil {
+			return fmt.Errorf("invalid --template: %w", err)
+		}
+		mapping.template = t
+	}
+	for col := range strings.SplitSeq(c.metadata, ",") {
+		if col = strings.TrimSpace(col); col != "" {
+			mapping.metadata = append(mapping.metadata, col)
+		}
+	}
+
+	format := c.format
+	if format == "" {
+		format = strings.TrimPrefix(strings.ToLower(filepath.Ext(path)), ".")
+		if format == "ndjson" {
+			format = "jsonl"
+		}
+	}
+
+	ctx := context.Background()
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var rows []dataRow
+	var failed []RowError
+	switch format {
+	case "csv", "tsv":
+		rows, failed, err = readCSVRows(f)
+	case "json", "jsonl":
+		rows, failed, err = readJSONRows(f, format == "jsonl")
+	default:
+		return fmt.Errorf("unsupported format %q: use --format csv, json, or jsonl", format)
+	}
+	if err != nil {
+		return err
+	}
+
+	report := ImportReport{Source: path, Rows: len(rows) + len(failed), Failed: failed}
+	w := &recordWriter{rag: rag, size: c.batch, report: &report}
+	for _, row := range rows {
+		rec, err := mapping.record(row)
+		if err != nil {
+			report.Failed = append(report.Failed, RowError{Row: row.num, ID: rec.ID, Error: err.Error()})
+			continue
+		}
+		if err := w.add(ctx, row.num, rec); err != nil {
+			return err
+		}
+	}
+	if err := w.flush(ctx); err != nil {
+		return err
+	}
+	if report.Failed == nil {
+		report.Failed = []RowError{}
+	}
+	slices.SortStableFunc(report.Failed, func(a, b RowError) int { return a.Row - b.Row })
+
+	if err := output.Print(Result{
+		Value: report,
+		Rows:  report.Failed,
+		Text:  func(w io.Writer) { printImportReport(w, report) },
+	}); err != nil {
+		return err
+	}
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("failed to import %d of %d rows", len(report.Failed), report.Rows)
+	}
+	return nil
+}
+
+// printImportReport writes the number of rows imported and the rows that
+// failed.
+func printImportReport(w io.Writer, r ImportReport) {
+	fmt.Fprintf(w, "Imported %d of %d rows from %s\n", r.Imported, r.Rows, r.Source)
+	if len(r.Failed) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nFailed rows:")
+	for _, f := range r.Failed {
+		if f.ID != "" {
+			fmt.Fprintf(w, "  row %d (%s): %s\n", f.Row, f.ID, f.Error)
+		} else {
+			fmt.Fprintf(w, "  row %d: %s\n", f.Row, f.Error)
+		}
+	}
+}
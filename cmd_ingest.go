@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func init() {
+	RegisterCommand(&IngestCommand{})
+}
+
+// IngestCommand implements the "ingest" CLI command, which adds files, and
+// the supported files found in directories, as chunked documents.
+type IngestCommand struct {
+	exclude string
+	stdin   bool
+}
+
+// IngestReport is the result of an ingest run.
+type IngestReport struct {
+	Files   []IngestedFile `json:"files"`
+	Skipped []SkippedFile  `json:"skipped"`
+	Failed  []FailedFile   `json:"failed"`
+}
+
+// IngestedFile is a file stored as a document.
+type IngestedFile struct {
+	ID     string `json:"id"`
+	Chunks int    `json:"chunks"`
+}
+
+// SkippedFile is a file that was not ingested. Reason is "unsupported",
+// "binary", "generated", "too large", or "duplicate".
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
+// FailedFile is a file that could not be ingested, or a directory that could
+// not be read.
+type FailedFile struct {
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// vendoredDirs are directories of third-party code, which are not walked.
+var vendoredDirs = []string{"vendor", "node_modules", "third_party", "bower_components"}
+
+// Name returns the command name "ingest".
+func (c *IngestCommand) Name() string {
+	return "ingest"
+}
+
+// Description returns a short summary of what the ingest command does.
+func (c *IngestCommand) Description() string {
+	return "Add files and directories as chunked documents"
+}
+
+// Usage returns the usage string for the ingest command.
+func (c *IngestCommand) Usage() string {
+	return "ingest [--exclude patterns] <path>... | ingest --stdin"
+}
+
+// Flags returns the ingest command's flag set.
+func (c *IngestCommand) Flags() *flag.FlagSet {
+	*c = IngestCommand{}
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	fs.BoolVar(&c.stdin, "stdin", false, `read JSON Lines records {"id", "content", "metadata"} from standard input instead of files`)
+	fs.StringVar(&c.exclude, "exclude", "", "comma-separated glob patterns of file and directory names or paths to skip, e.g. '*_test.go,testdata'")
+	return fs
+}
+
+// NeedsModel reports that the ingest command embeds text and requires a model.
+func (c *IngestCommand) NeedsModel() bool {
+	return true
+}
+
+// Run ingests each path in args. Files are stored under their path, with
+// forward slashes, as the document ID. Directories are walked, skipping
+// hidden and vendored directories, files matching --exclude, files whose
+// extension is not a supported format, and binary, generated, and oversized
+// files. Files named in args are always read. With --stdin, records are read
+// from standard input instead.
+func (c *IngestCommand) Run(rag *RAGSystem, args []string) error {
+	if c.stdin {
+		if len(args) > 0 {
+			return fmt.Errorf("--stdin takes no paths")
+		}
+		return c.ingestStdin(rag)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("usage: %s", c.Usage())
+	}
+	var exclude []string
+	for p := range strings.SplitSeq(c.exclude, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			if _, err := path.Match(p, ""); err != nil {
+				return fmt.Errorf("invalid --exclude pattern %q: %w", p, err)
+			}
+			exclude = append(exclude, p)
+		}
+	}
+
+	ctx := context.Background()
+	report := IngestReport{Files: []IngestedFile{}, Skipped: []SkippedFile{}, Failed: []FailedFile{}}
+	for _, arg := range args {
+		info, err := os.Stat(arg)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			c.ingest(ctx, rag, &report, filepath.Clean(arg), false)
+			continue
+		}
+		if err := filepath.WalkDir(arg, c.walkFunc(ctx, rag, &report, arg, exclude)); err != nil {
+			return fmt.Errorf("failed to walk %s: %w", arg, err)
+		}
+	}
+
+	if err := output.Print(Result{
+		Value: report,
+		Rows:  report.Files,
+		Text:  func(w io.Writer) { printIngestReport(w, report) },
+	}); err != nil {
+		return err
+	}
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("failed to ingest %d of %d files", len(report.Failed), len(report.Files)+len(report.Failed))
+	}
+	return nil
+}
+
+// ingestStdin stores the JSON Lines records read from standard input, in
+// batches as they arrive, and reports the lines that failed.
+func (c *IngestCommand) ingestStdin(rag *RAGSystem) error {
+	ctx := context.Background()
+	report := ImportReport{Source: "stdin", Failed: []RowError{}}
+	w := &recordWriter{rag: rag, size: defaultBatchSize, report: &report}
+
+	sc := bufio.NewScanner(input)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		report.Rows++
+		rec, err := parseRecord(line)
+		if err != nil {
+			report.Failed = append(report.Failed, RowError{Row: n, ID: rec.ID, Error: err.Error()})
+			continue
+		}
+		if err := w.add(ctx, n, rec); err != nil {
+			return err
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return fmt.Errorf("failed to read standard input: %w", err)
+	}
+	if err := w.flush(ctx); err != nil {
+		return err
+	}
+	slices.SortStableFunc(report.Failed, func(a, b RowError) int { return a.Row - b.Row })
+
+	if err := output.Print(Result{
+		Value: report,
+		Rows:  report.Failed,
+		Text:  func(w io.Writer) { printImportReport(w, report) },
+	}); err != nil {
+		return err
+	}
+	if len(report.Failed) > 0 {
+		return fmt.Errorf("failed to import %d of %d records", len(report.Failed), report.Rows)
+	}
+	return nil
+}
+
+// walkFunc returns the function that ingests each file found while walking
+// the directory root. Entries that cannot be read, such as unreadable
+// directories, are reported as failed and skipped, so the walk goes on.
+func (c *IngestCommand) walkFunc(ctx context.Context, rag *RAGSystem, report *IngestReport, root string, exclude []string) fs.WalkDirFunc {
+	return func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			report.Failed = append(report.Failed, FailedFile{Path: p, Error: err.Error()})
+			if d != nil && d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, _ := filepath.Rel(root, p)
+		if rel == "." {
+			return nil
+		}
+		if ignored(d.Name(), filepath.ToSlash(rel), d.IsDir(), exclude) {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.Type().IsRegular() {
+			c.ingest(ctx, rag, report, p, true)
+		}
+		return nil
+	}
+}
+
+// ingest adds the file at p to the report's files, or records why it was
+// skipped or failed. Files found by walking a directory are checked for
+// their format and contents first.
+func (c *IngestCommand) ingest(ctx context.Context, rag *RAGSystem, report *IngestReport, p string, walked bool) {
+	skip := func(reason string) {
+		report.Skipped = append(report.Skipped, SkippedFile{Path: p, Reason: reason})
+	}
+	fail := func(err error) {
+		report.Failed = append(report.Failed, FailedFile{Path: p, Error: err.Error()})
+	}
+
+	if walked && formatForExtension(p) == nil {
+		skip("unsupported")
+		return
+	}
+	limit := ingestConfig().MaxFileSize
+	if info, err := os.Stat(p); err != nil {
+		fail(err)
+		return
+	} else if limit > 0 && info.Size() > limit {
+		skip("too large")
+		return
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		fail(err)
+		return
+	}
+	if walked && isBinary(data) {
+		skip("binary")
+		return
+	}
+	if walked && isGenerated(p, data) {
+		skip("generated")
+		return
+	}
+
+	id := filepath.ToSlash(p)
+	chunkIDs, err := rag.IngestFile(ctx, id, id, "", data)
+	var dup *DuplicateError
+	switch {
+	case errors.As(err, &dup):
+		skip("duplicate")
+	case err != nil:
+		fail(err)
+	default:
+		report.Files = append(report.Files, IngestedFile{ID: id, Chunks: len(chunkIDs)})
+	}
+}
+
+// ignored reports whether a walked file or directory with the given name and
+// slash-separated path relative to the walked directory is skipped: hidden
+// entries, vendored directories, and entries matching an exclude pattern.
+func ignored(name, rel string, dir bool, exclude []string) bool {
+	if strings.HasPrefix(name, ".") || dir && slices.Contains(vendoredDirs, name) {
+		return true
+	}
+	for _, p := range exclude {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// printIngestReport writes the ingested files and a summary of the skipped
+// and failed ones.
+func printIngestReport(w io.Writer, r IngestReport) {
+	chunks := 0
+	for _, f := range r.Files {
+		fmt.Fprintf(w, "Ingested %s (%d chunks)\n", f.ID, f.Chunks)
+		chunks += f.Chunks
+	}
+	for _, f := range r.Failed {
+		fmt.Fprintf(w, "Failed %s: %s\n", f.Path, f.Error)
+	}
+	fmt.Fprintf(w, "\n%d files ingested as %d chunks", len(r.Files), chunks)
+	if len(r.Skipped) > 0 {
+		counts := make(map[string]int)
+		var reasons []string
+		for _, s := range r.Skipped {
+			if counts[s.Reason] == 0 {
+				reasons = append(reasons, s.Reason)
+			}
+			counts[s.Reason]++
+		}
+		parts := make([]string, len(reasons))
+		for i, reason := range reasons {
+			parts[i] = fmt.Sprintf("%d %s", counts[reason], reason)
+		}
+		fmt.Fprintf(w, ", %d skipped (%s)", len(r.Skipped), strings.Join(parts, ", "))
+	}
+	if len(r.Failed) > 0 {
+		fmt.Fprintf(w, ", %d failed", len(r.Failed))
+	}
+	fmt.Fprintln(w)
+}
@@ -1,6 +1,10 @@
 package main
 
-import "fmt"
+import (
+	"flag"
+	"fmt"
+	"io"
+)
 
 func init() {
 	RegisterCommand(&ListCommand{})
@@ -24,6 +28,16 @@ func (c *ListCommand) Usage() string {
 	return "list"
 }
 
+// Flags returns the list command's flag set, which defines no flags.
+func (c *ListCommand) Flags() *flag.FlagSet {
+	return flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+}
+
+// NeedsModel reports that the list command does not require a model.
+func (c *ListCommand) NeedsModel() bool {
+	return false
+}
+
 // Run executes the list command, printing all documents in the RAG system's
 // knowledge base with their IDs and truncated content.
 func (c *ListCommand) Run(rag *RAGSystem, args []string) error {
@@ -32,10 +46,16 @@ func (c *ListCommand) Run(rag *RAGSystem, args []string) error {
 		return fmt.Errorf("failed to list documents: %w", err)
 	}
 
-	fmt.Printf("Documents in knowledge base (%d total):\n\n", len(docs))
-	for _, doc := range docs {
-		fmt.Printf("  %s: %s\n", doc.ID, truncate(doc.Content, 80))
+	if docs == nil {
+		docs = []Document{}
 	}
-
-	return nil
+	return output.Print(Result{
+		Value: docs,
+		Text: func(w io.Writer) {
+			fmt.Fprintf(w, "Documents in knowledge base (%d total):\n\n", len(docs))
+			for _, doc := range docs {
+				fmt.Fprintf(w, "  %s: %s\n", doc.ID, truncate(doc.Content, 80))
+			}
+		},
+	})
 }
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
+	"io"
 	"strconv"
+	"strings"
 )
 
 func init() {
@@ -10,7 +14,9 @@ func init() {
 }
 
 // QueryCommand implements the "query" CLI command for searching the knowledge base by similarity.
-type QueryCommand struct{}
+type QueryCommand struct {
+	where string
+}
 
 // Name returns the command name "query".
 func (c *QueryCommand) Name() string {
@@ -24,7 +30,20 @@ func (c *QueryCommand) Description() string {
 
 // Usage returns the usage string showing expected arguments for the query command.
 func (c *QueryCommand) Usage() string {
-	return "query <text> [top_k]"
+	return "query [--where key=value,...] <text> [top_k]"
+}
+
+// Flags returns the query command's flag set, defining --where.
+func (c *QueryCommand) Flags() *flag.FlagSet {
+	*c = QueryCommand{}
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	fs.StringVar(&c.where, "where", "", "comma-separated key=value pairs the metadata of results must match, e.g. 'thread=m0@example.com'")
+	return fs
+}
+
+// NeedsModel reports that the query command embeds text and requires a model.
+func (c *QueryCommand) NeedsModel() bool {
+	return true
 }
 
 // Run executes the query command, searching the RAG system for documents similar
@@ -33,6 +52,10 @@ func (c *QueryCommand) Run(rag *RAGSystem, args []string) error {
 	if len(args) < 1 {
 		return fmt.Errorf("usage: %s", c.Usage())
 	}
+	where, err := parseWhere(c.where)
+	if err != nil {
+		return err
+	}
 
 	query := args[0]
 	topK := 5
@@ -43,15 +66,38 @@ func (c *QueryCommand) Run(rag *RAGSystem, args []string) error {
 		}
 	}
 
-	results, err := rag.Query(query, topK)
+	results, err := rag.Query(context.Background(), query, topK, where)
 	if err != nil {
 		return fmt.Errorf("failed to query: %w", err)
 	}
 
-	fmt.Printf("\nTop %d results for: %q\n\n", topK, query)
-	for i, r := range results {
-		fmt.Printf("%d. [%.4f] %s: %s\n", i+1, r.Score, r.ID, truncate(r.Content, 100))
+	if results == nil {
+		results = []SearchResult{}
 	}
+	return output.Print(Result{
+		Value: results,
+		Text: func(w io.Writer) {
+			fmt.Fprintf(w, "\nTop %d results for: %q\n\n", topK, query)
+			for i, r := range results {
+				fmt.Fprintf(w, "%d. [%.4f] %s: %s\n", i+1, r.Score, resultLabel(r), truncate(r.Content, 100))
+			}
+		},
+	})
+}
 
-	return nil
+// parseWhere parses the --where flag into metadata keys and the values they
+// must have.
+func parseWhere(s string) (map[string]string, error) {
+	where := make(map[string]string)
+	for pair := range strings.SplitSeq(s, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(pair, "=")
+		if key = strings.TrimSpace(key); !ok || key == "" {
+			return nil, fmt.Errorf("invalid --where %q: want key=value", pair)
+		}
+		where[key] = strings.TrimSpace(value)
+	}
+	return where, nil
 }
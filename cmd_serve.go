@@ -2,9 +2,12 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 )
 
@@ -13,7 +16,14 @@ func init() {
 }
 
 // ServeCommand implements the "serve" CLI command for starting the MCP server.
-type ServeCommand struct{}
+// Its flags override the corresponding server settings from the configuration.
+type ServeCommand struct {
+	transport      string
+	port           string
+	host           string
+	readOnly       bool
+	allowedOrigins string
+}
 
 // Name returns the command name "serve".
 func (c *ServeCommand) Name() string {
@@ -27,12 +37,38 @@ func (c *ServeCommand) Description() string {
 
 // Usage returns the usage string showing available flags for the serve command.
 func (c *ServeCommand) Usage() string {
-	return "serve [--transport stdio|sse|streamable-http] [--port PORT]"
+	return "serve [--transport stdio|sse|streamable-http] [--port PORT] [--host HOST] [--read-only] [--allowed-origins LIST]"
+}
+
+// Flags returns the serve command's flag set. Unset flags leave the configured
+// values in place.
+func (c *ServeCommand) Flags() *flag.FlagSet {
+	*c = ServeCommand{}
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	fs.StringVar(&c.transport, "transport", "", "MCP transport: stdio, sse, or streamable-http (default from config)")
+	fs.StringVar(&c.port, "port", "", "port for the sse and streamable-http transports (default from config)")
+	fs.StringVar(&c.host, "host", "", "bind address for the sse and streamable-http transports (default from config)")
+	fs.BoolVar(&c.readOnly, "read-only", false, "expose only tools that do not modify the knowledge base")
+	fs.StringVar(&c.allowedOrigins, "allowed-origins", "", "comma-separated browser origins allowed to connect (default from config)")
+	return fs
+}
+
+// NeedsModel reports that the serve command embeds text and requires a model.
+func (c *ServeCommand) NeedsModel() bool {
+	return true
 }
 
 // Run starts the MCP server using the configured transport and port, and blocks
 // until the context is cancelled by a SIGINT or SIGTERM signal.
 func (c *ServeCommand) Run(rag *RAGSystem, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("unexpected arguments: %s\nusage: %s", strings.Join(args, " "), c.Usage())
+	}
+	c.applyOverrides(&cfg.Server)
+	if err := rag.LoadModel(); err != nil {
+		return err
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
@@ -46,11 +82,9 @@ func (c *ServeCommand) Run(rag *RAGSystem, args []string) error {
 	server := NewMCPServer(rag)
 
 	transport := cfg.Server.Transport
-	addr := ":" + cfg.Server.Port
+	addr := listenAddr(cfg.Server)
 
-	if *verbose {
-		fmt.Fprintf(os.Stderr, "Starting MCP server (transport=%s)...\n", transport)
-	}
+	slog.Debug("starting MCP server", "transport", transport, "read_only", cfg.Server.ReadOnly)
 
 	if err := server.Run(ctx, transport, addr); err != nil {
 		return fmt.Errorf("MCP server error: %w", err)
@@ -58,3 +92,22 @@ func (c *ServeCommand) Run(rag *RAGSystem, args []string) error {
 
 	return nil
 }
+
+// applyOverrides copies any flags given on the command line into sc.
+func (c *ServeCommand) applyOverrides(sc *ServerConfig) {
+	if c.transport != "" {
+		sc.Transport = c.transport
+	}
+	if c.port != "" {
+		sc.Port = c.port
+	}
+	if c.host != "" {
+		sc.Host = c.host
+	}
+	if c.readOnly {
+		sc.ReadOnly = true
+	}
+	if c.allowedOrigins != "" {
+		sc.AllowedOrigins = splitList(c.allowedOrigins)
+	}
+}
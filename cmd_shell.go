@@ -0,0 +1,95 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/peterh/liner"
+)
+
+func init() {
+	RegisterCommand(&ShellCommand{})
+}
+
+// ShellCommand implements the "shell" CLI command, an interactive session that
+// loads the model once and runs commands against it with line editing and
+// history.
+type ShellCommand struct {
+	history string
+}
+
+// Name returns the command name "shell".
+func (c *ShellCommand) Name() string {
+	return "shell"
+}
+
+// Description returns a short summary of what the shell command does.
+func (c *ShellCommand) Description() string {
+	return "Start an interactive shell that keeps the model loaded"
+}
+
+// Usage returns the usage string for the shell command.
+func (c *ShellCommand) Usage() string {
+	return "shell [--history FILE]"
+}
+
+// Flags returns the shell command's flag set.
+func (c *ShellCommand) Flags() *flag.FlagSet {
+	*c = ShellCommand{}
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	fs.StringVar(&c.history, "history", defaultHistoryPath(), "file to keep command history in; empty disables history")
+	return fs
+}
+
+// NeedsModel reports that the shell command does not require a model; one is
+// loaded on first use by a command that embeds text.
+func (c *ShellCommand) NeedsModel() bool {
+	return false
+}
+
+// Run starts the shell on the terminal and saves the command history when it
+// ends.
+func (c *ShellCommand) Run(rag *RAGSystem, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: %s", c.Usage())
+	}
+
+	sh := newShell(rag, os.Stdout)
+	line := liner.NewLiner()
+	defer line.Close()
+	line.SetCtrlCAborts(true)
+	line.SetCompleter(sh.complete)
+
+	if c.history != "" {
+		if f, err := os.Open(c.history); err == nil {
+			line.ReadHistory(f)
+			f.Close()
+		}
+	}
+
+	fmt.Println("ydrag shell. Type 'help' for commands, 'exit' or Ctrl-D to quit.")
+	err := sh.Run(line)
+
+	if c.history != "" {
+		f, herr := os.Create(c.history)
+		if herr != nil {
+			rag.log().Warn("failed to save shell history", "file", c.history, "error", herr)
+			return err
+		}
+		line.WriteHistory(f)
+		f.Close()
+	}
+	return err
+}
+
+// defaultHistoryPath returns ~/.ydrag_history, or an empty path when the home
+// directory is unknown.
+func defaultHistoryPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".ydrag_history")
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+func init() {
+	RegisterCommand(&SimilarCommand{})
+}
+
+// SimilarCommand implements the "similar" CLI command, which finds documents
+// like an existing one by searching with its stored embedding.
+type SimilarCommand struct{}
+
+// Name returns the command name "similar".
+func (c *SimilarCommand) Name() string {
+	return "similar"
+}
+
+// Description returns a short summary of what the similar command does.
+func (c *SimilarCommand) Description() string {
+	return "Find documents similar to an existing document"
+}
+
+// Usage returns the usage string showing expected arguments for the similar command.
+func (c *SimilarCommand) Usage() string {
+	return "similar <id> [top_k]"
+}
+
+// Flags returns the similar command's flag set, which defines no flags.
+func (c *SimilarCommand) Flags() *flag.FlagSet {
+	return flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+}
+
+// NeedsModel reports that the similar command does not require a model; it
+// searches with a stored embedding.
+func (c *SimilarCommand) NeedsModel() bool {
+	return false
+}
+
+// Run searches for the top-k documents most similar to the document, chunk,
+// or ingested file with the given id, excluding that document itself.
+func (c *SimilarCommand) Run(rag *RAGSystem, args []string) error {
+	if len(args) < 1 || len(args) > 2 {
+		return fmt.Errorf("usage: %s", c.Usage())
+	}
+
+	id := args[0]
+	topK := 5
+	if len(args) == 2 {
+		k, err := strconv.Atoi(args[1])
+		if err != nil || k <= 0 {
+			return fmt.Errorf("top_k must be a positive integer, got %q", args[1])
+		}
+		topK = k
+	}
+
+	results, err := rag.Similar(context.Background(), id, topK)
+	if err != nil {
+		return fmt.Errorf("failed to find similar documents: %w", err)
+	}
+
+	if results == nil {
+		results = []SearchResult{}
+	}
+	return output.Print(Result{
+		Value: results,
+		Text: func(w io.Writer) {
+			fmt.Fprintf(w, "\nTop %d documents similar to %s\n\n", topK, id)
+			for i, r := range results {
+				fmt.Fprintf(w, "%d. [%.4f] %s: %s\n", i+1, r.Score, resultLabel(r), truncate(r.Content, 100))
+			}
+		},
+	})
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+)
+
+func init() {
+	RegisterCommand(&StatsCommand{})
+}
+
+// StatsCommand implements the "stats" CLI command, which reports document
+// counts, content sizes, the embedding model, and storage for the knowledge
+// base.
+type StatsCommand struct {
+	top int
+}
+
+// Name returns the command name "stats".
+func (c *StatsCommand) Name() string {
+	return "stats"
+}
+
+// Description returns a short summary of what the stats command does.
+func (c *StatsCommand) Description() string {
+	return "Show knowledge base statistics"
+}
+
+// Usage returns the usage string for the stats command.
+func (c *StatsCommand) Usage() string {
+	return "stats [--top N]"
+}
+
+// Flags returns the stats command's flag set.
+func (c *StatsCommand) Flags() *flag.FlagSet {
+	*c = StatsCommand{}
+	fs := flag.NewFlagSet(c.Name(), flag.ContinueOnError)
+	fs.IntVar(&c.top, "top", 10, "number of sources and largest documents to list")
+	return fs
+}
+
+// NeedsModel reports that the stats command does not require a model.
+func (c *StatsCommand) NeedsModel() bool {
+	return false
+}
+
+// Run computes and prints the statistics of the whole knowledge base, across
+// all collections.
+func (c *StatsCommand) Run(rag *RAGSystem, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: %s", c.Usage())
+	}
+	if c.top < 0 {
+		return fmt.Errorf("--top must not be negative")
+	}
+
+	stats, err := rag.Stats(c.top)
+	if err != nil {
+		return err
+	}
+	return output.Print(Result{
+		Value: stats,
+		Text:  func(w io.Writer) { printStats(w, stats) },
+	})
+}
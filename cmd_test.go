@@ -1,6 +1,13 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 )
@@ -70,6 +77,16 @@ func TestQueryCommand_MissingArgs(t *testing.T) {
 	}
 }
 
+func TestParseWhere(t *testing.T) {
+	where, err := parseWhere(" from=alice@example.com, thread = t1 ,")
+	if want := map[string]string{"from": "alice@example.com", "thread": "t1"}; err != nil || !reflect.DeepEqual(where, want) {
+		t.Errorf("parseWhere = %v, %v; want %v", where, err, want)
+	}
+	if err := RunCommand(&QueryCommand{}, nil, []string{"--where", "from", "text"}); err == nil || !strings.Contains(err.Error(), "key=value") {
+		t.Errorf("query --where from: err = %v, want key=value error", err)
+	}
+}
+
 func TestQueryCommand_Name(t *testing.T) {
 	cmd := &QueryCommand{}
 	if cmd.Name() != "query" {
@@ -90,3 +107,261 @@ func TestListCommand_Name(t *testing.T) {
 		t.Fatalf("expected name 'list', got: %s", cmd.Name())
 	}
 }
+
+func TestServeCommand_Flags(t *testing.T) {
+	cmd := &ServeCommand{}
+	fs := cmd.Flags()
+	err := fs.Parse([]string{"--transport", "sse", "--port", "9999", "--host", "127.0.0.1", "--read-only", "--allowed-origins", "http://a.example, http://b.example"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sc := DefaultConfig().Server
+	cmd.applyOverrides(&sc)
+
+	if sc.Transport != "sse" {
+		t.Errorf("Transport = %q, want %q", sc.Transport, "sse")
+	}
+	if sc.Port != "9999" {
+		t.Errorf("Port = %q, want %q", sc.Port, "9999")
+	}
+	if sc.Host != "127.0.0.1" {
+		t.Errorf("Host = %q, want %q", sc.Host, "127.0.0.1")
+	}
+	if !sc.ReadOnly {
+		t.Error("ReadOnly = false, want true")
+	}
+	if len(sc.AllowedOrigins) != 2 || sc.AllowedOrigins[1] != "http://b.example" {
+		t.Errorf("AllowedOrigins = %v, want [http://a.example http://b.example]", sc.AllowedOrigins)
+	}
+}
+
+func TestServeCommand_FlagsKeepConfig(t *testing.T) {
+	cmd := &ServeCommand{}
+	if err := cmd.Flags().Parse(nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sc := DefaultConfig().Server
+	sc.Transport = "streamable-http"
+	sc.AllowedOrigins = []string{"http://x.example"}
+	cmd.applyOverrides(&sc)
+
+	if sc.Transport != "streamable-http" || sc.Port != "8080" || sc.ReadOnly {
+		t.Errorf("expected config values to be kept, got %+v", sc)
+	}
+	if len(sc.AllowedOrigins) != 1 {
+		t.Errorf("AllowedOrigins = %v, want config value", sc.AllowedOrigins)
+	}
+}
+
+func TestHelpCommand_UnknownCommand(t *testing.T) {
+	cmd := &HelpCommand{}
+	if err := cmd.Run(nil, []string{"nonexistent"}); err == nil {
+		t.Fatal("expected error for unknown command")
+	}
+}
+
+func TestExportCommand(t *testing.T) {
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "file#0", "chunk text", "file", `{"source":"a.txt"}`, []float32{1, 0, 0})
+	insertTestDoc(t, rag, "note", "standalone", "", "", []float32{0, 0.5, 0})
+	buf := captureOutput(t, formatText)
+
+	if err := RunCommand(&ExportCommand{}, rag, []string{"-embeddings"}); err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected 2 lines, got %q", buf.String())
+	}
+	var rec exportRecord
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	if rec.ID != "file#0" || rec.ParentID != "file" || rec.Metadata["source"] != "a.txt" || len(rec.Embedding) != 3 || rec.Embedding[0] != 1 {
+		t.Errorf("first record = %+v", rec)
+	}
+
+	path := filepath.Join(t.TempDir(), "kb.jsonl")
+	if err := RunCommand(&ExportCommand{}, rag, []string{"-o", path}); err != nil {
+		t.Fatalf("export to file failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(data), "\n") != 2 || strings.Contains(string(data), "embedding") {
+		t.Errorf("file export = %q", data)
+	}
+}
+
+func TestSimilarCommand(t *testing.T) {
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "a", "alpha", "", "", []float32{1, 0, 0})
+	insertTestDoc(t, rag, "b", "beta", "", "", []float32{0.8, 0.6, 0})
+	insertTestDoc(t, rag, "c", "gamma", "", "", []float32{0, 0, 1})
+	buf := captureOutput(t, formatJSON)
+
+	if err := RunCommand(&SimilarCommand{}, rag, []string{"a", "1"}); err != nil {
+		t.Fatalf("similar failed: %v", err)
+	}
+	var results []SearchResult
+	if err := json.Unmarshal(buf.Bytes(), &results); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if len(results) != 1 || results[0].ID != "b" {
+		t.Errorf("results = %+v, want b", results)
+	}
+
+	for _, args := range [][]string{nil, {"a", "zero"}, {"a", "0"}, {"a", "1", "2"}} {
+		if err := RunCommand(&SimilarCommand{}, rag, args); err == nil {
+			t.Errorf("expected error for args %q", args)
+		}
+	}
+}
+
+func TestIngestCommand_SkipsFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.go":              "package main\n\nfunc main() {}\n",
+		"api.pb.go":            "package main\n",
+		"gen.go":               "// Code generated by stringer; DO NOT EDIT.\n\npackage main\n",
+		"logo.png":             "\x89PNG\r\n\x1a\n",
+		"blob.txt":             "a\x00b",
+		"go.sum":               "example.com/x v1.0.0 h1:abc=\n",
+		"main_test.go":         "package main\n",
+		"vendor/lib/lib.go":    "package lib\n",
+		".git/config":          "[core]\n",
+		"testdata/fixture.txt": "fixture\n",
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	rag := newTestRAG(t)
+	buf := captureOutput(t, formatJSON)
+	cmd := &IngestCommand{}
+	fs := cmd.Flags()
+	if err := fs.Parse([]string{"--exclude", "*_test.go, testdata"}); err != nil {
+		t.Fatal(err)
+	}
+	// Without a model the one file that is read fails to embed.
+	if err := cmd.Run(rag, []string{dir}); err == nil || !strings.Contains(err.Error(), "failed to ingest 1 of 1 files") {
+		t.Errorf("expected ingest failure, got %v", err)
+	}
+
+	var report IngestReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Path != filepath.Join(dir, "main.go") {
+		t.Errorf("failed = %+v, want main.go", report.Failed)
+	}
+	skipped := make(map[string]string)
+	for _, s := range report.Skipped {
+		rel, _ := filepath.Rel(dir, s.Path)
+		skipped[filepath.ToSlash(rel)] = s.Reason
+	}
+	want := map[string]string{
+		"api.pb.go": "generated",
+		"gen.go":    "generated",
+		"logo.png":  "unsupported",
+		"blob.txt":  "binary",
+		"go.sum":    "unsupported",
+	}
+	if !reflect.DeepEqual(skipped, want) {
+		t.Errorf("skipped = %v, want %v", skipped, want)
+	}
+
+	if err := RunCommand(&IngestCommand{}, rag, nil); err == nil {
+		t.Error("expected usage error without paths")
+	}
+	if err := RunCommand(&IngestCommand{}, rag, []string{filepath.Join(dir, "missing")}); err == nil {
+		t.Error("expected error for a missing path")
+	}
+}
+
+// setInput replaces the standard input read by commands for the rest of the test.
+func setInput(t *testing.T, s string) {
+	t.Helper()
+	old := input
+	input = strings.NewReader(s)
+	t.Cleanup(func() { input = old })
+}
+
+func TestAddCommand_Stdin(t *testing.T) {
+	rag := newTestRAG(t)
+	captureOutput(t, formatText)
+
+	for _, tt := range []struct{ in, want string }{
+		{" \n\t", "no content on standard input"},
+		{"bad \xff bytes", "not valid UTF-8"},
+		// Without a model, reading succeeds and embedding fails.
+		{"line one\nline two\n", "failed to add document"},
+	} {
+		setInput(t, tt.in)
+		if err := RunCommand(&AddCommand{}, rag, []string{"doc", "-"}); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("add - with %q: error %v, want %q", tt.in, err, tt.want)
+		}
+	}
+}
+
+func TestIngestCommand_UnreadableDirectory(t *testing.T) {
+	dir := t.TempDir()
+	sub := filepath.Join(dir, "private")
+	if err := os.Mkdir(sub, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(sub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Directories that cannot be read are reported and skipped instead of
+	// ending the walk; tests may run as root, so the error is simulated.
+	var report IngestReport
+	walk := (&IngestCommand{}).walkFunc(context.Background(), newTestRAG(t), &report, dir, nil)
+	if err := walk(sub, fs.FileInfoToDirEntry(info), fs.ErrPermission); err != filepath.SkipDir {
+		t.Errorf("walk returned %v, want SkipDir", err)
+	}
+	if len(report.Failed) != 1 || report.Failed[0].Path != sub || !strings.Contains(report.Failed[0].Error, "permission denied") {
+		t.Errorf("failed = %+v", report.Failed)
+	}
+	if err := walk(filepath.Join(dir, "gone.go"), nil, fs.ErrNotExist); err != nil {
+		t.Errorf("walk returned %v for an unreadable file, want nil", err)
+	}
+}
+
+func TestIngestCommand_Stdin(t *testing.T) {
+	rag := newTestRAG(t)
+	buf := captureOutput(t, formatJSON)
+	setInput(t, `{"id": "a", "content": "Alpha", "metadata": {"team": "ops", "n": 2}}`+"\n\n"+
+		`{"id": "b", "content": ""}`+"\n"+
+		`not json`+"\n")
+
+	if err := RunCommand(&IngestCommand{}, rag, []string{"--stdin"}); err == nil || !strings.Contains(err.Error(), "failed to import 3 of 3 records") {
+		t.Errorf("expected import failure, got %v", err)
+	}
+	var report ImportReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	var got []string
+	for _, f := range report.Failed {
+		got = append(got, fmt.Sprintf("%d %s", f.Row, f.ID))
+	}
+	if want := []string{"1 a", "3 b", "4 "}; report.Source != "stdin" || !reflect.DeepEqual(got, want) {
+		t.Errorf("report = %+v, want failed rows %q", report, want)
+	}
+
+	if err := RunCommand(&IngestCommand{}, rag, []string{"--stdin", "docs"}); err == nil {
+		t.Error("expected error for paths with --stdin")
+	}
+}
@@ -0,0 +1,467 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// codeLanguage describes how to split the source files of a programming
+// language at their top-level declarations.
+type codeLanguage struct {
+	name       string
+	extensions []string
+
+	// declarations match the first line of an unindented declaration; the
+	// first non-empty submatch is the declared symbol.
+	declarations []*regexp.Regexp
+
+	// leading are prefixes of the comment, decorator, and attribute lines
+	// that belong to the declaration following them.
+	leading []string
+
+	// split, if set, replaces the heuristic; it returns nil if src cannot be
+	// parsed, and the heuristic is used instead.
+	split func(src string) []codeDecl
+}
+
+// codeDecl is a top-level declaration of a source file, spanning the lines
+// from start to end inclusive, counted from 1. Lines before the first
+// declaration, such as a package clause and imports, form a declaration
+// without a symbol.
+type codeDecl struct {
+	symbol     string
+	kind       string // "func", "method", "type", "var", or "const"; Go only
+	start, end int
+}
+
+// cDecl matches C and C++ function definitions: an unindented line naming a
+// function followed by its parameter list, not ending in a semicolon.
+var cDecl = regexp.MustCompile(`^(?:[A-Za-z_][\w\s\*&:<>,]*?[\s\*&])?([A-Za-z_][\w:~]*)\s*\([^;]*$`)
+
+var jsDecls = []*regexp.Regexp{
+	regexp.MustCompile(`^(?:export\s+(?:default\s+)?)?(?:async\s+)?function\*?\s*([\w$]+)`),
+	regexp.MustCompile(`^(?:export\s+(?:default\s+)?)?(?:abstract\s+)?class\s+([\w$]+)`),
+	regexp.MustCompile(`^(?:export\s+)?(?:declare\s+)?(?:interface|type|enum|namespace)\s+([\w$]+)`),
+	regexp.MustCompile(`^(?:export\s+)?(?:const|let|var)\s+([\w$]+)`),
+}
+
+var jvmDecl = regexp.MustCompile(`^(?:@\w+\s+)*(?:(?:public|private|protected|internal|abstract|final|sealed|static|data|open|partial|case)\s+)*(?:class|interface|enum|record|object|struct|trait)\s+(\w+)`)
+
+// codeLanguages are the programming languages whose source files are ingested
+// one declaration at a time.
+var codeLanguages = []*codeLanguage{
+	{
+		name:         "Go",
+		extensions:   []string{".go"},
+		declarations: []*regexp.Regexp{regexp.MustCompile(`^(?:func\s+(?:\([^)]*\)\s*)?(\w+)|type\s+(\w+)|var\s+(\w+)|const\s+(\w+))`)},
+		leading:      []string{"//", "/*", "*"},
+		split:        splitGo,
+	},
+	{
+		name:       "Python",
+		extensions: []string{".py", ".pyi"},
+		declarations: []*regexp.Regexp{
+			regexp.MustCompile(`^(?:async\s+)?def\s+(\w+)`),
+			regexp.MustCompile(`^class\s+(\w+)`),
+		},
+		leading: []string{"#", "@"},
+	},
+	{
+		name:         "JavaScript",
+		extensions:   []string{".js", ".mjs", ".cjs", ".jsx"},
+		declarations: jsDecls,
+		leading:      []string{"//", "/*", "*", "@"},
+	},
+	{
+		name:         "TypeScript",
+		extensions:   []string{".ts", ".mts", ".cts", ".tsx"},
+		declarations: jsDecls,
+		leading:      []string{"//", "/*", "*", "@"},
+	},
+	{
+		name:       "Rust",
+		extensions: []string{".rs"},
+		declarations: []*regexp.Regexp{
+			regexp.MustCompile(`^(?:pub(?:\([^)]*\))?\s+)?(?:(?:async|const|unsafe|extern\s+"\w+")\s+)*(?:fn|struct|enum|trait|mod|type|const|static|union)\s+(\w+)`),
+			regexp.MustCompile(`^(?:unsafe\s+)?impl(?:<[^>]*>)?\s+(?:[\w:<>, ]+\s+for\s+)?([\w:]+)`),
+			regexp.MustCompile(`^macro_rules!\s*(\w+)`),
+		},
+		leading: []string{"//", "/*", "*", "#["},
+	},
+	{
+		name:       "C",
+		extensions: []string{".c", ".h"},
+		declarations: []*regexp.Regexp{
+			regexp.MustCompile(`^(?:typedef\s+)?(?:struct|union|enum)\s+(\w+)`),
+			cDecl,
+		},
+		leading: []string{"//", "/*", "*"},
+	},
+	{
+		name:       "C++",
+		extensions: []string{".cc", ".cpp", ".cxx", ".hh", ".hpp", ".hxx"},
+		declarations: []*regexp.Regexp{
+			regexp.MustCompile(`^(?:template\s*<.*>\s*)?(?:typedef\s+)?(?:struct|union|enum(?:\s+class)?|class|namespace)\s+(\w+)`),
+			cDecl,
+		},
+		leading: []string{"//", "/*", "*", "template"},
+	},
+	{
+		name:         "Java",
+		extensions:   []string{".java"},
+		declarations: []*regexp.Regexp{jvmDecl},
+		leading:      []string{"//", "/*", "*", "@"},
+	},
+	{
+		name:       "Kotlin",
+		extensions: []string{".kt", ".kts"},
+		declarations: []*regexp.Regexp{
+			jvmDecl,
+			regexp.MustCompile(`^(?:(?:public|private|internal|inline|suspend|operator)\s+)*fun\s+(?:<[^>]*>\s*)?(?:[\w.]+\.)?(\w+)`),
+		},
+		leading: []string{"//", "/*", "*", "@"},
+	},
+	{
+		name:         "C#",
+		extensions:   []string{".cs"},
+		declarations: []*regexp.Regexp{jvmDecl, regexp.MustCompile(`^namespace\s+([\w.]+)\s*\{`)},
+		leading:      []string{"//", "/*", "*", "["},
+	},
+	{
+		name:       "Ruby",
+		extensions: []string{".rb"},
+		declarations: []*regexp.Regexp{
+			regexp.MustCompile(`^def\s+(?:self\.)?([\w?!=]+)`),
+			regexp.MustCompile(`^(?:class|module)\s+([\w:]+)`),
+		},
+		leading: []string{"#"},
+	},
+	{
+		name:       "PHP",
+		extensions: []string{".php"},
+		declarations: []*regexp.Regexp{
+			regexp.MustCompile(`^(?:(?:abstract|final|readonly)\s+)*(?:class|interface|trait|enum)\s+(\w+)`),
+			regexp.MustCompile(`^function\s+&?(\w+)`),
+		},
+		leading: []string{"//", "/*", "*", "#"},
+	},
+	{
+		name:       "shell",
+		extensions: []string{".sh", ".bash", ".zsh"},
+		declarations: []*regexp.Regexp{
+			regexp.MustCompile(`^function\s+([\w:.-]+)`),
+			regexp.MustCompile(`^([\w:.-]+)\s*\(\)`),
+		},
+		leading: []string{"#"},
+	},
+}
+
+func init() {
+	for _, lang := range codeLanguages {
+		registerFormat(&fileFormat{
+			name:       lang.name,
+			extensions: lang.extensions,
+			read: func(data []byte) ([]section, map[string]string, error) {
+				if !utf8.Valid(data) {
+					return nil, nil, fmt.Errorf("invalid UTF-8")
+				}
+				return lang.sections(string(data)), map[string]string{"language": lang.name}, nil
+			},
+		})
+	}
+}
+
+// sections splits src into one section per top-level declaration. Each
+// section's metadata records its line range as "lines", such as "12-40", and
+// the declared symbol, if any, as "symbol"; the symbol is also its title.
+func (lang *codeLanguage) sections(src string) []section {
+	var decls []codeDecl
+	if lang.split != nil {
+		decls = lang.split(src)
+	}
+	if decls == nil {
+		decls = lang.declare(src)
+	}
+
+	lines := strings.SplitAfter(src, "\n")
+	var sections []section
+	for _, d := range decls {
+		text := strings.Join(lines[d.start-1:d.end], "")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		meta := map[string]string{"lines": lineRange(d.start, d.end)}
+		if d.symbol != "" {
+			meta["symbol"] = d.symbol
+		}
+		if d.kind != "" {
+			meta["kind"] = d.kind
+		}
+		sections = append(sections, section{
+			title:     d.symbol,
+			text:      text,
+			metadata:  meta,
+			split:     chunkCode,
+			firstLine: d.start,
+		})
+	}
+	return sections
+}
+
+// declare finds the top-level declarations of src with the language's
+// patterns. A declaration runs until the next one starts, and takes the
+// comment and decorator lines directly above it.
+func (lang *codeLanguage) declare(src string) []codeDecl {
+	lines := strings.Split(strings.TrimSuffix(src, "\n"), "\n")
+	decls := []codeDecl{{start: 1}}
+	for i, line := range lines {
+		symbol, ok := lang.match(line)
+		if !ok {
+			continue
+		}
+		start := i + 1
+		for start > 1 && lang.isLeading(lines[start-2]) {
+			start--
+		}
+		if last := &decls[len(decls)-1]; start > last.start {
+			last.end = start - 1
+		} else if len(decls) > 1 {
+			// The declaration shares its leading lines with the previous one,
+			// as in a definition spread over several matching lines.
+			continue
+		} else {
+			// The file starts with the declaration.
+			decls = decls[:0]
+		}
+		decls = append(decls, codeDecl{symbol: symbol, start: start})
+	}
+	decls[len(decls)-1].end = len(lines)
+	return decls
+}
+
+// match reports whether line starts a top-level declaration, and returns the
+// declared symbol.
+func (lang *codeLanguage) match(line string) (string, bool) {
+	if line == "" || line[0] == ' ' || line[0] == '\t' {
+		return "", false
+	}
+	for _, re := range lang.declarations {
+		m := re.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		for _, s := range m[1:] {
+			if s != "" {
+				return s, true
+			}
+		}
+		return "", true
+	}
+	return "", false
+}
+
+// isLeading reports whether line is an unindented comment, decorator, or
+// attribute line, or a continuation of a block comment, that belongs to the
+// declaration below it.
+func (lang *codeLanguage) isLeading(line string) bool {
+	if strings.HasPrefix(line, " *") && slices.Contains(lang.leading, "*") {
+		return true
+	}
+	if line == "" || line[0] == ' ' || line[0] == '\t' {
+		return false
+	}
+	for _, p := range lang.leading {
+		if strings.HasPrefix(line, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitGo finds the top-level declarations of Go source with go/parser. The
+// package clause and imports form the first declaration; each later one
+// starts at its doc comment and runs until the next one starts, so that
+// comments between declarations stay with the one above them. It returns nil
+// if src does not parse.
+func splitGo(src string) []codeDecl {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil
+	}
+
+	line := func(p token.Pos) int { return fset.Position(p).Line }
+	decls := []codeDecl{{start: 1}}
+	for _, d := range f.Decls {
+		var decl codeDecl
+		start := d.Pos()
+		switch d := d.(type) {
+		case *ast.FuncDecl:
+			decl.symbol, decl.kind = d.Name.Name, "func"
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				decl.symbol, decl.kind = receiverName(d.Recv.List[0].Type)+"."+d.Name.Name, "method"
+			}
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		case *ast.GenDecl:
+			if d.Tok == token.IMPORT {
+				continue
+			}
+			decl.kind = d.Tok.String()
+			decl.symbol = strings.Join(specNames(d.Specs), ", ")
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+		}
+		decl.start = line(start)
+		decls[len(decls)-1].end = decl.start - 1
+		decls = append(decls, decl)
+	}
+	decls[len(decls)-1].end = strings.Count(strings.TrimSuffix(src, "\n"), "\n") + 1
+	return decls
+}
+
+// receiverName returns the type name of a method receiver, without pointer
+// or type parameters.
+func receiverName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return receiverName(e.X)
+	case *ast.IndexExpr:
+		return receiverName(e.X)
+	case *ast.IndexListExpr:
+		return receiverName(e.X)
+	case *ast.ParenExpr:
+		return receiverName(e.X)
+	case *ast.Ident:
+		return e.Name
+	}
+	return ""
+}
+
+// specNames returns the names declared by the specs of a type, var, or const
+// declaration.
+func specNames(specs []ast.Spec) []string {
+	var names []string
+	for _, s := range specs {
+		switch s := s.(type) {
+		case *ast.TypeSpec:
+			names = append(names, s.Name.Name)
+		case *ast.ValueSpec:
+			for _, n := range s.Names {
+				if n.Name != "_" {
+					names = append(names, n.Name)
+				}
+			}
+		}
+	}
+	return names
+}
+
+// chunkCode splits source code into chunks of at most size bytes made of
+// whole lines, preferring to break at blank lines in the second half of a
+// chunk. Indentation is kept; lines longer than size are split with
+// chunkText. Code is not overlapped, so that each chunk covers its own lines.
+func chunkCode(text string, size, overlap int) []string {
+	text = strings.Trim(text, "\n")
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	if size <= 0 || len(text) <= size {
+		return []string{strings.TrimRight(text, " \t\n")}
+	}
+
+	var chunks []string
+	var cur []string
+	curLen, blank := 0, -1 // blank is the index in cur of the last blank line
+	flush := func(n int) {
+		if c := strings.Trim(strings.Join(cur[:n], "\n"), "\n"); strings.TrimSpace(c) != "" {
+			chunks = append(chunks, strings.TrimRight(c, " \t\n"))
+		}
+		cur = append([]string(nil), cur[n:]...)
+		curLen, blank = 0, -1
+		for i, l := range cur {
+			curLen += len(l) + 1
+			if strings.TrimSpace(l) == "" {
+				blank = i
+			}
+		}
+	}
+	for _, line := range strings.Split(text, "\n") {
+		if len(line) > size {
+			flush(len(cur))
+			chunks = append(chunks, chunkText(line, size, 0)...)
+			continue
+		}
+		if curLen+len(line) > size && blank > len(cur)/2 {
+			flush(blank)
+		}
+		if curLen+len(line) > size {
+			flush(len(cur))
+		}
+		if strings.TrimSpace(line) == "" {
+			blank = len(cur)
+		}
+		cur = append(cur, line)
+		curLen += len(line) + 1
+	}
+	flush(len(cur))
+	return chunks
+}
+
+// lineRange formats the lines from start to end as "12" or "12-40".
+func lineRange(start, end int) string {
+	if start >= end {
+		return strconv.Itoa(start)
+	}
+	return fmt.Sprintf("%d-%d", start, end)
+}
+
+// generatedMarkers match the conventional comments that mark generated
+// source files: Go's "// Code generated ... DO NOT EDIT." line and the
+// "@generated" tag used by many other generators.
+var generatedMarkers = []*regexp.Regexp{
+	regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`),
+	regexp.MustCompile(`^\s*(?://|#|/?\*|<!--|--)(?:.*\s)?@generated\b`),
+}
+
+// isGenerated reports whether a file with the given name and contents is
+// generated code, judging by its name or by a marker comment in its first
+// lines.
+func isGenerated(name string, data []byte) bool {
+	base := strings.ToLower(filepath.Base(name))
+	for _, suffix := range []string{".pb.go", ".pb.gw.go", "_generated.go", ".gen.go", ".min.js", ".min.css", ".bundle.js"} {
+		if strings.HasSuffix(base, suffix) {
+			return true
+		}
+	}
+	head := data[:min(len(data), 4096)]
+	for i, line := range bytes.Split(head, []byte("\n")) {
+		if i >= 20 {
+			break
+		}
+		line = bytes.TrimSuffix(line, []byte("\r"))
+		for _, re := range generatedMarkers {
+			if re.Match(line) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isBinary reports whether data looks like a binary file: one with a NUL byte
+// in its first 8000 bytes, the test git uses.
+func isBinary(data []byte) bool {
+	return bytes.IndexByte(data[:min(len(data), 8000)], 0) >= 0
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// declSummary returns the title and line range of each section.
+func declSummary(sections []section) []string {
+	var got []string
+	for _, s := range sections {
+		got = append(got, s.title+" "+s.metadata["lines"])
+	}
+	return got
+}
+
+func TestSplitGo(t *testing.T) {
+	src := `// Package shop sells things.
+package shop
+
+import "fmt"
+
+// Item is a thing for sale.
+type Item struct {
+	Name string
+}
+
+// String returns the item's name.
+func (i *Item) String() string {
+	return fmt.Sprint(i.Name)
+}
+
+// A stray comment stays with String.
+
+const (
+	A = 1
+	B = 2
+)
+
+func Buy[T any](t T) {}
+`
+	sections := codeLanguages[0].sections(src)
+	got := declSummary(sections)
+	want := []string{" 1-5", "Item 6-10", "Item.String 11-17", "A, B 18-22", "Buy 23"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("sections = %q, want %q", got, want)
+	}
+	if m := sections[2].metadata; m["symbol"] != "Item.String" || m["kind"] != "method" {
+		t.Errorf("metadata = %v", m)
+	}
+	if m := sections[3].metadata; m["kind"] != "const" {
+		t.Errorf("metadata = %v", m)
+	}
+	if !strings.HasPrefix(sections[1].text, "// Item is a thing for sale.\n") {
+		t.Errorf("doc comment not kept: %q", sections[1].text)
+	}
+}
+
+func TestSplitGo_ParseErrorUsesHeuristic(t *testing.T) {
+	src := "package broken\n\nfunc A() {\n\nfunc B() {}\n"
+	got := declSummary(codeLanguages[0].sections(src))
+	want := []string{" 1-2", "A 3-4", "B 5"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sections = %q, want %q", got, want)
+	}
+}
+
+func TestCodeLanguage_Heuristic(t *testing.T) {
+	tests := []struct {
+		ext, src string
+		want     []string
+	}{
+		{".py", "import os\n\n@cache\ndef load(path):\n    # read it\n    return 1\n\nclass Store:\n    def get(self):\n        pass\n",
+			[]string{" 1-2", "load 3-7", "Store 8-10"}},
+		{".ts", "/**\n * Adds.\n */\nexport function add(a: number) {}\nexport interface Shape {}\n",
+			[]string{"add 1-4", "Shape 5"}},
+		{".rs", "use std::fmt;\n\n#[derive(Debug)]\npub struct Point;\n\nimpl fmt::Display for Point {\n}\n",
+			[]string{" 1-2", "Point 3-5", "Point 6-7"}},
+		{".c", "#include <stdio.h>\n\nstatic int\ncount(char *s)\n{\n  return 0;\n}\n",
+			[]string{" 1-3", "count 4-7"}},
+		{".sh", "#!/bin/sh\nset -e\n\nusage() {\n  echo hi\n}\n",
+			[]string{" 1-3", "usage 4-6"}},
+	}
+	for _, tt := range tests {
+		f := formatForExtension("x" + tt.ext)
+		if f == nil {
+			t.Fatalf("no format for %s", tt.ext)
+		}
+		sections, metadata, err := f.read([]byte(tt.src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if metadata["language"] != f.name {
+			t.Errorf("%s: metadata = %v", tt.ext, metadata)
+		}
+		if got := declSummary(sections); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: sections = %q, want %q", tt.ext, got, tt.want)
+		}
+	}
+}
+
+func TestChunkCode(t *testing.T) {
+	var lines []string
+	for i := range 12 {
+		lines = append(lines, "\tx := "+strings.Repeat("a", i))
+		if i == 5 {
+			lines = append(lines, "")
+		}
+	}
+	text := strings.Join(lines, "\n")
+	chunks := chunkCode(text, 80, 20)
+	if len(chunks) < 2 {
+		t.Fatalf("expected several chunks, got %q", chunks)
+	}
+	for i, c := range chunks {
+		if len(c) > 80 {
+			t.Errorf("chunk %d has length %d", i, len(c))
+		}
+		if !strings.HasPrefix(c, "\tx := ") {
+			t.Errorf("chunk %d does not start with a whole, indented line: %q", i, c)
+		}
+	}
+	if strings.Join(chunks, "\n") != strings.Replace(text, "\n\n", "\n", 1) {
+		t.Errorf("chunks do not cover the text exactly once: %q", chunks)
+	}
+}
+
+func TestChunkSections_LineRanges(t *testing.T) {
+	sections := []section{{
+		title:     "Run",
+		text:      "func Run() {\n\ta()\n\tb()\n\tc()\n}\n",
+		firstLine: 10,
+		split:     chunkCode,
+	}}
+	var got []string
+	for _, c := range chunkSections(sections, 30, 0) {
+		got = append(got, c.Metadata["lines"])
+	}
+	if want := []string{"10-12", "13-14"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("lines = %q, want %q", got, want)
+	}
+}
+
+func TestIsGenerated(t *testing.T) {
+	tests := []struct {
+		name, src string
+		want      bool
+	}{
+		{"a.go", "// Code generated by protoc-gen-go. DO NOT EDIT.\n\npackage a\n", true},
+		{"a.py", "# -*- coding: utf-8 -*-\n# @generated by tool\n", true},
+		{"a.js", "/**\n * @generated\n */\n", true},
+		{"a.go", "// Code generated by stringer; DO NOT EDIT.\r\n", true},
+		{"a.py", "# handles auto-generated IDs\n", false},
+		{"a.js", "/* this file was generated once, then edited by hand */\n", false},
+		{"a.go", "// Code generated by hand, please edit.\n", false},
+		{"api.pb.go", "package a\n", true},
+		{"app.min.js", "var a=1", true},
+		{"a.go", "package a\n\n// generate docs with make\n", false},
+		{"a.go", "package a\n\nconst s = \"Code generated, DO NOT EDIT\"\n", false},
+	}
+	for _, tt := range tests {
+		if got := isGenerated(tt.name, []byte(tt.src)); got != tt.want {
+			t.Errorf("isGenerated(%q, %q) = %v, want %v", tt.name, tt.src, got, tt.want)
+		}
+	}
+	if !isBinary([]byte("a\x00b")) || isBinary([]byte("plain text")) {
+		t.Error("isBinary misclassified data")
+	}
+}
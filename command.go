@@ -1,18 +1,35 @@
 package main
 
-import "fmt"
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
 
 // Command is the interface that all CLI subcommands must implement.
+//
+// Flags returns a new FlagSet for the command's options, bound to fields of
+// the command and reset to their defaults; Run receives the arguments left
+// after those flags are parsed. NeedsModel reports whether the command embeds
+// text, in which case an embedding model must be configured to run it.
 type Command interface {
 	Name() string
 	Description() string
 	Usage() string
+	Flags() *flag.FlagSet
+	NeedsModel() bool
 	Run(rag *RAGSystem, args []string) error
 }
 
 // commands holds the registry of all available CLI commands keyed by name.
 var commands = make(map[string]Command)
 
+// input is the standard input that commands read from, such as "add <id> -";
+// tests replace it.
+var input io.Reader = os.Stdin
+
 // RegisterCommand adds a Command to the global command registry.
 func RegisterCommand(cmd Command) {
 	commands[cmd.Name()] = cmd
@@ -40,3 +57,32 @@ func PrintCommandsHelp() {
 		fmt.Printf("  %-25s %s\n", cmd.Name(), cmd.Description())
 	}
 }
+
+// RunCommand parses cmd's flags from args and runs cmd with the remaining
+// positional arguments. Requesting help with -h prints the command's help and
+// is not an error.
+func RunCommand(cmd Command, rag *RAGSystem, args []string) error {
+	fs := cmd.Flags()
+	fs.Usage = func() { PrintCommandHelp(fs.Output(), cmd) }
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	return cmd.Run(rag, fs.Args())
+}
+
+// PrintCommandHelp writes the usage, description, and flag defaults of cmd to w.
+func PrintCommandHelp(w io.Writer, cmd Command) {
+	fmt.Fprintf(w, "Usage: ydrag [options] %s\n\n%s\n", cmd.Usage(), cmd.Description())
+
+	fs := cmd.Flags()
+	hasFlags := false
+	fs.VisitAll(func(*flag.Flag) { hasFlags = true })
+	if hasFlags {
+		fmt.Fprintln(w, "\nFlags:")
+		fs.SetOutput(w)
+		fs.PrintDefaults()
+	}
+}
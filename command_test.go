@@ -1,24 +1,39 @@
 package main
 
 import (
+	"bytes"
+	"flag"
 	"sort"
+	"strings"
 	"testing"
 )
 
-// mockCommand implements Command for testing.
+// mockCommand implements Command for testing. It records the value of its
+// -n flag and the positional arguments it was run with.
 type mockCommand struct {
 	name        string
 	description string
 	usage       string
+	n           int
+	gotArgs     []string
 }
 
-func (m *mockCommand) Name() string                            { return m.name }
-func (m *mockCommand) Description() string                     { return m.description }
-func (m *mockCommand) Usage() string                           { return m.usage }
-func (m *mockCommand) Run(rag *RAGSystem, args []string) error { return nil }
+func (m *mockCommand) Name() string        { return m.name }
+func (m *mockCommand) Description() string { return m.description }
+func (m *mockCommand) Usage() string       { return m.usage }
+func (m *mockCommand) Flags() *flag.FlagSet {
+	fs := flag.NewFlagSet(m.name, flag.ContinueOnError)
+	fs.IntVar(&m.n, "n", 1, "a number")
+	return fs
+}
+func (m *mockCommand) NeedsModel() bool { return false }
+func (m *mockCommand) Run(rag *RAGSystem, args []string) error {
+	m.gotArgs = args
+	return nil
+}
 
 func TestGetCommand_Exists(t *testing.T) {
-	expected := []string{"add", "delete", "list", "query", "serve"}
+	expected := []string{"add", "bench", "dedupe", "delete", "eval", "export", "get", "help", "import", "ingest", "list", "query", "serve", "shell", "similar", "stats"}
 	for _, name := range expected {
 		cmd, ok := GetCommand(name)
 		if !ok {
@@ -31,6 +46,22 @@ func TestGetCommand_Exists(t *testing.T) {
 	}
 }
 
+func TestNeedsModel(t *testing.T) {
+	want := map[string]bool{
+		"add": true, "bench": true, "eval": true, "import": true, "ingest": true, "query": true, "serve": true,
+		"dedupe": false, "delete": false, "export": false, "get": false, "help": false, "list": false, "shell": false, "similar": false, "stats": false,
+	}
+	for name, needs := range want {
+		cmd, ok := GetCommand(name)
+		if !ok {
+			t.Fatalf("command %q not registered", name)
+		}
+		if cmd.NeedsModel() != needs {
+			t.Errorf("%s.NeedsModel() = %v, want %v", name, cmd.NeedsModel(), needs)
+		}
+	}
+}
+
 func TestGetCommand_NotExists(t *testing.T) {
 	_, ok := GetCommand("nonexistent")
 	if ok {
@@ -41,7 +72,7 @@ func TestGetCommand_NotExists(t *testing.T) {
 func TestListCommands(t *testing.T) {
 	cmds := ListCommands()
 
-	expected := []string{"add", "delete", "list", "query", "serve"}
+	expected := []string{"add", "bench", "dedupe", "delete", "eval", "export", "get", "help", "import", "ingest", "list", "query", "serve", "shell", "similar", "stats"}
 
 	if len(cmds) < len(expected) {
 		t.Fatalf("expected at least %d commands, got %d", len(expected), len(cmds))
@@ -100,3 +131,47 @@ func TestRegisterCommand(t *testing.T) {
 		t.Error("expected mock command to be removed after cleanup")
 	}
 }
+
+func TestRunCommand_ParsesFlags(t *testing.T) {
+	mock := &mockCommand{name: "mock"}
+	if err := RunCommand(mock, nil, []string{"-n", "7", "a", "b"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mock.n != 7 {
+		t.Errorf("n = %d, want 7", mock.n)
+	}
+	if strings.Join(mock.gotArgs, ",") != "a,b" {
+		t.Errorf("args = %v, want [a b]", mock.gotArgs)
+	}
+
+	if err := RunCommand(mock, nil, []string{"c"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mock.n != 1 {
+		t.Errorf("n = %d, want default 1 after re-parsing", mock.n)
+	}
+}
+
+func TestRunCommand_UnknownFlag(t *testing.T) {
+	mock := &mockCommand{name: "mock"}
+	if err := RunCommand(mock, nil, []string{"-bogus"}); err == nil {
+		t.Fatal("expected error for unknown flag")
+	}
+}
+
+func TestPrintCommandHelp(t *testing.T) {
+	var buf bytes.Buffer
+	PrintCommandHelp(&buf, &ServeCommand{})
+	out := buf.String()
+	for _, want := range []string{"serve", "-transport", "-port", "-host", "-read-only", "-allowed-origins"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("help output missing %q:\n%s", want, out)
+		}
+	}
+
+	buf.Reset()
+	PrintCommandHelp(&buf, &ListCommand{})
+	if strings.Contains(buf.String(), "Flags:") {
+		t.Errorf("expected no flags section for list command:\n%s", buf.String())
+	}
+}
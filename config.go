@@ -2,7 +2,9 @@ package main
 
 import (
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -10,16 +12,78 @@ import (
 // Config holds the application configuration for the RAG service, including
 // model settings, database paths, and server options.
 type Config struct {
-	Model       string `yaml:"model"`
-	LibPath     string `yaml:"lib_path"`
-	DBPath      string `yaml:"db_path"`
-	ContextSize int    `yaml:"context_size"`
-	BatchSize   int    `yaml:"batch_size"`
-	Verbose     bool   `yaml:"verbose"`
-	Server struct {
-		Port      string `yaml:"port"`
-		Transport string `yaml:"transport"` // "stdio", "sse", or "streamable-http"
-	} `yaml:"server"`
+	Model       string        `yaml:"model"`
+	LibPath     string        `yaml:"lib_path"`
+	DBPath      string        `yaml:"db_path"`
+	Collection  string        `yaml:"collection"` // collection used by commands; empty selects "default"
+	ContextSize int           `yaml:"context_size"`
+	BatchSize   int           `yaml:"batch_size"`
+	Verbose     bool          `yaml:"verbose"`
+	Log         LogConfig     `yaml:"log"`
+	Tracing     TracingConfig `yaml:"tracing"`
+	Server      ServerConfig  `yaml:"server"`
+	Ingest      IngestConfig  `yaml:"ingest"`
+}
+
+// LogConfig controls the structured diagnostic log written by ydrag and
+// llama.cpp. Command output is unaffected.
+type LogConfig struct {
+	Level  string `yaml:"level"`  // "debug", "info", "warn", or "error"
+	Format string `yaml:"format"` // "text" or "json"
+	File   string `yaml:"file"`   // log file path; empty logs to stderr
+}
+
+// TracingConfig controls OpenTelemetry tracing. Tracing is disabled unless an
+// OTLP endpoint is set.
+type TracingConfig struct {
+	Endpoint    string  `yaml:"endpoint"`     // OTLP/HTTP collector URL, e.g. "http://localhost:4318"
+	SampleRatio float64 `yaml:"sample_ratio"` // fraction of new traces to record, from 0 to 1
+}
+
+// ServerConfig holds the MCP server transport and access-control settings.
+type ServerConfig struct {
+	Host      string     `yaml:"host"` // bind address; empty listens on all interfaces
+	Port      string     `yaml:"port"`
+	Socket    string     `yaml:"socket"`    // Unix domain socket path; overrides host and port
+	Transport string     `yaml:"transport"` // "stdio", "sse", or "streamable-http"
+	TLS       TLSConfig  `yaml:"tls"`
+	Auth      AuthConfig `yaml:"auth"`
+
+	ReadOnly       bool     `yaml:"read_only"`       // expose only tools that do not modify the knowledge base
+	AllowedOrigins []string `yaml:"allowed_origins"` // browser origins allowed to connect; empty allows any
+}
+
+// TLSConfig holds the certificate files used to serve the HTTP transports over
+// TLS. Setting ClientCAFile additionally requires clients to present a
+// certificate signed by that CA (mutual TLS).
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// AuthConfig lists the bearer tokens accepted by the HTTP transports. When no
+// tokens are configured, authentication is disabled.
+type AuthConfig struct {
+	Tokens  []TokenConfig `yaml:"tokens"`
+	KeyFile string        `yaml:"key_file"` // file with one "<token> [read|write] [name]" entry per line
+}
+
+// TokenConfig describes a single bearer token and the scope it grants.
+type TokenConfig struct {
+	Name  string `yaml:"name"`
+	Token string `yaml:"token"`
+	Scope string `yaml:"scope"` // "read" (default) or "write"
+}
+
+// IngestConfig controls how files are split into chunks, which server-side
+// paths may be ingested, and whether near-duplicates are rejected.
+type IngestConfig struct {
+	AllowedRoots       []string `yaml:"allowed_roots"`       // directories add_file may read from; empty disables path ingestion
+	ChunkSize          int      `yaml:"chunk_size"`          // maximum chunk length in bytes
+	ChunkOverlap       int      `yaml:"chunk_overlap"`       // bytes repeated between consecutive chunks
+	MaxFileSize        int64    `yaml:"max_file_size"`       // largest accepted file in bytes
+	DuplicateThreshold float64  `yaml:"duplicate_threshold"` // reject documents at least this similar to an existing one; 0 disables
 }
 
 // DefaultConfig returns a Config populated with sensible default values.
@@ -31,10 +95,22 @@ func DefaultConfig() *Config {
 		ContextSize: 512,
 		BatchSize:   512,
 		Verbose:     false,
-		Server: struct {
-			Port      string `yaml:"port"`
-			Transport string `yaml:"transport"`
-		}{Port: "8080", Transport: "stdio"},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "text",
+		},
+		Tracing: TracingConfig{
+			SampleRatio: 1,
+		},
+		Server: ServerConfig{
+			Port:      "8080",
+			Transport: "stdio",
+		},
+		Ingest: IngestConfig{
+			ChunkSize:    1000,
+			ChunkOverlap: 100,
+			MaxFileSize:  50 << 20,
+		},
 	}
 }
 
@@ -67,6 +143,9 @@ func (c *Config) applyEnvOverrides() {
 	if v := os.Getenv("YDRAG_DB_PATH"); v != "" {
 		c.DBPath = v
 	}
+	if v := os.Getenv("YDRAG_COLLECTION"); v != "" {
+		c.Collection = v
+	}
 	if v := os.Getenv("YDRAG_CONTEXT_SIZE"); v != "" {
 		if n, err := strconv.Atoi(v); err == nil {
 			c.ContextSize = n
@@ -80,10 +159,108 @@ func (c *Config) applyEnvOverrides() {
 	if v := os.Getenv("YDRAG_VERBOSE"); v != "" {
 		c.Verbose = v == "true" || v == "1"
 	}
+	if v := os.Getenv("YDRAG_LOG_LEVEL"); v != "" {
+		c.Log.Level = v
+	}
+	if v := os.Getenv("YDRAG_LOG_FORMAT"); v != "" {
+		c.Log.Format = v
+	}
+	if v := os.Getenv("YDRAG_LOG_FILE"); v != "" {
+		c.Log.File = v
+	}
+	if v := os.Getenv("YDRAG_OTLP_ENDPOINT"); v != "" {
+		c.Tracing.Endpoint = v
+	}
+	if v := os.Getenv("YDRAG_TRACE_SAMPLE_RATIO"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			c.Tracing.SampleRatio = f
+		}
+	}
 	if v := os.Getenv("YDRAG_SERVER_PORT"); v != "" {
 		c.Server.Port = v
 	}
 	if v := os.Getenv("YDRAG_TRANSPORT"); v != "" {
 		c.Server.Transport = v
 	}
+	if v := os.Getenv("YDRAG_SERVER_HOST"); v != "" {
+		c.Server.Host = v
+	}
+	if v := os.Getenv("YDRAG_SERVER_SOCKET"); v != "" {
+		c.Server.Socket = v
+	}
+	if v := os.Getenv("YDRAG_TLS_CERT"); v != "" {
+		c.Server.TLS.CertFile = v
+	}
+	if v := os.Getenv("YDRAG_TLS_KEY"); v != "" {
+		c.Server.TLS.KeyFile = v
+	}
+	if v := os.Getenv("YDRAG_TLS_CLIENT_CA"); v != "" {
+		c.Server.TLS.ClientCAFile = v
+	}
+	if v := os.Getenv("YDRAG_READ_ONLY"); v != "" {
+		c.Server.ReadOnly = v == "true" || v == "1"
+	}
+	if v := os.Getenv("YDRAG_ALLOWED_ORIGINS"); v != "" {
+		c.Server.AllowedOrigins = splitList(v)
+	}
+	if v := os.Getenv("YDRAG_AUTH_KEY_FILE"); v != "" {
+		c.Server.Auth.KeyFile = v
+	}
+	if v := os.Getenv("YDRAG_ALLOWED_ROOTS"); v != "" {
+		c.Ingest.AllowedRoots = filepath.SplitList(v)
+	}
+	if v := os.Getenv("YDRAG_CHUNK_SIZE"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Ingest.ChunkSize = n
+		}
+	}
+	if v := os.Getenv("YDRAG_CHUNK_OVERLAP"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil {
+			c.Ingest.ChunkOverlap = n
+		}
+	}
+	if v := os.Getenv("YDRAG_DUPLICATE_THRESHOLD"); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			c.Ingest.DuplicateThreshold = f
+		}
+	}
+}
+
+// splitList splits a comma-separated list, trimming spaces and dropping empty
+// entries.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// RunSettings records the configuration that affects retrieval quality and
+// speed, so that eval and bench reports from different runs can be compared.
+type RunSettings struct {
+	Model        string `json:"model,omitempty"`
+	ContextSize  int    `json:"context_size,omitempty"`
+	BatchSize    int    `json:"batch_size,omitempty"`
+	ChunkSize    int    `json:"chunk_size,omitempty"`
+	ChunkOverlap int    `json:"chunk_overlap,omitempty"`
+}
+
+// runSettings returns the RunSettings of c, naming the model by its file name.
+func runSettings(c *Config) RunSettings {
+	if c == nil {
+		return RunSettings{}
+	}
+	s := RunSettings{
+		ContextSize:  c.ContextSize,
+		BatchSize:    c.BatchSize,
+		ChunkSize:    c.Ingest.ChunkSize,
+		ChunkOverlap: c.Ingest.ChunkOverlap,
+	}
+	if c.Model != "" {
+		s.Model = filepath.Base(c.Model)
+	}
+	return s
 }
@@ -33,6 +33,30 @@ func TestDefaultConfig(t *testing.T) {
 	if cfg.Server.Transport != "stdio" {
 		t.Errorf("Server.Transport = %q, want %q", cfg.Server.Transport, "stdio")
 	}
+	if len(cfg.Ingest.AllowedRoots) != 0 {
+		t.Errorf("Ingest.AllowedRoots = %v, want empty", cfg.Ingest.AllowedRoots)
+	}
+	if cfg.Ingest.ChunkSize != 1000 {
+		t.Errorf("Ingest.ChunkSize = %d, want %d", cfg.Ingest.ChunkSize, 1000)
+	}
+	if cfg.Ingest.ChunkOverlap != 100 {
+		t.Errorf("Ingest.ChunkOverlap = %d, want %d", cfg.Ingest.ChunkOverlap, 100)
+	}
+	if cfg.Log.Level != "info" {
+		t.Errorf("Log.Level = %q, want %q", cfg.Log.Level, "info")
+	}
+	if cfg.Log.Format != "text" {
+		t.Errorf("Log.Format = %q, want %q", cfg.Log.Format, "text")
+	}
+	if cfg.Log.File != "" {
+		t.Errorf("Log.File = %q, want empty", cfg.Log.File)
+	}
+	if cfg.Tracing.Endpoint != "" {
+		t.Errorf("Tracing.Endpoint = %q, want empty", cfg.Tracing.Endpoint)
+	}
+	if cfg.Tracing.SampleRatio != 1 {
+		t.Errorf("Tracing.SampleRatio = %v, want 1", cfg.Tracing.SampleRatio)
+	}
 }
 
 func TestLoadConfig_NonExistentFile(t *testing.T) {
@@ -58,6 +82,15 @@ verbose: true
 server:
   port: "9090"
   transport: "sse"
+  auth:
+    key_file: /etc/ydrag/keys
+    tokens:
+      - name: ci
+        token: secret
+        scope: write
+ingest:
+  allowed_roots: ["/srv/docs", "/data"]
+  chunk_size: 400
 `)
 	tmpFile := filepath.Join(t.TempDir(), "config.yaml")
 	if err := os.WriteFile(tmpFile, yamlContent, 0644); err != nil {
@@ -92,6 +125,21 @@ server:
 	if cfg.Server.Transport != "sse" {
 		t.Errorf("Server.Transport = %q, want %q", cfg.Server.Transport, "sse")
 	}
+	if cfg.Server.Auth.KeyFile != "/etc/ydrag/keys" {
+		t.Errorf("Server.Auth.KeyFile = %q, want %q", cfg.Server.Auth.KeyFile, "/etc/ydrag/keys")
+	}
+	if len(cfg.Server.Auth.Tokens) != 1 || cfg.Server.Auth.Tokens[0].Scope != "write" {
+		t.Errorf("Server.Auth.Tokens = %+v, want one write token", cfg.Server.Auth.Tokens)
+	}
+	if len(cfg.Ingest.AllowedRoots) != 2 || cfg.Ingest.AllowedRoots[0] != "/srv/docs" {
+		t.Errorf("Ingest.AllowedRoots = %v, want [/srv/docs /data]", cfg.Ingest.AllowedRoots)
+	}
+	if cfg.Ingest.ChunkSize != 400 {
+		t.Errorf("Ingest.ChunkSize = %d, want %d", cfg.Ingest.ChunkSize, 400)
+	}
+	if cfg.Ingest.ChunkOverlap != 100 {
+		t.Errorf("Ingest.ChunkOverlap = %d, want default %d", cfg.Ingest.ChunkOverlap, 100)
+	}
 }
 
 func TestLoadConfig_InvalidYAML(t *testing.T) {
@@ -123,11 +171,28 @@ func TestApplyEnvOverrides(t *testing.T) {
 	t.Setenv("YDRAG_MODEL", "env-model")
 	t.Setenv("YZMA_LIB", "/env/lib")
 	t.Setenv("YDRAG_DB_PATH", "env.db")
+	t.Setenv("YDRAG_COLLECTION", "notes")
 	t.Setenv("YDRAG_CONTEXT_SIZE", "2048")
 	t.Setenv("YDRAG_BATCH_SIZE", "128")
 	t.Setenv("YDRAG_VERBOSE", "true")
 	t.Setenv("YDRAG_SERVER_PORT", "3000")
 	t.Setenv("YDRAG_TRANSPORT", "streamable-http")
+	t.Setenv("YDRAG_SERVER_HOST", "127.0.0.1")
+	t.Setenv("YDRAG_READ_ONLY", "1")
+	t.Setenv("YDRAG_ALLOWED_ORIGINS", "http://localhost:3000, https://app.example")
+	t.Setenv("YDRAG_SERVER_SOCKET", "/run/ydrag.sock")
+	t.Setenv("YDRAG_TLS_CERT", "server.crt")
+	t.Setenv("YDRAG_TLS_KEY", "server.key")
+	t.Setenv("YDRAG_TLS_CLIENT_CA", "ca.crt")
+	t.Setenv("YDRAG_ALLOWED_ROOTS", "/a"+string(filepath.ListSeparator)+"/b")
+	t.Setenv("YDRAG_CHUNK_SIZE", "300")
+	t.Setenv("YDRAG_CHUNK_OVERLAP", "30")
+	t.Setenv("YDRAG_DUPLICATE_THRESHOLD", "0.97")
+	t.Setenv("YDRAG_LOG_LEVEL", "debug")
+	t.Setenv("YDRAG_LOG_FORMAT", "json")
+	t.Setenv("YDRAG_LOG_FILE", "/var/log/ydrag.log")
+	t.Setenv("YDRAG_OTLP_ENDPOINT", "http://collector:4318")
+	t.Setenv("YDRAG_TRACE_SAMPLE_RATIO", "0.25")
 
 	cfg := DefaultConfig()
 	cfg.applyEnvOverrides()
@@ -141,6 +206,9 @@ func TestApplyEnvOverrides(t *testing.T) {
 	if cfg.DBPath != "env.db" {
 		t.Errorf("DBPath = %q, want %q", cfg.DBPath, "env.db")
 	}
+	if cfg.Collection != "notes" {
+		t.Errorf("Collection = %q, want %q", cfg.Collection, "notes")
+	}
 	if cfg.ContextSize != 2048 {
 		t.Errorf("ContextSize = %d, want %d", cfg.ContextSize, 2048)
 	}
@@ -156,6 +224,39 @@ func TestApplyEnvOverrides(t *testing.T) {
 	if cfg.Server.Transport != "streamable-http" {
 		t.Errorf("Server.Transport = %q, want %q", cfg.Server.Transport, "streamable-http")
 	}
+	if cfg.Server.Host != "127.0.0.1" {
+		t.Errorf("Server.Host = %q, want %q", cfg.Server.Host, "127.0.0.1")
+	}
+	if !cfg.Server.ReadOnly {
+		t.Errorf("Server.ReadOnly = %v, want %v", cfg.Server.ReadOnly, true)
+	}
+	if len(cfg.Server.AllowedOrigins) != 2 || cfg.Server.AllowedOrigins[1] != "https://app.example" {
+		t.Errorf("Server.AllowedOrigins = %v, want [http://localhost:3000 https://app.example]", cfg.Server.AllowedOrigins)
+	}
+	if cfg.Server.Socket != "/run/ydrag.sock" {
+		t.Errorf("Server.Socket = %q, want %q", cfg.Server.Socket, "/run/ydrag.sock")
+	}
+	if cfg.Server.TLS.CertFile != "server.crt" || cfg.Server.TLS.KeyFile != "server.key" || cfg.Server.TLS.ClientCAFile != "ca.crt" {
+		t.Errorf("Server.TLS = %+v, want server.crt/server.key/ca.crt", cfg.Server.TLS)
+	}
+	if len(cfg.Ingest.AllowedRoots) != 2 || cfg.Ingest.AllowedRoots[1] != "/b" {
+		t.Errorf("Ingest.AllowedRoots = %v, want [/a /b]", cfg.Ingest.AllowedRoots)
+	}
+	if cfg.Ingest.ChunkSize != 300 {
+		t.Errorf("Ingest.ChunkSize = %d, want %d", cfg.Ingest.ChunkSize, 300)
+	}
+	if cfg.Ingest.ChunkOverlap != 30 {
+		t.Errorf("Ingest.ChunkOverlap = %d, want %d", cfg.Ingest.ChunkOverlap, 30)
+	}
+	if cfg.Ingest.DuplicateThreshold != 0.97 {
+		t.Errorf("Ingest.DuplicateThreshold = %v, want %v", cfg.Ingest.DuplicateThreshold, 0.97)
+	}
+	if cfg.Log.Level != "debug" || cfg.Log.Format != "json" || cfg.Log.File != "/var/log/ydrag.log" {
+		t.Errorf("Log = %+v, want debug/json//var/log/ydrag.log", cfg.Log)
+	}
+	if cfg.Tracing.Endpoint != "http://collector:4318" || cfg.Tracing.SampleRatio != 0.25 {
+		t.Errorf("Tracing = %+v, want http://collector:4318 at 0.25", cfg.Tracing)
+	}
 }
 
 func TestApplyEnvOverrides_InvalidNumbers(t *testing.T) {
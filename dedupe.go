@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+// DuplicateError reports that a document was not stored because it is a
+// near-duplicate of an existing one.
+type DuplicateError struct {
+	ID          string
+	DuplicateOf string
+	Similarity  float64
+}
+
+// Error implements the error interface.
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("document '%s' is a near-duplicate of '%s' (similarity %.4f)", e.ID, e.DuplicateOf, e.Similarity)
+}
+
+// DuplicateCluster is a group of near-duplicate documents in one collection.
+// Keep is the oldest document; Duplicates are the others, in ID order.
+type DuplicateCluster struct {
+	Keep       string   `json:"keep"`
+	Duplicates []string `json:"duplicates"`
+	Similarity float64  `json:"similarity"` // lowest similarity of any matched pair in the cluster
+}
+
+// duplicateThreshold returns the similarity at or above which new documents
+// are rejected as near-duplicates, or 0 when rejection is disabled.
+func duplicateThreshold() float64 {
+	if cfg == nil {
+		return 0
+	}
+	return cfg.Ingest.DuplicateThreshold
+}
+
+// checkDuplicate returns a *DuplicateError if duplicate rejection is enabled
+// and the document id with the given chunk embeddings is a near-duplicate of
+// another document in the selected collection: every chunk must have a match
+// at or above the threshold. The reported original is the best match of the
+// first chunk.
+func (r *RAGSystem) checkDuplicate(ctx context.Context, id string, vecs [][]float32) error {
+	threshold := duplicateThreshold()
+	if threshold <= 0 || !r.hasTable() || len(vecs) == 0 {
+		return nil
+	}
+
+	dup := &DuplicateError{ID: id, Similarity: 1}
+	for i, vec := range vecs {
+		var doc string
+		var score float64
+		err := r.db.QueryRowContext(ctx, fmt.Sprintf(`
+			SELECT coalesce(parent_id, id), array_cosine_similarity(embedding, %s::FLOAT[%d]) AS score
+			FROM documents
+			WHERE embedding IS NOT NULL AND coalesce(parent_id, id) <> ? AND %s
+			ORDER BY score DESC
+			LIMIT 1
+		`, floatArrayToSQL(vec), r.embeddingDim, collectionFilter), id, r.Collection()).Scan(&doc, &score)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to check for duplicates: %w", err)
+		}
+		if score < threshold {
+			return nil
+		}
+		if i == 0 {
+			dup.DuplicateOf = doc
+		}
+		dup.Similarity = min(dup.Similarity, score)
+	}
+	r.log().Info("rejected near-duplicate", "document", id, "duplicate_of", dup.DuplicateOf, "similarity", dup.Similarity)
+	return dup
+}
+
+// FindDuplicates groups the documents of the selected collection into
+// clusters of near-duplicates. Two documents match when they have the same
+// number of chunks and each pair of chunks at the same position has a cosine
+// similarity of at least threshold; a standalone document matches a
+// single-chunk file. Clusters are the connected groups of matching documents.
+func (r *RAGSystem) FindDuplicates(ctx context.Context, threshold float64) ([]DuplicateCluster, error) {
+	if !r.hasTable() {
+		return nil, nil
+	}
+
+	defer observeDB("dedupe", time.Now())
+	rows, err := r.db.QueryContext(ctx, `
+		WITH chunks AS (
+			SELECT coalesce(parent_id, id) AS doc, coalesce(chunk_index, 0) AS idx, embedding, created_at
+			FROM documents
+			WHERE embedding IS NOT NULL AND `+collectionFilter+`
+		),
+		docs AS (
+			SELECT doc, count(*) AS n, min(created_at) AS created
+			FROM chunks
+			GROUP BY doc
+		),
+		pairs AS (
+			SELECT a.doc AS a, b.doc AS b, count(*) AS matched,
+				min(array_cosine_similarity(a.embedding, b.embedding)) AS score
+			FROM chunks a JOIN chunks b ON a.idx = b.idx AND a.doc < b.doc
+			WHERE array_cosine_similarity(a.embedding, b.embedding) >= ?
+			GROUP BY a.doc, b.doc
+		)
+		SELECT p.a, da.created, p.b, db.created, p.score
+		FROM pairs p
+		JOIN docs da ON da.doc = p.a
+		JOIN docs db ON db.doc = p.b
+		WHERE p.matched = da.n AND da.n = db.n
+	`, r.Collection(), threshold)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicates: %w", err)
+	}
+	defer rows.Close()
+
+	var pairs []duplicatePair
+	created := make(map[string]sql.NullTime)
+	for rows.Next() {
+		var p duplicatePair
+		var ca, cb sql.NullTime
+		if err := rows.Scan(&p.a, &ca, &p.b, &cb, &p.score); err != nil {
+			return nil, fmt.Errorf("failed to scan duplicate: %w", err)
+		}
+		created[p.a], created[p.b] = ca, cb
+		pairs = append(pairs, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read duplicates: %w", err)
+	}
+	return clusterDuplicates(pairs, created), nil
+}
+
+// duplicatePair is two matching documents and their similarity.
+type duplicatePair struct {
+	a, b  string
+	score float64
+}
+
+// clusterDuplicates joins matching pairs into connected clusters, keeping
+// the document created first in each. Documents with no recorded creation
+// time predate it and count as oldest; ties are broken by ID. Clusters are
+// ordered by the kept ID.
+func clusterDuplicates(pairs []duplicatePair, created map[string]sql.NullTime) []DuplicateCluster {
+	parent := make(map[string]string)
+	var find func(string) string
+	find = func(id string) string {
+		if p, ok := parent[id]; ok && p != id {
+			root := find(p)
+			parent[id] = root
+			return root
+		}
+		parent[id] = id
+		return id
+	}
+	for _, p := range pairs {
+		parent[find(p.a)] = find(p.b)
+	}
+
+	members := make(map[string][]string)
+	similarity := make(map[string]float64)
+	for id := range parent {
+		root := find(id)
+		members[root] = append(members[root], id)
+	}
+	for _, p := range pairs {
+		root := find(p.a)
+		if s, ok := similarity[root]; !ok || p.score < s {
+			similarity[root] = p.score
+		}
+	}
+
+	older := func(a, b string) int {
+		ta, tb := created[a], created[b]
+		switch {
+		case ta.Valid != tb.Valid:
+			if !ta.Valid {
+				return -1
+			}
+			return 1
+		case ta.Valid && !ta.Time.Equal(tb.Time):
+			return ta.Time.Compare(tb.Time)
+		}
+		return strings.Compare(a, b)
+	}
+
+	clusters := make([]DuplicateCluster, 0, len(members))
+	for root, ids := range members {
+		slices.SortFunc(ids, older)
+		dups := slices.Clone(ids[1:])
+		slices.Sort(dups)
+		clusters = append(clusters, DuplicateCluster{Keep: ids[0], Duplicates: dups, Similarity: similarity[root]})
+	}
+	slices.SortFunc(clusters, func(a, b DuplicateCluster) int { return strings.Compare(a.Keep, b.Keep) })
+	return clusters
+}
+
+// RemoveDuplicates deletes the duplicates in each cluster from the selected
+// collection, keeping the oldest document. When merge is set, the IDs of the
+// removed documents are added to the kept document's "aliases" metadata, a
+// comma-separated list. It returns the number of documents removed.
+func (r *RAGSystem) RemoveDuplicates(ctx context.Context, clusters []DuplicateCluster, merge bool) (int, error) {
+	removed := 0
+	for _, c := range clusters {
+		if err := r.removeCluster(ctx, c, merge); err != nil {
+			return removed, err
+		}
+		removed += len(c.Duplicates)
+	}
+	return removed, nil
+}
+
+// removeCluster applies RemoveDuplicates to one cluster in a transaction.
+func (r *RAGSystem) removeCluster(ctx context.Context, c DuplicateCluster, merge bool) error {
+	defer observeDB("delete", time.Now())
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if merge {
+		var metadata sql.NullString
+		err := tx.QueryRow(`
+			SELECT CAST(metadata AS VARCHAR) FROM documents
+			WHERE (id = ? OR parent_id = ?) AND `+collectionFilter+`
+			ORDER BY coalesce(chunk_index, 0)
+			LIMIT 1
+		`, c.Keep, c.Keep, r.Collection()).Scan(&metadata)
+		if err != nil {
+			return fmt.Errorf("failed to read '%s': %w", c.Keep, err)
+		}
+		meta := decodeMetadata(metadata)
+		if meta == nil {
+			meta = make(map[string]string)
+		}
+		aliases := c.Duplicates
+		if meta["aliases"] != "" {
+			aliases = append(strings.Split(meta["aliases"], ","), aliases...)
+		}
+		meta["aliases"] = strings.Join(aliases, ",")
+		data, err := json.Marshal(meta)
+		if err != nil {
+			return fmt.Errorf("failed to encode metadata: %w", err)
+		}
+		if _, err := tx.Exec(`UPDATE documents SET metadata = ? WHERE (id = ? OR parent_id = ?) AND `+collectionFilter,
+			string(data), c.Keep, c.Keep, r.Collection()); err != nil {
+			return fmt.Errorf("failed to merge into '%s': %w", c.Keep, err)
+		}
+	}
+
+	for _, id := range c.Duplicates {
+		if _, err := tx.Exec(`DELETE FROM documents WHERE (id = ? OR parent_id = ?) AND `+collectionFilter,
+			id, id, r.Collection()); err != nil {
+			return fmt.Errorf("failed to delete '%s': %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit: %w", err)
+	}
+	r.log().Info("removed duplicates", "kept", c.Keep, "removed", c.Duplicates, "merged", merge)
+	return nil
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newDedupeTestRAG returns a knowledge base where a and b are near-duplicates,
+// files f and g are chunk-for-chunk copies, and c and h match nothing. b is
+// the oldest document; the others have no creation time except a.
+func newDedupeTestRAG(t *testing.T) *RAGSystem {
+	t.Helper()
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "a", "alpha", "", "", []float32{1, 0, 0})
+	insertTestDoc(t, rag, "b", "alpha copy", "", "", []float32{0.99, 0.01, 0})
+	insertTestDoc(t, rag, "c", "gamma", "", "", []float32{0, 0, 1})
+	insertTestDoc(t, rag, "f#0", "first", "f", `{"source":"f.txt"}`, []float32{1, 0, 0})
+	insertTestDoc(t, rag, "f#1", "second", "f", `{"source":"f.txt"}`, []float32{0, 1, 0})
+	insertTestDoc(t, rag, "g#0", "first", "g", `{"source":"g.txt"}`, []float32{1, 0, 0})
+	insertTestDoc(t, rag, "g#1", "second", "g", `{"source":"g.txt"}`, []float32{0, 1, 0})
+	insertTestDoc(t, rag, "h#0", "first", "h", "", []float32{1, 0, 0})
+	insertTestDoc(t, rag, "h#1", "other", "h", "", []float32{0, 0, 1})
+	_, err := rag.db.Exec(`
+		UPDATE documents SET chunk_index = CAST(right(id, 1) AS INTEGER) WHERE parent_id IS NOT NULL;
+		UPDATE documents SET created_at = TIMESTAMP '2024-01-01' WHERE id = 'b';
+		UPDATE documents SET created_at = TIMESTAMP '2025-01-01' WHERE id = 'a';
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return rag
+}
+
+func TestFindDuplicates(t *testing.T) {
+	rag := newDedupeTestRAG(t)
+
+	clusters, err := rag.FindDuplicates(context.Background(), 0.95)
+	if err != nil {
+		t.Fatalf("FindDuplicates failed: %v", err)
+	}
+	if len(clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %+v", clusters)
+	}
+	if clusters[0].Keep != "b" || !reflect.DeepEqual(clusters[0].Duplicates, []string{"a"}) || clusters[0].Similarity < 0.99 {
+		t.Errorf("first cluster = %+v, want b keeping a", clusters[0])
+	}
+	if clusters[1].Keep != "f" || !reflect.DeepEqual(clusters[1].Duplicates, []string{"g"}) || !approxEqual(clusters[1].Similarity, 1) {
+		t.Errorf("second cluster = %+v, want f keeping g", clusters[1])
+	}
+
+	clusters, err = rag.FindDuplicates(context.Background(), 0.99999)
+	if err != nil || len(clusters) != 1 || clusters[0].Keep != "f" {
+		t.Errorf("strict threshold = %+v, %v", clusters, err)
+	}
+}
+
+func TestClusterDuplicates_Transitive(t *testing.T) {
+	pairs := []duplicatePair{{"x", "y", 0.97}, {"y", "z", 0.96}, {"p", "q", 0.99}}
+	created := map[string]sql.NullTime{
+		"x": {Time: time.Unix(200, 0), Valid: true},
+		"z": {Time: time.Unix(100, 0), Valid: true},
+	}
+	clusters := clusterDuplicates(pairs, created)
+	want := []DuplicateCluster{
+		{Keep: "p", Duplicates: []string{"q"}, Similarity: 0.99},
+		{Keep: "y", Duplicates: []string{"x", "z"}, Similarity: 0.96},
+	}
+	if !reflect.DeepEqual(clusters, want) {
+		t.Errorf("clusters = %+v, want %+v", clusters, want)
+	}
+}
+
+func TestRemoveDuplicates_Merge(t *testing.T) {
+	rag := newDedupeTestRAG(t)
+	ctx := context.Background()
+	clusters, err := rag.FindDuplicates(ctx, 0.95)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	removed, err := rag.RemoveDuplicates(ctx, clusters, true)
+	if err != nil || removed != 2 {
+		t.Fatalf("RemoveDuplicates = %d, %v", removed, err)
+	}
+	if _, err := rag.GetDocument("a"); err == nil {
+		t.Error("duplicate a was not removed")
+	}
+	docs, err := rag.GetDocument("f")
+	if err != nil || len(docs) != 2 || docs[1].Metadata["aliases"] != "g" || docs[1].Metadata["source"] != "f.txt" {
+		t.Errorf("merged file = %+v, %v", docs, err)
+	}
+	docs, err = rag.GetDocument("b")
+	if err != nil || docs[0].Metadata["aliases"] != "a" {
+		t.Errorf("merged document = %+v, %v", docs, err)
+	}
+}
+
+func TestCheckDuplicate(t *testing.T) {
+	rag := newDedupeTestRAG(t)
+	ctx := context.Background()
+	if err := rag.checkDuplicate(ctx, "new", [][]float32{{1, 0, 0}}); err != nil {
+		t.Errorf("rejection disabled by default, got %v", err)
+	}
+
+	prev := cfg
+	cfg = DefaultConfig()
+	cfg.Ingest.DuplicateThreshold = 0.99
+	t.Cleanup(func() { cfg = prev })
+
+	var dup *DuplicateError
+	err := rag.checkDuplicate(ctx, "new", [][]float32{{0, 0, 1}})
+	if !errors.As(err, &dup) || (dup.DuplicateOf != "c" && dup.DuplicateOf != "h") {
+		t.Errorf("checkDuplicate = %v, want duplicate of c or h", err)
+	}
+	if err := rag.checkDuplicate(ctx, "c", [][]float32{{0, 0.5, 0.5}}); err != nil {
+		t.Errorf("dissimilar document rejected: %v", err)
+	}
+	if err := rag.checkDuplicate(ctx, "f2", [][]float32{{1, 0, 0}, {0, 0.5, 0.5}}); err != nil {
+		t.Errorf("file with one unmatched chunk rejected: %v", err)
+	}
+	err = rag.checkDuplicate(ctx, "f", [][]float32{{1, 0, 0}, {0, 1, 0}})
+	if !errors.As(err, &dup) || dup.ID != "f" || dup.DuplicateOf == "f" {
+		t.Errorf("re-adding f should match another document, got %v", err)
+	}
+}
+
+func TestInsertDocument_KeepsCreatedAt(t *testing.T) {
+	rag := newTestRAG(t)
+	ctx := context.Background()
+	if err := rag.insertDocument(ctx, "doc", "v1", []float32{1, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rag.db.Exec(`UPDATE documents SET created_at = TIMESTAMP '2020-01-01'`); err != nil {
+		t.Fatal(err)
+	}
+	if err := rag.insertDocument(ctx, "doc", "v2", []float32{0, 1, 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	var content string
+	var created time.Time
+	if err := rag.db.QueryRow(`SELECT content, created_at FROM documents WHERE id = 'doc'`).Scan(&content, &created); err != nil {
+		t.Fatal(err)
+	}
+	if content != "v2" || created.Year() != 2020 {
+		t.Errorf("after replace: content %q, created %v", content, created)
+	}
+}
+
+func TestDedupeCommand(t *testing.T) {
+	rag := newDedupeTestRAG(t)
+	buf := captureOutput(t, formatJSON)
+
+	if err := RunCommand(&DedupeCommand{}, rag, []string{"-delete"}); err != nil {
+		t.Fatalf("dedupe failed: %v", err)
+	}
+	var report DedupeReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if report.Action != "delete" || report.Removed != 2 || len(report.Clusters) != 2 {
+		t.Errorf("report = %+v", report)
+	}
+	if _, err := rag.GetDocument("g"); err == nil {
+		t.Error("duplicate g was not deleted")
+	}
+
+	if err := RunCommand(&DedupeCommand{}, rag, []string{"-delete", "-merge"}); err == nil {
+		t.Error("expected error for --delete with --merge")
+	}
+}
@@ -9,7 +9,8 @@
 //   - CLI — a set of subcommands (add, query, list, delete, serve) for managing
 //     documents and running the server.
 //   - RAG core — handles embedding generation through YZMA/llama.cpp, document
-//     storage in DuckDB, and cosine-similarity vector search.
+//     storage in DuckDB, and cosine-similarity vector search. The model is
+//     loaded on first use, so commands that do not embed text run without one.
 //   - MCP server — exposes the RAG system as a Model Context Protocol server
 //     with configurable transports (stdio, SSE, Streamable HTTP) for integration
 //     with AI assistants such as Claude and Amp.
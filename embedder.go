@@ -0,0 +1,112 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/hybridgroup/yzma/pkg/llama"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Embedder generates embedding vectors with a llama.cpp GGUF model.
+type Embedder struct {
+	model llama.Model
+	vocab llama.Vocab
+	ctx   llama.Context
+	dim   int32
+
+	// mu serializes use of the llama context, which is not safe for
+	// concurrent decoding.
+	mu sync.Mutex
+}
+
+// NewEmbedder loads the llama.cpp library from libPath and the embedding model
+// from modelPath, using the context and batch sizes from the configuration.
+func NewEmbedder(modelPath, libPath string, logger *slog.Logger) (*Embedder, error) {
+	if err := llama.Load(libPath); err != nil {
+		return nil, fmt.Errorf("unable to load llama library: %w", err)
+	}
+
+	routeLlamaLogs()
+	llama.Init()
+	start := time.Now()
+
+	model, err := llama.ModelLoadFromFile(modelPath, llama.ModelDefaultParams())
+	if err != nil {
+		llama.Close()
+		return nil, fmt.Errorf("unable to load model from %s: %w", modelPath, err)
+	}
+	if model == 0 {
+		llama.Close()
+		return nil, fmt.Errorf("failed to load model from %s", modelPath)
+	}
+
+	ctxParams := llama.ContextDefaultParams()
+	if cfg != nil {
+		ctxParams.NCtx = uint32(cfg.ContextSize)
+		ctxParams.NBatch = uint32(cfg.BatchSize)
+	}
+	ctxParams.PoolingType = llama.PoolingTypeMean
+	ctxParams.Embeddings = 1
+
+	lctx, err := llama.InitFromModel(model, ctxParams)
+	if err != nil {
+		llama.ModelFree(model)
+		llama.Close()
+		return nil, fmt.Errorf("unable to initialize context: %w", err)
+	}
+
+	e := &Embedder{
+		model: model,
+		vocab: llama.ModelGetVocab(model),
+		ctx:   lctx,
+		dim:   llama.ModelNEmbd(model),
+	}
+	logger.Info("model loaded", "model", modelPath, "embedding_dim", e.dim, "duration", time.Since(start))
+	return e, nil
+}
+
+// Dim returns the number of dimensions in the model's embeddings.
+func (e *Embedder) Dim() int32 {
+	return e.dim
+}
+
+// Embed returns the raw embedding of text, recording tokenize and decode spans
+// under ctx and the embedding metrics. It also returns the number of tokens.
+func (e *Embedder) Embed(ctx context.Context, text string) ([]float32, int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	start := time.Now()
+	_, tokSpan := tracer().Start(ctx, "tokenize")
+	tokens := llama.Tokenize(e.vocab, text, true, true)
+	tokSpan.SetAttributes(attribute.Int("tokens", len(tokens)))
+	tokSpan.End()
+
+	_, decSpan := tracer().Start(ctx, "decode")
+	batch := llama.BatchGetOne(tokens)
+	llama.Decode(e.ctx, batch)
+	vec, err := llama.GetEmbeddingsSeq(e.ctx, 0, e.dim)
+	decSpan.End()
+	if err != nil {
+		return nil, len(tokens), fmt.Errorf("failed to get embeddings: %w", err)
+	}
+
+	embeddingDuration.Observe(time.Since(start).Seconds())
+	embeddingTokens.Observe(float64(len(tokens)))
+	return vec, len(tokens), nil
+}
+
+// Close frees the context and model and unloads the llama.cpp library.
+func (e *Embedder) Close() {
+	if e.ctx != 0 {
+		llama.Free(e.ctx)
+	}
+	if e.model != 0 {
+		llama.ModelFree(e.model)
+	}
+	llama.Close()
+}
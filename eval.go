@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// EvalQuery is one line of a qrels file: a query and the IDs of the documents
+// relevant to it. A relevant ID may name a standalone document, a chunk, or an
+// ingested file, in which case any of the file's chunks matches it.
+type EvalQuery struct {
+	ID       string   `json:"id,omitempty"`
+	Query    string   `json:"query"`
+	Relevant []string `json:"relevant"`
+}
+
+// EvalMetrics holds retrieval quality measured at a cutoff k. Relevance is
+// binary and each relevant ID is credited at most once, at its best rank.
+type EvalMetrics struct {
+	Recall    float64 `json:"recall"`
+	Precision float64 `json:"precision"`
+	MRR       float64 `json:"mrr"`
+	NDCG      float64 `json:"ndcg"`
+}
+
+// QueryEval is the evaluation of a single query.
+type QueryEval struct {
+	ID        string      `json:"id,omitempty"`
+	Query     string      `json:"query"`
+	Relevant  []string    `json:"relevant"`
+	Retrieved []string    `json:"retrieved"`
+	Metrics   EvalMetrics `json:"metrics"`
+}
+
+// EvalReport is the result of evaluating a set of queries. Metrics are the
+// mean of the per-query metrics.
+type EvalReport struct {
+	K        int         `json:"k"`
+	Queries  int         `json:"queries"`
+	Settings RunSettings `json:"settings"`
+	Metrics  EvalMetrics `json:"metrics"`
+	PerQuery []QueryEval `json:"per_query"`
+}
+
+// searchFunc runs a similarity search, as RAGSystem.Query does.
+type searchFunc func(ctx context.Context, query string, topK int) ([]SearchResult, error)
+
+// loadQrels reads a JSON Lines file of EvalQuery values. Blank lines are
+// skipped; every query must have text and at least one relevant ID.
+func loadQrels(path string) ([]EvalQuery, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open qrels file: %w", err)
+	}
+	defer f.Close()
+
+	var queries []EvalQuery
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		var q EvalQuery
+		if err := json.Unmarshal([]byte(text), &q); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, line, err)
+		}
+		if q.Query == "" {
+			return nil, fmt.Errorf("%s:%d: missing query", path, line)
+		}
+		if len(q.Relevant) == 0 {
+			return nil, fmt.Errorf("%s:%d: no relevant document IDs", path, line)
+		}
+		queries = append(queries, q)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read qrels file: %w", err)
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("%s contains no queries", path)
+	}
+	return queries, nil
+}
+
+// evaluate runs every query through search with a cutoff of k and scores the
+// results against the relevant IDs.
+func evaluate(ctx context.Context, search searchFunc, queries []EvalQuery, k int) (EvalReport, error) {
+	if k <= 0 {
+		return EvalReport{}, fmt.Errorf("k must be positive, got %d", k)
+	}
+
+	report := EvalReport{K: k, Queries: len(queries)}
+	for _, q := range queries {
+		results, err := search(ctx, q.Query, k)
+		if err != nil {
+			return EvalReport{}, fmt.Errorf("query %q: %w", q.Query, err)
+		}
+		metrics, retrieved := scoreRanking(results, q.Relevant, k)
+		report.PerQuery = append(report.PerQuery, QueryEval{
+			ID:        q.ID,
+			Query:     q.Query,
+			Relevant:  q.Relevant,
+			Retrieved: retrieved,
+			Metrics:   metrics,
+		})
+		report.Metrics.Recall += metrics.Recall
+		report.Metrics.Precision += metrics.Precision
+		report.Metrics.MRR += metrics.MRR
+		report.Metrics.NDCG += metrics.NDCG
+	}
+
+	n := float64(len(queries))
+	if n > 0 {
+		report.Metrics.Recall /= n
+		report.Metrics.Precision /= n
+		report.Metrics.MRR /= n
+		report.Metrics.NDCG /= n
+	}
+	return report, nil
+}
+
+// scoreRanking computes recall, precision, reciprocal rank, and nDCG for the
+// first k results against the relevant IDs. A result matches a relevant ID
+// equal to its own ID or its parent's. It also returns the IDs of the results
+// considered.
+func scoreRanking(results []SearchResult, relevant []string, k int) (EvalMetrics, []string) {
+	want := make(map[string]bool, len(relevant))
+	for _, id := range relevant {
+		want[id] = true
+	}
+
+	var m EvalMetrics
+	var hits int
+	var dcg float64
+	credited := make(map[string]bool)
+	retrieved := []string{}
+	for i, r := range results {
+		if i >= k {
+			break
+		}
+		retrieved = append(retrieved, r.ID)
+
+		key := ""
+		switch {
+		case want[r.ID]:
+			key = r.ID
+		case r.ParentID != "" && want[r.ParentID]:
+			key = r.ParentID
+		}
+		if key == "" || credited[key] {
+			continue
+		}
+		credited[key] = true
+		hits++
+		rank := i + 1
+		dcg += 1 / math.Log2(float64(rank)+1)
+		if m.MRR == 0 {
+			m.MRR = 1 / float64(rank)
+		}
+	}
+
+	var idcg float64
+	for rank := 1; rank <= min(len(want), k); rank++ {
+		idcg += 1 / math.Log2(float64(rank)+1)
+	}
+
+	if len(want) > 0 {
+		m.Recall = float64(hits) / float64(len(want))
+	}
+	m.Precision = float64(hits) / float64(k)
+	if idcg > 0 {
+		m.NDCG = dcg / idcg
+	}
+	return m, retrieved
+}
+
+// loadEvalReport reads a report previously written by "eval -json".
+func loadEvalReport(path string) (EvalReport, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return EvalReport{}, fmt.Errorf("failed to read baseline: %w", err)
+	}
+	var report EvalReport
+	if err := json.Unmarshal(data, &report); err != nil {
+		return EvalReport{}, fmt.Errorf("failed to parse baseline: %w", err)
+	}
+	return report, nil
+}
+
+// regressions lists the metrics in got that fall more than tolerance below
+// the same metric in baseline.
+func regressions(got, baseline EvalMetrics, tolerance float64) []string {
+	var out []string
+	check := func(name string, g, b float64) {
+		if g < b-tolerance {
+			out = append(out, fmt.Sprintf("%s %.4f < baseline %.4f", name, g, b))
+		}
+	}
+	check("recall", got.Recall, baseline.Recall)
+	check("precision", got.Precision, baseline.Precision)
+	check("mrr", got.MRR, baseline.MRR)
+	check("ndcg", got.NDCG, baseline.NDCG)
+	return out
+}
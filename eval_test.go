@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < epsilon
+}
+
+func TestScoreRanking(t *testing.T) {
+	results := []SearchResult{
+		{ID: "x"},
+		{ID: "a"},
+		{ID: "y"},
+		{ID: "b"},
+	}
+	m, retrieved := scoreRanking(results, []string{"a", "b", "c"}, 4)
+
+	if !approxEqual(m.Recall, 2.0/3) {
+		t.Errorf("Recall = %v, want 2/3", m.Recall)
+	}
+	if !approxEqual(m.Precision, 0.5) {
+		t.Errorf("Precision = %v, want 0.5", m.Precision)
+	}
+	if !approxEqual(m.MRR, 0.5) {
+		t.Errorf("MRR = %v, want 0.5", m.MRR)
+	}
+	dcg := 1/math.Log2(3) + 1/math.Log2(5)
+	idcg := 1 + 1/math.Log2(3) + 1/math.Log2(4)
+	if !approxEqual(m.NDCG, dcg/idcg) {
+		t.Errorf("NDCG = %v, want %v", m.NDCG, dcg/idcg)
+	}
+	if strings.Join(retrieved, ",") != "x,a,y,b" {
+		t.Errorf("retrieved = %v", retrieved)
+	}
+}
+
+func TestScoreRanking_Cutoff(t *testing.T) {
+	results := []SearchResult{{ID: "x"}, {ID: "a"}}
+	m, retrieved := scoreRanking(results, []string{"a"}, 1)
+	if m.Recall != 0 || m.MRR != 0 || m.NDCG != 0 {
+		t.Errorf("relevant result beyond k was credited: %+v", m)
+	}
+	if len(retrieved) != 1 {
+		t.Errorf("retrieved = %v, want only the first result", retrieved)
+	}
+}
+
+func TestScoreRanking_ChunksMatchParent(t *testing.T) {
+	results := []SearchResult{
+		{ID: "manual#3", ParentID: "manual"},
+		{ID: "manual#0", ParentID: "manual"},
+		{ID: "note"},
+	}
+	m, _ := scoreRanking(results, []string{"manual"}, 3)
+
+	if m.Recall != 1 || m.MRR != 1 || !approxEqual(m.NDCG, 1) {
+		t.Errorf("chunk of relevant file not credited: %+v", m)
+	}
+	if !approxEqual(m.Precision, 1.0/3) {
+		t.Errorf("Precision = %v, want 1/3 (each relevant ID counted once)", m.Precision)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	ranked := map[string][]SearchResult{
+		"first":  {{ID: "a"}, {ID: "b"}},
+		"second": {{ID: "b"}, {ID: "c"}},
+	}
+	search := func(ctx context.Context, query string, topK int) ([]SearchResult, error) {
+		return ranked[query], nil
+	}
+	queries := []EvalQuery{
+		{ID: "q1", Query: "first", Relevant: []string{"a"}},
+		{ID: "q2", Query: "second", Relevant: []string{"c"}},
+	}
+
+	report, err := evaluate(context.Background(), search, queries, 2)
+	if err != nil {
+		t.Fatalf("evaluate failed: %v", err)
+	}
+	if report.K != 2 || report.Queries != 2 || len(report.PerQuery) != 2 {
+		t.Fatalf("unexpected report shape: %+v", report)
+	}
+	if !approxEqual(report.Metrics.Recall, 1) {
+		t.Errorf("Recall = %v, want 1", report.Metrics.Recall)
+	}
+	if !approxEqual(report.Metrics.MRR, 0.75) {
+		t.Errorf("MRR = %v, want 0.75", report.Metrics.MRR)
+	}
+	if !approxEqual(report.Metrics.Precision, 0.5) {
+		t.Errorf("Precision = %v, want 0.5", report.Metrics.Precision)
+	}
+}
+
+func TestEvaluate_Errors(t *testing.T) {
+	failing := func(ctx context.Context, query string, topK int) ([]SearchResult, error) {
+		return nil, errors.New("boom")
+	}
+	queries := []EvalQuery{{Query: "q", Relevant: []string{"a"}}}
+	if _, err := evaluate(context.Background(), failing, queries, 5); err == nil {
+		t.Error("expected search error to be returned")
+	}
+	if _, err := evaluate(context.Background(), failing, queries, 0); err == nil {
+		t.Error("expected error for k=0")
+	}
+}
+
+func TestLoadQrels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "qrels.jsonl")
+	content := `{"id": "q1", "query": "how to install", "relevant": ["install-guide"]}
+
+{"query": "pricing", "relevant": ["faq", "pricing"]}
+`
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	queries, err := loadQrels(path)
+	if err != nil {
+		t.Fatalf("loadQrels failed: %v", err)
+	}
+	if len(queries) != 2 || queries[0].ID != "q1" || len(queries[1].Relevant) != 2 {
+		t.Errorf("unexpected queries: %+v", queries)
+	}
+}
+
+func TestLoadQrels_Invalid(t *testing.T) {
+	tests := map[string]string{
+		"bad json":            "{not json}\n",
+		"no query":            `{"relevant": ["a"]}` + "\n",
+		"no relevant":         `{"query": "q"}` + "\n",
+		"empty file":          "\n\n",
+		"relevant not a list": `{"query": "q", "relevant": "a"}` + "\n",
+	}
+	for name, content := range tests {
+		path := filepath.Join(t.TempDir(), "qrels.jsonl")
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := loadQrels(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+	if _, err := loadQrels(filepath.Join(t.TempDir(), "missing.jsonl")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
+
+func TestRegressions(t *testing.T) {
+	baseline := EvalMetrics{Recall: 0.8, Precision: 0.4, MRR: 0.7, NDCG: 0.75}
+
+	got := EvalMetrics{Recall: 0.79, Precision: 0.4, MRR: 0.6, NDCG: 0.8}
+	regressed := regressions(got, baseline, 0.02)
+	if len(regressed) != 1 || !strings.HasPrefix(regressed[0], "mrr") {
+		t.Errorf("regressions = %v, want only mrr", regressed)
+	}
+	if r := regressions(baseline, baseline, 0); len(r) != 0 {
+		t.Errorf("identical metrics reported as regressions: %v", r)
+	}
+}
+
+func TestLoadEvalReport_RoundTrip(t *testing.T) {
+	want := EvalReport{K: 5, Queries: 1, Metrics: EvalMetrics{Recall: 0.5, MRR: 1}}
+	data, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadEvalReport(path)
+	if err != nil {
+		t.Fatalf("loadEvalReport failed: %v", err)
+	}
+	if got.K != want.K || got.Metrics != want.Metrics {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestEvalCommand_MissingArgs(t *testing.T) {
+	cmd := &EvalCommand{}
+	cmd.Flags()
+	err := cmd.Run(nil, []string{})
+	if err == nil || !strings.Contains(err.Error(), "usage") {
+		t.Fatalf("expected usage error, got: %v", err)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// fileFormat describes a file format that can be ingested. Files are matched
+// to a format by extension, then by MIME type, then by sniffing their
+// contents.
+type fileFormat struct {
+	name       string
+	extensions []string // lower case, with the leading dot
+	mimeTypes  []string
+	sniff      func(data []byte) bool // optional; reports whether data is in this format
+
+	// read returns the text of data split into sections, and metadata
+	// describing the whole document.
+	read func(data []byte) ([]section, map[string]string, error)
+}
+
+// formats is the registry of ingestible file formats, in lookup order.
+var formats []*fileFormat
+
+// registerFormat adds f to the format registry.
+func registerFormat(f *fileFormat) {
+	formats = append(formats, f)
+}
+
+func init() {
+	registerFormat(plainText)
+	registerFormat(&fileFormat{
+		name:       "PDF",
+		extensions: []string{".pdf"},
+		mimeTypes:  []string{"application/pdf"},
+		sniff:      func(data []byte) bool { return bytes.HasPrefix(data, []byte("%PDF-")) },
+		read: func(data []byte) ([]section, map[string]string, error) {
+			doc, err := ReadPDFDocument(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			return []section{pdfSection(doc)}, pdfMetadata(doc), nil
+		},
+	})
+	registerFormat(&fileFormat{
+		name:       "Markdown",
+		extensions: []string{".md", ".markdown", ".mdown", ".mkd"},
+		mimeTypes:  []string{"text/markdown", "text/x-markdown"},
+		read: func(data []byte) ([]section, map[string]string, error) {
+			if !utf8.Valid(data) {
+				return nil, nil, fmt.Errorf("invalid UTF-8")
+			}
+			return splitMarkdown(string(data)), nil, nil
+		},
+	})
+	registerFormat(&fileFormat{
+		name:       "HTML",
+		extensions: []string{".html", ".htm", ".xhtml"},
+		mimeTypes:  []string{"text/html", "application/xhtml+xml"},
+		read: func(data []byte) ([]section, map[string]string, error) {
+			doc, err := ReadHTMLBytes(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			metadata := make(map[string]string)
+			if doc.Title != "" {
+				metadata["title"] = doc.Title
+			}
+			if doc.Description != "" {
+				metadata["description"] = doc.Description
+			}
+			return splitMarkdown(doc.Text), metadata, nil
+		},
+	})
+}
+
+// plainText is the format of text files, and of files that match no other
+// format but hold valid UTF-8.
+var plainText = &fileFormat{
+	name:       "text",
+	extensions: []string{".txt", ".text"},
+	mimeTypes:  []string{"text/plain"},
+	read: func(data []byte) ([]section, map[string]string, error) {
+		if !utf8.Valid(data) {
+			return nil, nil, fmt.Errorf("invalid UTF-8")
+		}
+		return []section{{text: string(data)}}, nil, nil
+	},
+}
+
+// formatFor returns the format of a file with the given name, MIME type, and
+// contents, or nil if it is not supported. The MIME type may be empty;
+// parameters such as charset are ignored.
+func formatFor(name, mimeType string, data []byte) *fileFormat {
+	if f := formatForExtension(name); f != nil {
+		return f
+	}
+	if mt, _, err := mime.ParseMediaType(mimeType); err == nil {
+		for _, f := range formats {
+			if slices.Contains(f.mimeTypes, mt) {
+				return f
+			}
+		}
+	}
+	for _, f := range formats {
+		if f.sniff != nil && f.sniff(data) {
+			return f
+		}
+	}
+	if strings.HasPrefix(http.DetectContentType(data), "text/html") {
+		return formatFor("", "text/html", nil)
+	}
+	if utf8.Valid(data) {
+		return plainText
+	}
+	return nil
+}
+
+// formatForExtension returns the format registered for the extension of name,
+// or nil if there is none.
+func formatForExtension(name string) *fileFormat {
+	ext := strings.ToLower(filepath.Ext(name))
+	if ext == "" {
+		return nil
+	}
+	for _, f := range formats {
+		if slices.Contains(f.extensions, ext) {
+			return f
+		}
+	}
+	return nil
+}
+
+// extractSections returns the text held in data split into sections, and
+// metadata describing the whole document, reading it with the format chosen
+// by formatFor.
+func extractSections(name, mimeType string, data []byte) ([]section, map[string]string, error) {
+	f := formatFor(name, mimeType, data)
+	if f == nil {
+		return nil, nil, fmt.Errorf("unsupported file format: %s", name)
+	}
+	sections, metadata, err := f.read(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s %s: %w", f.name, name, err)
+	}
+	return sections, metadata, nil
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+)
+
+// version is the ydrag release reported to MCP clients and by /info.
+const version = "1.0.0"
+
+// KBInfo describes the running server, its embedding model, and the size of
+// the knowledge base.
+type KBInfo struct {
+	Version          string `json:"version"`
+	Model            string `json:"model"`
+	ModelFingerprint string `json:"model_fingerprint"`
+	EmbeddingDim     int    `json:"embedding_dim"`
+	Documents        int    `json:"documents"`
+	Chunks           int    `json:"chunks"`
+}
+
+// Ready reports whether the RAG system can serve requests: the database must
+// respond, the model must be loaded, and a test embedding must succeed.
+func (r *RAGSystem) Ready(ctx context.Context) error {
+	if err := r.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("database unreachable: %w", err)
+	}
+	if !r.ModelLoaded() {
+		return fmt.Errorf("model not loaded")
+	}
+	vec, err := r.GenerateEmbedding(ctx, "readiness check")
+	if err != nil {
+		return fmt.Errorf("test embedding failed: %w", err)
+	}
+	if len(vec) != int(r.embeddingDim) {
+		return fmt.Errorf("test embedding has %d dimensions, want %d", len(vec), r.embeddingDim)
+	}
+	return nil
+}
+
+// Info returns the version, model details, and document counts of the RAG
+// system. Documents counts files and standalone documents, with all chunks
+// of a file counted once; Chunks counts every stored row.
+func (r *RAGSystem) Info() (KBInfo, error) {
+	info := KBInfo{
+		Version:      version,
+		EmbeddingDim: int(r.embeddingDim),
+	}
+	if r.modelPath != "" {
+		info.Model = filepath.Base(r.modelPath)
+		if fp, err := modelFingerprint(r.modelPath); err == nil {
+			info.ModelFingerprint = fp
+		}
+	}
+
+	if !r.hasTable() {
+		return info, nil
+	}
+	err := r.db.QueryRow(`
+		SELECT count(DISTINCT `+documentKey+`), count(*)
+		FROM documents
+	`).Scan(&info.Documents, &info.Chunks)
+	if err != nil {
+		return info, fmt.Errorf("failed to count documents: %w", err)
+	}
+	return info, nil
+}
+
+// fingerprintSampleSize is how much of the model file modelFingerprint hashes.
+const fingerprintSampleSize = 1 << 20
+
+// modelFingerprint returns a short identifier for the model file at path,
+// derived from its size and the SHA-256 of its first megabyte. GGUF files keep
+// their metadata and tensor layout at the start, so this distinguishes models
+// without reading multi-gigabyte files in full.
+func modelFingerprint(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.New()
+	binary.Write(h, binary.LittleEndian, info.Size())
+	if _, err := io.CopyN(h, f, fingerprintSampleSize); err != nil && err != io.EOF {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil))[:16], nil
+}
+
+// handleHealth serves /healthz, which succeeds whenever the process is running.
+func (m *MCPServer) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+}
+
+// handleReady serves /readyz, which succeeds only when the RAG system passes
+// its readiness checks.
+func (m *MCPServer) handleReady(w http.ResponseWriter, r *http.Request) {
+	if err := m.rag.Ready(r.Context()); err != nil {
+		writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable", "error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+}
+
+// handleInfo serves /info with the version, model, and document counts.
+func (m *MCPServer) handleInfo(w http.ResponseWriter, r *http.Request) {
+	info, err := m.rag.Info()
+	if err != nil {
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, info)
+}
+
+// writeJSON writes v as a JSON response with the given status code.
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
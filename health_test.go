@@ -0,0 +1,142 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newHealthTestServer serves the streamable HTTP handler of an MCP server
+// backed by a model-less test RAG system holding one file and one document.
+func newHealthTestServer(t *testing.T, a AuthConfig) *httptest.Server {
+	t.Helper()
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "file#0", "first", "file", "", []float32{1, 0, 0})
+	insertTestDoc(t, rag, "file#1", "second", "file", "", []float32{0, 1, 0})
+	insertTestDoc(t, rag, "note", "standalone", "", "", []float32{0, 0, 1})
+
+	m := NewMCPServer(rag)
+	m.auth = a
+	handler, err := m.httpHandler("streamable-http")
+	if err != nil {
+		t.Fatalf("httpHandler failed: %v", err)
+	}
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func TestHealthz(t *testing.T) {
+	ts := newHealthTestServer(t, AuthConfig{Tokens: []TokenConfig{{Token: "secret"}}})
+
+	resp, err := http.Get(ts.URL + "/healthz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d (no token required)", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestReadyz_ModelNotLoaded(t *testing.T) {
+	ts := newHealthTestServer(t, AuthConfig{})
+
+	resp, err := http.Get(ts.URL + "/readyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	var body map[string]string
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(body["error"], "model not loaded") {
+		t.Errorf("error = %q, want model not loaded", body["error"])
+	}
+}
+
+func TestInfo(t *testing.T) {
+	ts := newHealthTestServer(t, AuthConfig{})
+
+	resp, err := http.Get(ts.URL + "/info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	var info KBInfo
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		t.Fatal(err)
+	}
+	if info.Version != version {
+		t.Errorf("Version = %q, want %q", info.Version, version)
+	}
+	if info.EmbeddingDim != 3 {
+		t.Errorf("EmbeddingDim = %d, want 3", info.EmbeddingDim)
+	}
+	if info.Documents != 2 || info.Chunks != 3 {
+		t.Errorf("Documents/Chunks = %d/%d, want 2/3", info.Documents, info.Chunks)
+	}
+}
+
+func TestInfo_RequiresToken(t *testing.T) {
+	ts := newHealthTestServer(t, AuthConfig{Tokens: []TokenConfig{{Token: "secret"}}})
+
+	resp, err := http.Get(ts.URL + "/info")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusUnauthorized)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/info", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestModelFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.gguf")
+	b := filepath.Join(dir, "b.gguf")
+	os.WriteFile(a, []byte("GGUF model a"), 0644)
+	os.WriteFile(b, []byte("GGUF model b"), 0644)
+
+	fa, err := modelFingerprint(a)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fa2, _ := modelFingerprint(a)
+	fb, _ := modelFingerprint(b)
+
+	if !strings.HasPrefix(fa, "sha256:") {
+		t.Errorf("fingerprint %q lacks sha256: prefix", fa)
+	}
+	if fa != fa2 {
+		t.Error("expected fingerprint to be stable")
+	}
+	if fa == fb {
+		t.Error("expected different files to have different fingerprints")
+	}
+	if _, err := modelFingerprint(filepath.Join(dir, "missing.gguf")); err == nil {
+		t.Error("expected error for missing file")
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Record is a document read from structured data, with the metadata to store
+// on it.
+type Record struct {
+	ID       string            `json:"id"`
+	Content  string            `json:"content"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// dataRow is a row of a CSV, JSON, or JSONL file, with every value as text.
+// num is the row's line for CSV files, where the header is line 1, and JSONL
+// files, and its position from 1 in a JSON array.
+type dataRow struct {
+	num    int
+	values map[string]string
+}
+
+// RowError reports a row that could not be imported.
+type RowError struct {
+	Row   int    `json:"row"`
+	ID    string `json:"id,omitempty"`
+	Error string `json:"error"`
+}
+
+// csvDelimiters are the delimiters readCSVRows chooses between.
+var csvDelimiters = []rune{',', '\t', ';', '|'}
+
+// readCSVRows reads CSV rows with a header row. The delimiter is whichever of
+// csvDelimiters is most common in the header, so TSV and semicolon-separated
+// files need no option. Rows are numbered by the line they start on, so a
+// quoted value spanning lines does not shift the rows after it. Malformed
+// rows, including those with the wrong number of fields, are skipped and
+// returned as errors.
+func readCSVRows(r io.Reader) ([]dataRow, []RowError, error) {
+	const peek = 64 * 1024
+	br := bufio.NewReaderSize(r, peek)
+	if bom, _ := br.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		br.Discard(3)
+	}
+	// Short files peek as a whole, with an io.EOF error.
+	head, _ := br.Peek(peek)
+	header, _, _ := bytes.Cut(head, []byte("\n"))
+	delim, best := ',', 0
+	for _, d := range csvDelimiters {
+		if n := bytes.Count(header, []byte(string(d))); n > best {
+			delim, best = d, n
+		}
+	}
+
+	cr := csv.NewReader(br)
+	cr.Comma = delim
+	cols, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	for i, c := range cols {
+		cols[i] = strings.TrimSpace(c)
+	}
+
+	var rows []dataRow
+	var failed []RowError
+	for {
+		record, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		var perr *csv.ParseError
+		if errors.As(err, &perr) {
+			failed = append(failed, RowError{Row: perr.StartLine, Error: perr.Err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := cr.FieldPos(0)
+		values := make(map[string]string, len(cols))
+		for i, c := range cols {
+			values[c] = record[i]
+		}
+		rows = append(rows, dataRow{num: line, values: values})
+	}
+	return rows, failed, nil
+}
+
+// readJSONRows reads a JSON array of objects, or JSON Lines with one object
+// per line when lines is set. Nested values are kept as compact JSON. A JSON
+// Lines line that is not an object is returned as an error; blank lines are
+// skipped.
+func readJSONRows(r io.Reader, lines bool) ([]dataRow, []RowError, error) {
+	var rows []dataRow
+	var failed []RowError
+	if !lines {
+		var items []json.RawMessage
+		if err := json.NewDecoder(r).Decode(&items); err != nil {
+			return nil, nil, fmt.Errorf("failed to read JSON array: %w", err)
+		}
+		for i, item := range items {
+			values, err := jsonObjectValues(item)
+			if err != nil {
+				failed = append(failed, RowError{Row: i + 1, Error: err.Error()})
+				continue
+			}
+			rows = append(rows, dataRow{num: i + 1, values: values})
+		}
+		return rows, failed, nil
+	}
+
+	sc := bufio.NewScanner(r)
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for n := 1; sc.Scan(); n++ {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		values, err := jsonObjectValues(line)
+		if err != nil {
+			failed = append(failed, RowError{Row: n, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, dataRow{num: n, values: values})
+	}
+	if err := sc.Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to read JSON Lines: %w", err)
+	}
+	return rows, failed, nil
+}
+
+// jsonObjectValues returns the fields of a JSON object as text: strings as
+// they are, null as "", and numbers, booleans, arrays, and objects as JSON.
+func jsonObjectValues(data []byte) (map[string]string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, fmt.Errorf("invalid JSON object: %w", err)
+	}
+	if fields == nil {
+		return nil, fmt.Errorf("invalid JSON object: null")
+	}
+	values := make(map[string]string, len(fields))
+	for k, v := range fields {
+		var s string
+		switch {
+		case json.Unmarshal(v, &s) == nil:
+			values[k] = s
+		case string(v) == "null":
+			values[k] = ""
+		default:
+			var b bytes.Buffer
+			if err := json.Compact(&b, v); err != nil {
+				return nil, fmt.Errorf("invalid JSON value for %q: %w", k, err)
+			}
+			values[k] = b.String()
+		}
+	}
+	return values, nil
+}
+
+// parseRecord parses a JSON Lines record of the form
+// {"id": "...", "content": "...", "metadata": {...}}. Metadata values that are
+// not strings are kept as JSON, as by jsonObjectValues.
+func parseRecord(line []byte) (Record, error) {
+	var raw struct {
+		ID       string          `json:"id"`
+		Content  string          `json:"content"`
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	if err := json.Unmarshal(line, &raw); err != nil {
+		return Record{}, fmt.Errorf("invalid record: %w", err)
+	}
+	rec := Record{ID: strings.TrimSpace(raw.ID), Content: strings.TrimSpace(raw.Content)}
+	if len(raw.Metadata) > 0 && string(raw.Metadata) != "null" {
+		meta, err := jsonObjectValues(raw.Metadata)
+		if err != nil {
+			return rec, fmt.Errorf("invalid metadata: %w", err)
+		}
+		rec.Metadata = meta
+	}
+	switch {
+	case rec.ID == "":
+		return rec, fmt.Errorf("empty id")
+	case rec.Content == "":
+		return rec, fmt.Errorf("empty content")
+	}
+	return rec, nil
+}
+
+// importMapping turns data rows into records.
+type importMapping struct {
+	id       string             // column holding the ID; "" to use "id" if present
+	content  string             // column holding the content, unless template is set
+	template *template.Template // executed with the row's values to build the content
+	metadata []string           // columns stored as metadata; "*" for all others
+	source   string             // file name, recorded as the "source" metadata
+}
+
+// record returns the record for row. Without an ID column, the ID is
+// "<source>:<row>".
+func (m importMapping) record(row dataRow) (Record, error) {
+	rec := Record{Metadata: map[string]string{"source": m.source, "row": strconv.Itoa(row.num)}}
+
+	idCol := m.id
+	if idCol == "" {
+		idCol = "id"
+	}
+	switch id, ok := row.values[idCol]; {
+	case ok:
+		rec.ID = strings.TrimSpace(id)
+		if rec.ID == "" {
+			return rec, fmt.Errorf("empty id")
+		}
+	case m.id != "":
+		return rec, fmt.Errorf("missing id column %q", m.id)
+	default:
+		rec.ID = fmt.Sprintf("%s:%d", filepath.Base(m.source), row.num)
+	}
+
+	if m.template != nil {
+		var b strings.Builder
+		if err := m.template.Execute(&b, row.values); err != nil {
+			return rec, fmt.Errorf("failed to execute template: %w", err)
+		}
+		rec.Content = b.String()
+	} else {
+		rec.Content = row.values[m.content]
+	}
+	rec.Content = strings.TrimSpace(rec.Content)
+	if rec.Content == "" {
+		return rec, fmt.Errorf("empty content")
+	}
+
+	for _, col := range m.metadata {
+		if col != "*" {
+			if v := row.values[col]; v != "" {
+				rec.Metadata[col] = v
+			}
+			continue
+		}
+		for _, k := range slices.Sorted(maps.Keys(row.values)) {
+			if v := row.values[k]; v != "" && k != idCol && (m.template != nil || k != m.content) {
+				rec.Metadata[k] = v
+			}
+		}
+	}
+	return rec, nil
+}
+
+// AddRecords embeds records and stores them in one transaction, replacing
+// documents with the same IDs but keeping their creation time. A record that
+// cannot be embedded, or that is rejected as a near-duplicate, is not stored.
+// It returns the error of each record, nil for those stored, and an error if
+// the transaction fails.
+func (r *RAGSystem) AddRecords(ctx context.Context, records []Record) ([]error, error) {
+	errs := make([]error, len(records))
+	embeddings := make([]string, len(records))
+	for i, rec := range records {
+		vec, err := r.GenerateEmbedding(ctx, rec.Content)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to generate embedding: %w", err)
+			continue
+		}
+		if err := r.checkDuplicate(ctx, rec.ID, [][]float32{vec}); err != nil {
+			errs[i] = err
+			continue
+		}
+		embeddings[i] = floatArrayToSQL(vec)
+	}
+	if !slices.ContainsFunc(errs, func(err error) bool { return err == nil }) {
+		return errs, nil
+	}
+
+	defer observeDB("insert", time.Now())
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for i, rec := range records {
+		if errs[i] != nil {
+			continue
+		}
+		var metaJSON any
+		if len(rec.Metadata) > 0 {
+			data, err := json.Marshal(rec.Metadata)
+			if err != nil {
+				errs[i] = fmt.Errorf("failed to encode metadata: %w", err)
+				continue
+			}
+			metaJSON = string(data)
+		}
+		// Drop the chunks of a file or long text previously stored under the ID.
+		if err := r.deleteChunks(ctx, tx, rec.ID); err != nil {
+			return nil, err
+		}
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO documents (id, content, embedding, metadata, collection, created_at)
+			VALUES (?, ?, ?::FLOAT[], ?, ?, current_timestamp)
+			ON CONFLICT (collection, id) DO UPDATE SET
+				content = excluded.content,
+				embedding = excluded.embedding,
+				parent_id = NULL,
+				chunk_index = NULL,
+				metadata = excluded.metadata
+		`, rec.ID, rec.Content, embeddings[i], metaJSON, r.Collection())
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert document '%s': %w", rec.ID, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	r.log().Debug("stored records", "count", len(records)-countErrors(errs))
+	return errs, nil
+}
+
+// defaultBatchSize is the number of records embedded and stored per
+// transaction unless configured otherwise.
+const defaultBatchSize = 100
+
+// recordWriter stores records in batches, counting those stored in its report
+// and recording those that fail. Records longer than the chunk size are
+// stored at once as chunks, with AddText.
+type recordWriter struct {
+	rag    *RAGSystem
+	size   int
+	report *ImportReport
+
+	batch []Record
+	rows  []int // row number of each batched record
+}
+
+// add stores rec, read from the given row, or queues it for the next batch.
+func (w *recordWriter) add(ctx context.Context, row int, rec Record) error {
+	if size := ingestConfig().ChunkSize; size > 0 && len(rec.Content) > size {
+		if _, err := w.rag.AddText(ctx, rec.ID, rec.Content, rec.Metadata); err != nil {
+			w.report.Failed = append(w.report.Failed, RowError{Row: row, ID: rec.ID, Error: err.Error()})
+		} else {
+			w.report.Imported++
+		}
+		return nil
+	}
+	w.batch = append(w.batch, rec)
+	w.rows = append(w.rows, row)
+	if len(w.batch) < w.size {
+		return nil
+	}
+	return w.flush(ctx)
+}
+
+// flush stores the queued records. It returns an error only if the batch
+// could not be written at all.
+func (w *recordWriter) flush(ctx context.Context) error {
+	if len(w.batch) == 0 {
+		return nil
+	}
+	errs, err := w.rag.AddRecords(ctx, w.batch)
+	if err != nil {
+		return err
+	}
+	for i, err := range errs {
+		if err != nil {
+			w.report.Failed = append(w.report.Failed, RowError{Row: w.rows[i], ID: w.batch[i].ID, Error: err.Error()})
+		} else {
+			w.report.Imported++
+		}
+	}
+	w.rag.log().Info("imported rows", "source", w.report.Source, "imported", w.report.Imported, "failed", len(w.report.Failed))
+	w.batch, w.rows = w.batch[:0], w.rows[:0]
+	return nil
+}
+
+// countErrors returns the number of non-nil errors in errs.
+func countErrors(errs []error) int {
+	n := 0
+	for _, err := range errs {
+		if err != nil {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestReadCSVRows(t *testing.T) {
+	data := "id,question,answer\n1,What?,This\n2,\"Why\nnot\",\"Because, ok\"\n3,x,y,z\n4,ok,\n5,\"bad\"quote,x\n6,last,row\n"
+	rows, failed, err := readCSVRows(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("readCSVRows failed: %v", err)
+	}
+	// Rows are numbered by the line they start on, after the value spanning
+	// lines 3 and 4.
+	want := []dataRow{
+		{2, map[string]string{"id": "1", "question": "What?", "answer": "This"}},
+		{3, map[string]string{"id": "2", "question": "Why\nnot", "answer": "Because, ok"}},
+		{6, map[string]string{"id": "4", "question": "ok", "answer": ""}},
+		{8, map[string]string{"id": "6", "question": "last", "answer": "row"}},
+	}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+	var lines []int
+	for _, f := range failed {
+		if f.Error == "" {
+			t.Errorf("row %d has no error", f.Row)
+		}
+		lines = append(lines, f.Row)
+	}
+	if want := []int{5, 7}; !reflect.DeepEqual(lines, want) {
+		t.Errorf("failed rows = %v, want %v", lines, want)
+	}
+
+	// The delimiter is detected from the header, after any byte order mark.
+	rows, failed, err = readCSVRows(strings.NewReader("\ufeffid\ttext\na\tone, two\n"))
+	if err != nil || len(failed) != 0 {
+		t.Fatalf("TSV: failed = %+v, err = %v", failed, err)
+	}
+	if want := []dataRow{{2, map[string]string{"id": "a", "text": "one, two"}}}; !reflect.DeepEqual(rows, want) {
+		t.Errorf("TSV rows = %v, want %v", rows, want)
+	}
+}
+
+func TestReadJSONRows(t *testing.T) {
+	rows, failed, err := readJSONRows(strings.NewReader(`[{"id": 7, "q": "a", "tags": ["x", "y"], "extra": {"k": true}, "n": null}, 3]`), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []dataRow{{1, map[string]string{"id": "7", "q": "a", "tags": `["x","y"]`, "extra": `{"k":true}`, "n": ""}}}
+	if !reflect.DeepEqual(rows, want) {
+		t.Errorf("rows = %v, want %v", rows, want)
+	}
+	if len(failed) != 1 || failed[0].Row != 2 {
+		t.Errorf("failed = %+v, want row 2", failed)
+	}
+
+	rows, failed, err = readJSONRows(strings.NewReader("{\"q\": \"a\"}\n\n{broken\n{\"q\": \"b\"}\n"), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].num != 1 || rows[1].num != 4 || rows[1].values["q"] != "b" {
+		t.Errorf("rows = %v", rows)
+	}
+	if len(failed) != 1 || failed[0].Row != 3 {
+		t.Errorf("failed = %+v, want line 3", failed)
+	}
+
+	if _, _, err := readJSONRows(strings.NewReader(`{"not": "an array"}`), false); err == nil {
+		t.Error("expected error for a JSON object instead of an array")
+	}
+}
+
+func TestImportMapping(t *testing.T) {
+	row := dataRow{num: 3, values: map[string]string{"key": "k1", "question": "Q?", "answer": "A.", "team": "ops", "empty": ""}}
+
+	m := importMapping{id: "key", content: "answer", metadata: []string{"team"}, source: "faq.csv"}
+	rec, err := m.record(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Record{ID: "k1", Content: "A.", Metadata: map[string]string{"source": "faq.csv", "row": "3", "team": "ops"}}
+	if !reflect.DeepEqual(rec, want) {
+		t.Errorf("record = %+v, want %+v", rec, want)
+	}
+
+	m = importMapping{
+		template: template.Must(template.New("").Option("missingkey=zero").Parse("{{.question}}\n\n{{.answer}}{{.missing}}")),
+		metadata: []string{"*"},
+		source:   "data/faq.csv",
+	}
+	rec, err = m.record(row)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = Record{ID: "faq.csv:3", Content: "Q?\n\nA.", Metadata: map[string]string{
+		"source": "data/faq.csv", "row": "3", "key": "k1", "question": "Q?", "answer": "A.", "team": "ops",
+	}}
+	if !reflect.DeepEqual(rec, want) {
+		t.Errorf("record = %+v, want %+v", rec, want)
+	}
+
+	for _, tt := range []struct {
+		m    importMapping
+		want string
+	}{
+		{importMapping{id: "nope", content: "answer"}, "missing id column"},
+		{importMapping{id: "empty", content: "answer"}, "empty id"},
+		{importMapping{content: "empty"}, "empty content"},
+	} {
+		if _, err := tt.m.record(row); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("record with %+v: error %v, want %q", tt.m, err, tt.want)
+		}
+	}
+}
+
+func TestImportCommand_ReportsFailedRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tickets.jsonl")
+	data := `{"id": "t1", "content": "Printer on fire"}` + "\n" + `{"id": "t2"}` + "\n" + "oops\n"
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rag := newTestRAG(t)
+	buf := captureOutput(t, formatJSON)
+	// Without a model the one valid row fails to embed.
+	if err := RunCommand(&ImportCommand{}, rag, []string{path}); err == nil || !strings.Contains(err.Error(), "failed to import 3 of 3 rows") {
+		t.Errorf("expected import failure, got %v", err)
+	}
+	var report ImportReport
+	if err := json.Unmarshal(buf.Bytes(), &report); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	var got []string
+	for _, f := range report.Failed {
+		got = append(got, f.ID+" "+f.Error)
+	}
+	if len(got) != 3 || !strings.HasPrefix(got[0], "t1 failed to generate embedding") ||
+		got[1] != "t2 empty content" || !strings.HasPrefix(got[2], " invalid JSON object") {
+		t.Errorf("failed = %q", got)
+	}
+
+	for _, args := range [][]string{nil, {"a.xml"}, {"--batch", "0", path}, {"--template", "{{", path}} {
+		if err := RunCommand(&ImportCommand{}, rag, args); err == nil {
+			t.Errorf("expected error for args %q", args)
+		}
+	}
+}
+
+func TestParseRecord(t *testing.T) {
+	rec, err := parseRecord([]byte(`{"id": " a ", "content": "text", "metadata": {"team": "ops", "tags": ["x"]}, "other": 1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Record{ID: "a", Content: "text", Metadata: map[string]string{"team": "ops", "tags": `["x"]`}}
+	if !reflect.DeepEqual(rec, want) {
+		t.Errorf("record = %+v, want %+v", rec, want)
+	}
+
+	for line, msg := range map[string]string{
+		`{"content": "text"}`:                          "empty id",
+		`{"id": "a", "content": " "}`:                  "empty content",
+		`{"id": "a", "content": "t", "metadata": [1]}`: "invalid metadata",
+		`{"id": "a", "content": "t", "metadata": "x"}`: "invalid metadata",
+		`["a", "text"]`:                                "invalid record",
+	} {
+		if _, err := parseRecord([]byte(line)); err == nil || !strings.Contains(err.Error(), msg) {
+			t.Errorf("parseRecord(%s): error %v, want %q", line, err, msg)
+		}
+	}
+}
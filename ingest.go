@@ -0,0 +1,247 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// IngestFile extracts the text of a file from its contents, splits it into
+// chunks, and stores them under id. The name, and the MIME type if it is not
+// empty, are used to detect the file format; the name is recorded as the
+// chunks' source, along with any document metadata the format provides, such
+// as an HTML page's title. It returns the chunk IDs.
+func (r *RAGSystem) IngestFile(ctx context.Context, id, name, mimeType string, data []byte) ([]string, error) {
+	sections, metadata, err := extractSections(name, mimeType, data)
+	if err != nil {
+		return nil, err
+	}
+
+	ingest := ingestConfig()
+	chunks := chunkSections(sections, ingest.ChunkSize, ingest.ChunkOverlap)
+	if len(chunks) == 0 {
+		return nil, fmt.Errorf("no text extracted from %s", name)
+	}
+
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	metadata["source"] = name
+	return r.AddChunks(ctx, id, chunks, metadata)
+}
+
+// AddText stores text under id as one document, or, when it is longer than
+// the configured chunk size, as chunks split with chunkText. The metadata is
+// attached to the document or to every chunk. It returns the stored IDs.
+func (r *RAGSystem) AddText(ctx context.Context, id, text string, metadata map[string]string) ([]string, error) {
+	ingest := ingestConfig()
+	if ingest.ChunkSize <= 0 || len(text) <= ingest.ChunkSize {
+		errs, err := r.AddRecords(ctx, []Record{{ID: id, Content: text, Metadata: metadata}})
+		if err != nil {
+			return nil, err
+		}
+		if errs[0] != nil {
+			return nil, errs[0]
+		}
+		return []string{id}, nil
+	}
+
+	var chunks []Chunk
+	for _, c := range chunkText(text, ingest.ChunkSize, ingest.ChunkOverlap) {
+		chunks = append(chunks, Chunk{Text: c})
+	}
+	return r.AddChunks(ctx, id, chunks, metadata)
+}
+
+// ingestConfig returns the ingestion settings of the loaded configuration, or
+// the defaults when none is loaded.
+func ingestConfig() IngestConfig {
+	if cfg == nil {
+		return DefaultConfig().Ingest
+	}
+	return cfg.Ingest
+}
+
+// section is a part of an extracted file, such as the text under a Markdown
+// heading. Its title is prefixed to each of its chunks so that they are
+// embedded in context, and its metadata is recorded on them.
+type section struct {
+	title    string
+	text     string
+	metadata map[string]string
+
+	// split chunks text; chunkText is used when it is nil.
+	split func(text string, size, overlap int) []string
+
+	// pageStarts holds the byte offset in text at which each page begins,
+	// for formats with pages; pageStarts[0] is page 1.
+	pageStarts []int
+
+	// firstLine is the line number of the first line of text in its file,
+	// for source code; 0 if lines are not counted.
+	firstLine int
+}
+
+// chunkSections splits each section into chunks of at most size bytes,
+// including a "<title>\n\n" prefix on chunks of titled sections. The prefix
+// takes from the size available for the text, leaving at least half of it.
+// Chunks of paged sections get "pages" metadata, such as "4" or "4-5", and
+// chunks of sections with counted lines get "lines" metadata, such as "12-40".
+func chunkSections(sections []section, size, overlap int) []Chunk {
+	var chunks []Chunk
+	for _, s := range sections {
+		prefix := ""
+		if s.title != "" {
+			prefix = s.title + "\n\n"
+		}
+		textSize := size
+		if size > 0 {
+			textSize = max(size-len(prefix), size/2)
+		}
+		split := s.split
+		if split == nil {
+			split = chunkText
+		}
+		from := 0
+		for _, text := range split(s.text, textSize, overlap) {
+			meta := s.metadata
+			if len(s.pageStarts) > 0 || s.firstLine > 0 {
+				if i := strings.Index(s.text[from:], text); i >= 0 {
+					start := from + i
+					meta = maps.Clone(meta)
+					if meta == nil {
+						meta = make(map[string]string)
+					}
+					if len(s.pageStarts) > 0 {
+						meta["pages"] = pageRange(s.pageStarts, start, start+len(text))
+					}
+					if s.firstLine > 0 {
+						first := s.firstLine + strings.Count(s.text[:start], "\n")
+						meta["lines"] = lineRange(first, first+strings.Count(text, "\n"))
+					}
+					from = start + 1
+				}
+			}
+			chunks = append(chunks, Chunk{Text: prefix + text, Metadata: meta})
+		}
+	}
+	return chunks
+}
+
+// pageRange returns the pages spanned by the text between the byte offsets
+// start and end, as "4" or "4-5", given the offset at which each page starts.
+func pageRange(pageStarts []int, start, end int) string {
+	page := func(off int) int {
+		return sort.Search(len(pageStarts), func(i int) bool { return pageStarts[i] > off })
+	}
+	first, last := page(start), page(max(end-1, start))
+	if first == last {
+		return strconv.Itoa(first)
+	}
+	return fmt.Sprintf("%d-%d", first, last)
+}
+
+// chunkText splits text into chunks of at most size bytes, preferring to break
+// at paragraph, line, sentence, and word boundaries. Consecutive chunks share
+// roughly overlap bytes of context. A non-positive size disables splitting.
+func chunkText(text string, size, overlap int) []string {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil
+	}
+	if size <= 0 || len(text) <= size {
+		return []string{text}
+	}
+	if overlap < 0 || overlap >= size/2 {
+		overlap = 0
+	}
+
+	var chunks []string
+	for len(text) > size {
+		cut := splitPoint(text, size)
+		if chunk := strings.TrimSpace(text[:cut]); chunk != "" {
+			chunks = append(chunks, chunk)
+		}
+
+		next := cut
+		if overlap > 0 {
+			next = cut - overlap
+			// Start the overlap on a word boundary.
+			if i := strings.IndexAny(text[next:cut], " \t\n"); i >= 0 {
+				next += i + 1
+			} else {
+				next = cut
+			}
+		}
+		text = strings.TrimSpace(text[next:])
+	}
+	if text != "" {
+		chunks = append(chunks, text)
+	}
+	return chunks
+}
+
+// splitPoint returns the index at which to cut text so that the first part is
+// at most size bytes. It looks for the last natural boundary in the second
+// half of the window and otherwise cuts on a rune boundary.
+func splitPoint(text string, size int) int {
+	window := text[:size]
+	for _, sep := range []string{"\n\n", "\n", ". ", " "} {
+		if i := strings.LastIndex(window, sep); i > size/2 {
+			return i + len(sep)
+		}
+	}
+	for size > 1 && !utf8.RuneStart(text[size]) {
+		size--
+	}
+	return size
+}
+
+// resolveAllowedPath returns the absolute, symlink-free form of path if it
+// names a regular file inside one of roots, and an error otherwise.
+func resolveAllowedPath(path string, roots []string) (string, error) {
+	if len(roots) == 0 {
+		return "", fmt.Errorf("file path ingestion is disabled (no allowed roots configured)")
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	resolved, err := filepath.EvalSymlinks(abs)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(resolved)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() {
+		return "", fmt.Errorf("%s is not a regular file", path)
+	}
+
+	for _, root := range roots {
+		rootAbs, err := filepath.Abs(root)
+		if err != nil {
+			continue
+		}
+		if r, err := filepath.EvalSymlinks(rootAbs); err == nil {
+			rootAbs = r
+		}
+		rel, err := filepath.Rel(rootAbs, resolved)
+		if err != nil {
+			continue
+		}
+		if rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return resolved, nil
+		}
+	}
+	return "", fmt.Errorf("%s is outside the allowed roots", path)
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestChunkText_Short(t *testing.T) {
+	chunks := chunkText("  hello world  ", 100, 10)
+	if len(chunks) != 1 || chunks[0] != "hello world" {
+		t.Fatalf("expected single trimmed chunk, got %q", chunks)
+	}
+}
+
+func TestChunkText_Empty(t *testing.T) {
+	if chunks := chunkText(" \n\t ", 100, 10); len(chunks) != 0 {
+		t.Fatalf("expected no chunks, got %q", chunks)
+	}
+}
+
+func TestChunkText_RespectsSize(t *testing.T) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 50)
+	chunks := chunkText(text, 200, 0)
+	if len(chunks) < 2 {
+		t.Fatalf("expected multiple chunks, got %d", len(chunks))
+	}
+	for i, c := range chunks {
+		if len(c) > 200 {
+			t.Errorf("chunk %d has length %d, want <= 200", i, len(c))
+		}
+		if !strings.HasSuffix(c, ".") && i < len(chunks)-1 {
+			t.Errorf("chunk %d does not end on a sentence boundary: %q", i, c)
+		}
+	}
+}
+
+func TestChunkText_ParagraphBoundary(t *testing.T) {
+	para1 := strings.Repeat("a", 60)
+	para2 := strings.Repeat("b", 60)
+	chunks := chunkText(para1+"\n\n"+para2, 100, 0)
+	if len(chunks) != 2 || chunks[0] != para1 || chunks[1] != para2 {
+		t.Fatalf("expected split at paragraph, got %q", chunks)
+	}
+}
+
+func TestChunkText_Overlap(t *testing.T) {
+	words := make([]string, 100)
+	for i := range words {
+		words[i] = "word"
+	}
+	chunks := chunkText(strings.Join(words, " "), 50, 10)
+	if len(chunks) < 2 {
+		t.Fatalf("expected multiple chunks, got %d", len(chunks))
+	}
+	for i := 1; i < len(chunks); i++ {
+		prev := chunks[i-1]
+		if !strings.HasPrefix(chunks[i], prev[len(prev)-4:]) {
+			t.Errorf("chunk %d does not overlap previous chunk: %q / %q", i, prev, chunks[i])
+		}
+	}
+}
+
+func TestChunkText_NoBoundaries(t *testing.T) {
+	text := strings.Repeat("é", 100) // 200 bytes, no spaces
+	chunks := chunkText(text, 51, 0)
+	if strings.Join(chunks, "") != text {
+		t.Fatal("expected chunks to reassemble original text")
+	}
+	for i, c := range chunks {
+		if !strings.HasPrefix(c, "é") {
+			t.Errorf("chunk %d split a rune: %q", i, c)
+		}
+	}
+}
+
+func TestChunkText_NoSplit(t *testing.T) {
+	text := strings.Repeat("x ", 500)
+	if chunks := chunkText(text, 0, 0); len(chunks) != 1 {
+		t.Fatalf("expected 1 chunk when size is 0, got %d", len(chunks))
+	}
+}
+
+func TestExtractSections_PlainText(t *testing.T) {
+	sections, _, err := extractSections("notes.txt", "", []byte("some notes"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(sections) != 1 || sections[0].text != "some notes" {
+		t.Errorf("expected %q, got %+v", "some notes", sections)
+	}
+}
+
+func TestExtractSections_Binary(t *testing.T) {
+	_, _, err := extractSections("image.bin", "", []byte{0xff, 0xfe, 0x00, 0x81})
+	if err == nil {
+		t.Fatal("expected error for binary data")
+	}
+	if !strings.Contains(err.Error(), "unsupported") {
+		t.Errorf("expected unsupported format error, got: %v", err)
+	}
+}
+
+func TestExtractSections_InvalidPDF(t *testing.T) {
+	if _, _, err := extractSections("broken.pdf", "", []byte("not a pdf")); err == nil {
+		t.Fatal("expected error for invalid PDF")
+	}
+}
+
+func TestResolveAllowedPath(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+
+	inside := filepath.Join(root, "sub", "doc.txt")
+	if err := os.MkdirAll(filepath.Dir(inside), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(inside, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	secret := filepath.Join(outside, "secret.txt")
+	if err := os.WriteFile(secret, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	link := filepath.Join(root, "link.txt")
+	if err := os.Symlink(secret, link); err != nil {
+		t.Fatal(err)
+	}
+
+	roots := []string{root}
+
+	if _, err := resolveAllowedPath(inside, roots); err != nil {
+		t.Errorf("expected file inside root to be allowed, got: %v", err)
+	}
+	if _, err := resolveAllowedPath(secret, roots); err == nil {
+		t.Error("expected file outside root to be rejected")
+	}
+	if _, err := resolveAllowedPath(filepath.Join(root, "..", filepath.Base(outside), "secret.txt"), roots); err == nil {
+		t.Error("expected traversal outside root to be rejected")
+	}
+	if _, err := resolveAllowedPath(link, roots); err == nil {
+		t.Error("expected symlink escaping root to be rejected")
+	}
+	if _, err := resolveAllowedPath(filepath.Join(root, "sub"), roots); err == nil {
+		t.Error("expected directory to be rejected")
+	}
+	if _, err := resolveAllowedPath(inside, nil); err == nil {
+		t.Error("expected rejection when no roots are configured")
+	}
+}
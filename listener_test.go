@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCert is a generated certificate together with its PEM files.
+type testCert struct {
+	cert     *x509.Certificate
+	key      *ecdsa.PrivateKey
+	certFile string
+	keyFile  string
+}
+
+// newTestCert creates a certificate for name signed by parent, or self-signed
+// when parent is nil, and writes it to PEM files in dir.
+func newTestCert(t *testing.T, dir, name string, isCA bool, parent *testCert) *testCert {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:     []string{"localhost"},
+		IsCA:         isCA,
+
+		BasicConstraintsValid: true,
+	}
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tc := &testCert{
+		cert:     cert,
+		key:      key,
+		certFile: filepath.Join(dir, name+".crt"),
+		keyFile:  filepath.Join(dir, name+".key"),
+	}
+	if err := os.WriteFile(tc.certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(tc.keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return tc
+}
+
+// serveTest serves a handler answering "ok" on addr and returns the listener address.
+func serveTest(t *testing.T, addr string, tlsConfig *tls.Config) net.Addr {
+	t.Helper()
+	ln, err := listen(addr, tlsConfig)
+	if err != nil {
+		t.Fatalf("listen failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		serveHTTP(ctx, ln, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok"))
+		}))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+	return ln.Addr()
+}
+
+func TestListenAddr(t *testing.T) {
+	tests := []struct {
+		sc   ServerConfig
+		want string
+	}{
+		{ServerConfig{Port: "8080"}, ":8080"},
+		{ServerConfig{Host: "127.0.0.1", Port: "9000"}, "127.0.0.1:9000"},
+		{ServerConfig{Host: "::1", Port: "9000"}, "[::1]:9000"},
+		{ServerConfig{Host: "127.0.0.1", Port: "9000", Socket: "/run/ydrag.sock"}, "unix:/run/ydrag.sock"},
+	}
+	for _, tt := range tests {
+		if got := listenAddr(tt.sc); got != tt.want {
+			t.Errorf("listenAddr(%+v) = %q, want %q", tt.sc, got, tt.want)
+		}
+	}
+}
+
+func TestBuildTLSConfig_Disabled(t *testing.T) {
+	config, err := buildTLSConfig(TLSConfig{})
+	if err != nil || config != nil {
+		t.Fatalf("expected nil config and no error, got %v, %v", config, err)
+	}
+}
+
+func TestBuildTLSConfig_Incomplete(t *testing.T) {
+	if _, err := buildTLSConfig(TLSConfig{CertFile: "server.crt"}); err == nil {
+		t.Error("expected error when key_file is missing")
+	}
+	if _, err := buildTLSConfig(TLSConfig{ClientCAFile: "ca.crt"}); err == nil {
+		t.Error("expected error when client CA is set without a certificate")
+	}
+}
+
+func TestServeTLS(t *testing.T) {
+	dir := t.TempDir()
+	server := newTestCert(t, dir, "server", true, nil)
+
+	tlsConfig, err := buildTLSConfig(TLSConfig{CertFile: server.certFile, KeyFile: server.keyFile})
+	if err != nil {
+		t.Fatalf("buildTLSConfig failed: %v", err)
+	}
+	addr := serveTest(t, "127.0.0.1:0", tlsConfig)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.cert)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+	resp, err := client.Get("https://" + addr.String())
+	if err != nil {
+		t.Fatalf("HTTPS request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+
+	if resp, err := http.Get("http://" + addr.String()); err == nil && resp.StatusCode == http.StatusOK {
+		resp.Body.Close()
+		t.Error("expected plain HTTP request to a TLS listener to fail")
+	}
+}
+
+func TestServeMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCert(t, dir, "ca", true, nil)
+	server := newTestCert(t, dir, "server", false, ca)
+	clientCert := newTestCert(t, dir, "client", false, ca)
+
+	tlsConfig, err := buildTLSConfig(TLSConfig{
+		CertFile:     server.certFile,
+		KeyFile:      server.keyFile,
+		ClientCAFile: ca.certFile,
+	})
+	if err != nil {
+		t.Fatalf("buildTLSConfig failed: %v", err)
+	}
+	addr := serveTest(t, "127.0.0.1:0", tlsConfig)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	anonymous := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	if resp, err := anonymous.Get("https://" + addr.String()); err == nil {
+		resp.Body.Close()
+		t.Error("expected request without a client certificate to fail")
+	}
+
+	pair, err := tls.LoadX509KeyPair(clientCert.certFile, clientCert.keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticated := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{pair},
+	}}}
+	resp, err := authenticated.Get("https://" + addr.String())
+	if err != nil {
+		t.Fatalf("request with client certificate failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
+
+func TestServeUnixSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ydrag.sock")
+
+	// A stale socket from a previous run must not prevent listening.
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	serveTest(t, unixPrefix+path, nil)
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://ydrag/")
+	if err != nil {
+		t.Fatalf("request over Unix socket failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/ebitengine/purego"
+	"github.com/hybridgroup/yzma/pkg/llama"
+	"github.com/hybridgroup/yzma/pkg/utils"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// newLogger builds a logger from c, writing to c.File or to stderr when no
+// file is set. Verbose lowers the level to debug regardless of c.Level. The
+// returned close function releases the log file, if any.
+func newLogger(c LogConfig, verbose bool) (*slog.Logger, func() error, error) {
+	level, err := parseLogLevel(c.Level)
+	if err != nil {
+		return nil, nil, err
+	}
+	if verbose {
+		level = slog.LevelDebug
+	}
+
+	var w io.Writer = os.Stderr
+	closeFn := func() error { return nil }
+	if c.File != "" {
+		f, err := os.OpenFile(c.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open log file: %w", err)
+		}
+		w, closeFn = f, f.Close
+	}
+
+	opts := &slog.HandlerOptions{Level: level}
+	var handler slog.Handler
+	switch strings.ToLower(c.Format) {
+	case "", "text":
+		handler = slog.NewTextHandler(w, opts)
+	case "json":
+		handler = slog.NewJSONHandler(w, opts)
+	default:
+		closeFn()
+		return nil, nil, fmt.Errorf("unknown log format %q (use text or json)", c.Format)
+	}
+	return slog.New(handler), closeFn, nil
+}
+
+// parseLogLevel converts a level name (debug, info, warn, error) to a
+// slog.Level. An empty name means info.
+func parseLogLevel(s string) (slog.Level, error) {
+	var level slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := level.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q (use debug, info, warn, or error)", s)
+	}
+	return level, nil
+}
+
+// llamaLog receives llama.cpp log output and forwards complete lines to the
+// default slog logger. llama.cpp emits lines in fragments, continuing the
+// previous line with LogLevelContinue, so text is buffered until a newline.
+var llamaLog struct {
+	once     sync.Once
+	callback uintptr
+
+	mu    sync.Mutex
+	level llama.LogLevel
+	buf   strings.Builder
+}
+
+// routeLlamaLogs directs llama.cpp logging through slog. It must be called
+// after the llama library is loaded.
+func routeLlamaLogs() {
+	llamaLog.once.Do(func() {
+		llamaLog.callback = purego.NewCallback(func(level int32, text *byte, data unsafe.Pointer) uintptr {
+			writeLlamaLog(llama.LogLevel(level), utils.BytePtrToString(text))
+			return 0
+		})
+	})
+	llama.LogSet(llamaLog.callback)
+}
+
+// writeLlamaLog buffers a llama.cpp log fragment and logs each completed line.
+func writeLlamaLog(level llama.LogLevel, text string) {
+	llamaLog.mu.Lock()
+	defer llamaLog.mu.Unlock()
+
+	if level != llama.LogLevelContinue {
+		llamaLog.level = level
+	}
+	llamaLog.buf.WriteString(text)
+
+	for {
+		s := llamaLog.buf.String()
+		i := strings.IndexByte(s, '\n')
+		if i < 0 {
+			return
+		}
+		if line := strings.TrimSpace(s[:i]); line != "" {
+			slog.Log(context.Background(), llamaSlogLevel(llamaLog.level), line, "component", "llama")
+		}
+		llamaLog.buf.Reset()
+		llamaLog.buf.WriteString(s[i+1:])
+	}
+}
+
+// llamaSlogLevel maps a llama.cpp log level to a slog level. llama.cpp logs
+// model loading details at info, which are demoted to debug to keep the
+// default output quiet.
+func llamaSlogLevel(level llama.LogLevel) slog.Level {
+	switch level {
+	case llama.LogLevelError:
+		return slog.LevelError
+	case llama.LogLevelWarn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelDebug
+	}
+}
+
+// logTools is MCP server middleware that logs every request with its method,
+// session ID, and duration. Tool calls, which also carry the tool name, are
+// logged at info and other requests at debug; failures are logged as warnings.
+func logTools(logger *slog.Logger) mcp.Middleware {
+	return func(next mcp.MethodHandler) mcp.MethodHandler {
+		return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+			start := time.Now()
+			result, err := next(ctx, method, req)
+
+			attrs := []any{"method", method, "duration", time.Since(start)}
+			if ss, ok := req.GetSession().(*mcp.ServerSession); ok && ss != nil {
+				attrs = append(attrs, "session", ss.ID())
+			}
+			params, isTool := req.GetParams().(*mcp.CallToolParamsRaw)
+			if isTool {
+				attrs = append(attrs, "tool", params.Name)
+			}
+
+			switch res, ok := result.(*mcp.CallToolResult); {
+			case err != nil:
+				logger.WarnContext(ctx, "MCP request failed", append(attrs, "error", err)...)
+			case ok && res.IsError:
+				logger.WarnContext(ctx, "MCP tool returned an error", attrs...)
+			case isTool:
+				logger.InfoContext(ctx, "MCP tool call", attrs...)
+			default:
+				logger.DebugContext(ctx, "MCP request", attrs...)
+			}
+			return result, err
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/hybridgroup/yzma/pkg/llama"
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// captureDefaultLogger replaces the default slog logger with one writing JSON
+// at debug level to the returned buffer for the duration of the test.
+func captureDefaultLogger(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return &buf
+}
+
+// logRecords decodes JSON log output into one map per record.
+func logRecords(t *testing.T, data []byte) []map[string]any {
+	t.Helper()
+	var records []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		if line == "" {
+			continue
+		}
+		var rec map[string]any
+		if err := json.Unmarshal([]byte(line), &rec); err != nil {
+			t.Fatalf("invalid log line %q: %v", line, err)
+		}
+		records = append(records, rec)
+	}
+	return records
+}
+
+func TestParseLogLevel(t *testing.T) {
+	tests := []struct {
+		in   string
+		want slog.Level
+	}{
+		{"", slog.LevelInfo},
+		{"debug", slog.LevelDebug},
+		{"INFO", slog.LevelInfo},
+		{"warn", slog.LevelWarn},
+		{"error", slog.LevelError},
+	}
+	for _, tt := range tests {
+		got, err := parseLogLevel(tt.in)
+		if err != nil {
+			t.Errorf("parseLogLevel(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseLogLevel(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	if _, err := parseLogLevel("loud"); err == nil {
+		t.Error("expected error for unknown level")
+	}
+}
+
+func TestNewLogger_JSONFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ydrag.log")
+	logger, closeLog, err := newLogger(LogConfig{Level: "warn", Format: "json", File: path}, false)
+	if err != nil {
+		t.Fatalf("newLogger failed: %v", err)
+	}
+	logger.Info("hidden")
+	logger.Warn("shown", "key", "value")
+	if err := closeLog(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read log file: %v", err)
+	}
+	records := logRecords(t, data)
+	if len(records) != 1 {
+		t.Fatalf("got %d records, want 1: %s", len(records), data)
+	}
+	if records[0]["msg"] != "shown" || records[0]["key"] != "value" {
+		t.Errorf("unexpected record: %v", records[0])
+	}
+}
+
+func TestNewLogger_VerboseEnablesDebug(t *testing.T) {
+	logger, closeLog, err := newLogger(LogConfig{Level: "error"}, true)
+	if err != nil {
+		t.Fatalf("newLogger failed: %v", err)
+	}
+	defer closeLog()
+	if !logger.Enabled(context.Background(), slog.LevelDebug) {
+		t.Error("verbose logger should enable debug level")
+	}
+}
+
+func TestNewLogger_Invalid(t *testing.T) {
+	if _, _, err := newLogger(LogConfig{Level: "loud"}, false); err == nil {
+		t.Error("expected error for unknown level")
+	}
+	if _, _, err := newLogger(LogConfig{Format: "xml"}, false); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	if _, _, err := newLogger(LogConfig{File: filepath.Join(t.TempDir(), "missing", "ydrag.log")}, false); err == nil {
+		t.Error("expected error for unwritable log file")
+	}
+}
+
+func TestWriteLlamaLog(t *testing.T) {
+	buf := captureDefaultLogger(t)
+
+	writeLlamaLog(llama.LogLevelInfo, "loading model")
+	writeLlamaLog(llama.LogLevelContinue, "... done\n")
+	writeLlamaLog(llama.LogLevelError, "first\nsecond\n")
+	writeLlamaLog(llama.LogLevelWarn, "\n")
+
+	records := logRecords(t, buf.Bytes())
+	want := []struct{ level, msg string }{
+		{"DEBUG", "loading model... done"},
+		{"ERROR", "first"},
+		{"ERROR", "second"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d: %s", len(records), len(want), buf)
+	}
+	for i, w := range want {
+		if records[i]["level"] != w.level || records[i]["msg"] != w.msg || records[i]["component"] != "llama" {
+			t.Errorf("record %d = %v, want level %s msg %q", i, records[i], w.level, w.msg)
+		}
+	}
+}
+
+func TestLogTools(t *testing.T) {
+	var buf bytes.Buffer
+	rag := newTestRAG(t)
+	m := NewMCPServer(rag)
+	m.logger = slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelInfo}))
+	server := m.newServer()
+	m.registerTools(server, scopeRead)
+	session := connectInMemory(t, server)
+	ctx := context.Background()
+
+	if _, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "list_documents"}); err != nil {
+		t.Fatalf("list_documents failed: %v", err)
+	}
+
+	var toolRecord map[string]any
+	for _, rec := range logRecords(t, buf.Bytes()) {
+		if rec["msg"] == "MCP tool call" {
+			toolRecord = rec
+		}
+		if rec["level"] == "DEBUG" {
+			t.Errorf("debug record logged at info level: %v", rec)
+		}
+	}
+	if toolRecord == nil {
+		t.Fatalf("no tool call record in %s", buf.String())
+	}
+	if toolRecord["tool"] != "list_documents" || toolRecord["method"] != "tools/call" {
+		t.Errorf("unexpected tool record: %v", toolRecord)
+	}
+	if _, ok := toolRecord["duration"]; !ok {
+		t.Errorf("tool record has no duration: %v", toolRecord)
+	}
+}
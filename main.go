@@ -1,21 +1,26 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"log/slog"
 	"os"
 )
 
-// configPath, modelFile, libPath, dbPath, contextSize, batchSize, and verbose
-// are command-line flags that override values loaded from the configuration file.
+// configPath, modelFile, libPath, dbPath, collection, contextSize, batchSize,
+// and verbose are command-line flags that override values loaded from the configuration file.
+// format selects how commands print their results.
 var (
 	configPath  = flag.String("config", "config.yaml", "path to configuration file")
 	modelFile   = flag.String("model", "", "path to embedding model file (GGUF format)")
 	libPath     = flag.String("lib", "", "path to llama.cpp library")
 	dbPath      = flag.String("db", "", "path to DuckDB database file (use :memory: for in-memory)")
+	collection  = flag.String("collection", "", "collection of documents to use (default \"default\")")
 	contextSize = flag.Int("context", 0, "context size for embeddings")
 	batchSize   = flag.Int("batch", 0, "batch size for processing")
-	verbose     = flag.Bool("verbose", false, "enable verbose logging")
+	verbose     = flag.Bool("verbose", false, "enable verbose (debug-level) logging")
+	format      = flag.String("format", formatText, "output format: text, json, jsonl, csv, or table")
 )
 
 // cfg holds the active configuration used throughout the application.
@@ -23,7 +28,8 @@ var cfg *Config
 
 // main is the entry point for the ydrag CLI. It parses flags, loads
 // configuration, initialises the RAG system, and dispatches the requested
-// sub-command.
+// sub-command. The embedding model is required only by commands that embed
+// text, and is loaded when first used.
 func main() {
 	flag.Parse()
 
@@ -36,12 +42,29 @@ func main() {
 
 	applyFlagOverrides(cfg)
 
-	if cfg.Model == "" {
-		showUsage()
+	logger, closeLog, err := newLogger(cfg.Log, cfg.Verbose)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error configuring logging: %v\n", err)
+		os.Exit(1)
+	}
+	defer closeLog()
+	slog.SetDefault(logger)
+
+	if err := SetOutputFormat(*format); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
 
 	args := flag.Args()
+	if len(args) > 0 && args[0] == "help" {
+		cmd, _ := GetCommand("help")
+		if err := RunCommand(cmd, nil, args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	if len(args) == 0 {
 		fmt.Println("Please specify a command")
 		PrintCommandsHelp()
@@ -55,14 +78,31 @@ func main() {
 		os.Exit(1)
 	}
 
+	if cmd.NeedsModel() && cfg.Model == "" {
+		showUsage()
+		os.Exit(1)
+	}
+
+	shutdownTrace, err := setupTracing(context.Background(), cfg.Tracing)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error configuring tracing: %v\n", err)
+		os.Exit(1)
+	}
+	defer func() {
+		if err := shutdownTracing(shutdownTrace); err != nil {
+			slog.Warn("failed to flush traces", "error", err)
+		}
+	}()
+
 	rag, err := NewRAGSystem(cfg.Model, cfg.LibPath, cfg.DBPath)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error initializing RAG system: %v\n", err)
 		os.Exit(1)
 	}
 	defer rag.Close()
+	rag.SetCollection(cfg.Collection)
 
-	if err := cmd.Run(rag, args[1:]); err != nil {
+	if err := RunCommand(cmd, rag, args[1:]); err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
@@ -80,6 +120,9 @@ func applyFlagOverrides(cfg *Config) {
 	if *dbPath != "" {
 		cfg.DBPath = *dbPath
 	}
+	if *collection != "" {
+		cfg.Collection = *collection
+	}
 	if *contextSize != 0 {
 		cfg.ContextSize = *contextSize
 	}
@@ -94,10 +137,11 @@ func applyFlagOverrides(cfg *Config) {
 // showUsage prints the full CLI usage message, including available commands,
 // flag descriptions, configuration priority, and example invocations.
 func showUsage() {
-	fmt.Println("Usage: ydrag [options] <command> [args]")
+	fmt.Println("Usage: ydrag [options] <command> [flags] [args]")
 	PrintCommandsHelp()
 	fmt.Println("\nOptions:")
 	flag.PrintDefaults()
+	fmt.Println("\nRun 'ydrag help <command>' for the flags of a command.")
 	fmt.Println("\nConfiguration priority: flags > env vars > config.yaml > defaults")
 	fmt.Println("\nExample:")
 	fmt.Println("  ydrag -model ./models/nomic-embed-text-v1.5.Q8_0.gguf add doc1 \"The capital of France is Paris\"")
@@ -0,0 +1,222 @@
+package main
+
+import "strings"
+
+// splitMarkdown splits Markdown text into one section per ATX heading ("#"
+// to "######"), with the text before the first heading as an untitled
+// section. Each section's title is its heading path, such as
+// "Install > Linux", which is also recorded as its "section" metadata.
+// Headings inside fenced code blocks are ignored, and YAML front matter is
+// dropped. Sections are chunked with chunkMarkdown.
+func splitMarkdown(text string) []section {
+	text = stripFrontMatter(text)
+
+	var sections []section
+	var headings []string // heading text by level, "" for skipped levels
+	var body strings.Builder
+	flush := func() {
+		path := headingPath(headings)
+		if strings.TrimSpace(body.String()) == "" {
+			body.Reset()
+			return
+		}
+		s := section{title: path, text: body.String(), split: chunkMarkdown}
+		if path != "" {
+			s.metadata = map[string]string{"section": path}
+		}
+		sections = append(sections, s)
+		body.Reset()
+	}
+
+	fence := ""
+	for _, line := range strings.SplitAfter(text, "\n") {
+		if fence != "" {
+			if isFenceClose(line, fence) {
+				fence = ""
+			}
+			body.WriteString(line)
+			continue
+		}
+		if f := fenceOpen(line); f != "" {
+			fence = f
+			body.WriteString(line)
+			continue
+		}
+		if level, title, ok := atxHeading(line); ok {
+			flush()
+			for len(headings) < level {
+				headings = append(headings, "")
+			}
+			headings = append(headings[:level-1], title)
+			continue
+		}
+		body.WriteString(line)
+	}
+	flush()
+	return sections
+}
+
+// headingPath joins the non-empty headings with " > ".
+func headingPath(headings []string) string {
+	var parts []string
+	for _, h := range headings {
+		if h != "" {
+			parts = append(parts, h)
+		}
+	}
+	return strings.Join(parts, " > ")
+}
+
+// stripFrontMatter removes a leading YAML front matter block delimited by
+// "---" lines.
+func stripFrontMatter(text string) string {
+	lines := strings.SplitAfter(text, "\n")
+	if strings.TrimRight(lines[0], "\r\n") != "---" {
+		return text
+	}
+	off := len(lines[0])
+	for _, line := range lines[1:] {
+		off += len(line)
+		if strings.TrimRight(line, "\r\n") == "---" {
+			return text[off:]
+		}
+	}
+	return text
+}
+
+// atxHeading parses an ATX heading line such as "## Install ##", returning
+// its level and text.
+func atxHeading(line string) (int, string, bool) {
+	line = strings.TrimRight(line, "\r\n")
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return 0, "", false
+	}
+	level := 0
+	for level < len(trimmed) && trimmed[level] == '#' {
+		level++
+	}
+	if level == 0 || level > 6 {
+		return 0, "", false
+	}
+	rest := trimmed[level:]
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return 0, "", false
+	}
+	rest = strings.TrimSpace(rest)
+	if closed := strings.TrimRight(rest, "#"); closed == "" || strings.HasSuffix(closed, " ") {
+		rest = strings.TrimSpace(closed)
+	}
+	if rest == "" {
+		return 0, "", false
+	}
+	return level, rest, true
+}
+
+// fenceOpen returns the fence marker ("```" or "~~~", possibly longer) that
+// opens a fenced code block on line, or "" if line does not open one.
+func fenceOpen(line string) string {
+	trimmed := strings.TrimLeft(line, " ")
+	if len(line)-len(trimmed) > 3 {
+		return ""
+	}
+	for _, c := range []byte{'`', '~'} {
+		n := 0
+		for n < len(trimmed) && trimmed[n] == c {
+			n++
+		}
+		if n >= 3 {
+			if c == '`' && strings.Contains(trimmed[n:], "`") {
+				return ""
+			}
+			return trimmed[:n]
+		}
+	}
+	return ""
+}
+
+// isFenceClose reports whether line closes a code block opened with fence.
+func isFenceClose(line, fence string) bool {
+	trimmed := strings.TrimSpace(line)
+	return strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == ""
+}
+
+// chunkMarkdown splits Markdown text into chunks of at most size bytes made
+// of whole blocks: paragraphs separated by blank lines and fenced code
+// blocks. A code block is never split, even if it is longer than size;
+// longer paragraphs are split with chunkText.
+func chunkMarkdown(text string, size, overlap int) []string {
+	if size <= 0 {
+		return chunkText(text, size, overlap)
+	}
+
+	var chunks []string
+	var cur strings.Builder
+	flush := func() {
+		if c := strings.TrimSpace(cur.String()); c != "" {
+			chunks = append(chunks, c)
+		}
+		cur.Reset()
+	}
+	for _, b := range markdownBlocks(text) {
+		if cur.Len() > 0 && cur.Len()+2+len(b.text) <= size {
+			cur.WriteString("\n\n")
+			cur.WriteString(b.text)
+			continue
+		}
+		flush()
+		if len(b.text) > size && !b.code {
+			chunks = append(chunks, chunkText(b.text, size, overlap)...)
+			continue
+		}
+		cur.WriteString(b.text)
+	}
+	flush()
+	return chunks
+}
+
+// markdownBlock is a paragraph or a fenced code block.
+type markdownBlock struct {
+	text string
+	code bool
+}
+
+// markdownBlocks splits text at blank lines outside fenced code blocks,
+// returning each code block as a single block.
+func markdownBlocks(text string) []markdownBlock {
+	var blocks []markdownBlock
+	var cur []string
+	code := false
+	flush := func() {
+		if b := strings.TrimSpace(strings.Join(cur, "\n")); b != "" {
+			blocks = append(blocks, markdownBlock{text: b, code: code})
+		}
+		cur, code = nil, false
+	}
+
+	fence := ""
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if fence != "" {
+			cur = append(cur, line)
+			if isFenceClose(line, fence) {
+				fence = ""
+				flush()
+			}
+			continue
+		}
+		if f := fenceOpen(line); f != "" {
+			flush()
+			fence, code = f, true
+			cur = append(cur, line)
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			flush()
+			continue
+		}
+		cur = append(cur, line)
+	}
+	flush()
+	return blocks
+}
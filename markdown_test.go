@@ -0,0 +1,133 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testMarkdown = `---
+title: Guide
+---
+Intro text.
+
+# Install
+
+Download it.
+
+## Linux
+
+Run this:
+
+` + "```sh\n# not a heading\n\nmake install\n```" + `
+
+### Notes ###
+
+Check permissions.
+
+## macOS
+
+Use brew.
+`
+
+func TestSplitMarkdown(t *testing.T) {
+	sections := splitMarkdown(testMarkdown)
+
+	var titles []string
+	for _, s := range sections {
+		titles = append(titles, s.title)
+		if s.title != "" && s.metadata["section"] != s.title {
+			t.Errorf("section %q has metadata %v", s.title, s.metadata)
+		}
+	}
+	want := []string{"", "Install", "Install > Linux", "Install > Linux > Notes", "Install > macOS"}
+	if !reflect.DeepEqual(titles, want) {
+		t.Fatalf("titles = %q, want %q", titles, want)
+	}
+	if strings.Contains(sections[0].text, "title: Guide") {
+		t.Errorf("front matter not stripped: %q", sections[0].text)
+	}
+	if !strings.Contains(sections[2].text, "# not a heading") {
+		t.Errorf("heading inside code block was split out: %q", sections[2].text)
+	}
+}
+
+func TestSplitMarkdown_SkippedLevel(t *testing.T) {
+	sections := splitMarkdown("# A\n\n### C\n\ntext\n\n## B\n\nmore\n")
+	if len(sections) != 2 || sections[0].title != "A > C" || sections[1].title != "A > B" {
+		t.Errorf("sections = %+v", sections)
+	}
+}
+
+func TestAtxHeading(t *testing.T) {
+	tests := []struct {
+		line  string
+		level int
+		title string
+		ok    bool
+	}{
+		{"# Title\n", 1, "Title", true},
+		{"### Closed ###", 3, "Closed", true},
+		{"## C# tips", 2, "C# tips", true},
+		{"#hashtag", 0, "", false},
+		{"####### seven", 0, "", false},
+		{"    # indented code", 0, "", false},
+		{"#", 0, "", false},
+	}
+	for _, tt := range tests {
+		level, title, ok := atxHeading(tt.line)
+		if level != tt.level || title != tt.title || ok != tt.ok {
+			t.Errorf("atxHeading(%q) = %d, %q, %v", tt.line, level, title, ok)
+		}
+	}
+}
+
+func TestChunkMarkdown_KeepsCodeBlocks(t *testing.T) {
+	code := "```go\n" + strings.Repeat("fmt.Println(\"x\")\n\n", 10) + "```"
+	text := "Short intro.\n\n" + code + "\n\nAfter the code."
+
+	chunks := chunkMarkdown(text, 60, 0)
+	found := false
+	for _, c := range chunks {
+		if strings.Contains(c, "```") {
+			if c != code {
+				t.Errorf("code block split or merged: %q", c)
+			}
+			found = true
+		}
+	}
+	if !found || chunks[0] != "Short intro." || chunks[len(chunks)-1] != "After the code." {
+		t.Errorf("chunks = %q", chunks)
+	}
+}
+
+func TestChunkMarkdown_PacksParagraphs(t *testing.T) {
+	chunks := chunkMarkdown("one\n\ntwo\n\n"+strings.Repeat("x", 30), 20, 0)
+	want := []string{"one\n\ntwo", strings.Repeat("x", 20), strings.Repeat("x", 10)}
+	if !reflect.DeepEqual(chunks, want) {
+		t.Errorf("chunks = %q, want %q", chunks, want)
+	}
+}
+
+func TestChunkSections_PrefixesTitle(t *testing.T) {
+	sections, _, err := extractSections("guide.md", "", []byte(testMarkdown))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := chunkSections(sections, 1000, 0)
+	if len(chunks) != 5 {
+		t.Fatalf("expected 5 chunks, got %d", len(chunks))
+	}
+	if chunks[0].Text != "Intro text." || chunks[0].Metadata != nil {
+		t.Errorf("untitled chunk = %+v", chunks[0])
+	}
+	if !strings.HasPrefix(chunks[2].Text, "Install > Linux\n\nRun this:") || chunks[2].Metadata["section"] != "Install > Linux" {
+		t.Errorf("titled chunk = %+v", chunks[2])
+	}
+
+	for _, c := range chunkSections(sections, 60, 0) {
+		if len(c.Text) > 60 {
+			t.Errorf("chunk longer than 60 bytes: %q", c.Text)
+		}
+	}
+}
@@ -2,10 +2,16 @@ package main
 
 import (
 	"context"
+	"encoding/base64"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 
+	"github.com/modelcontextprotocol/go-sdk/auth"
 	"github.com/modelcontextprotocol/go-sdk/mcp"
 )
 
@@ -23,15 +29,17 @@ type AddDocumentResult struct {
 
 // QueryDocumentsArgs contains the parameters for querying documents by vector similarity.
 type QueryDocumentsArgs struct {
-	Query string `json:"query" jsonschema:"required,Search query text"`
-	TopK  int    `json:"top_k" jsonschema:"Maximum number of results to return (default: 5)"`
+	Query  string            `json:"query" jsonschema:"required,Search query text"`
+	TopK   int               `json:"top_k" jsonschema:"Maximum number of results to return (default: 5)"`
+	Filter map[string]string `json:"filter,omitempty" jsonschema:"Only return documents whose metadata has each of these keys set to the given value; for example from or thread for email"`
 }
 
 // QueryResult represents a single document match from a similarity search.
 type QueryResult struct {
-	ID      string  `json:"id"`
-	Content string  `json:"content"`
-	Score   float64 `json:"score"`
+	ID       string  `json:"id"`
+	Content  string  `json:"content"`
+	Score    float64 `json:"score"`
+	Citation string  `json:"citation,omitempty"` // source file and pages, e.g. "spec.pdf p.42"
 }
 
 // QueryDocumentsResult is the response returned from a document query, containing matched results.
@@ -40,6 +48,12 @@ type QueryDocumentsResult struct {
 	Count   int           `json:"count"`
 }
 
+// FindSimilarArgs contains the parameters for finding documents similar to an existing one.
+type FindSimilarArgs struct {
+	ID   string `json:"id" jsonschema:"required,ID of the document or chunk to find similar documents for"`
+	TopK int    `json:"top_k" jsonschema:"Maximum number of results to return (default: 5)"`
+}
+
 // ListDocumentsArgs contains the parameters for listing documents (currently empty).
 type ListDocumentsArgs struct{}
 
@@ -55,6 +69,11 @@ type ListDocumentsResult struct {
 	Total     int            `json:"total"`
 }
 
+// KBStatsArgs contains the parameters for the kb_stats tool.
+type KBStatsArgs struct {
+	Top int `json:"top,omitempty" jsonschema:"Number of sources and largest documents to list (default: 10)"`
+}
+
 // DeleteDocumentArgs contains the parameters for deleting a document from the knowledge base.
 type DeleteDocumentArgs struct {
 	ID string `json:"id" jsonschema:"required,Document identifier to delete"`
@@ -66,52 +85,136 @@ type DeleteDocumentResult struct {
 	Message string `json:"message"`
 }
 
+// AddFileArgs contains the parameters for ingesting a file into the knowledge base.
+// Exactly one of Path or Data must be set.
+type AddFileArgs struct {
+	Path     string `json:"path,omitempty" jsonschema:"Path of a file on the server; must be inside a configured allowed root"`
+	Data     string `json:"data,omitempty" jsonschema:"Base64-encoded file contents, as an alternative to path"`
+	Filename string `json:"filename,omitempty" jsonschema:"File name used to detect the format of data (e.g. report.pdf)"`
+	MIMEType string `json:"mime_type,omitempty" jsonschema:"MIME type of the file, used to detect its format when the file name has no known extension"`
+	ID       string `json:"id,omitempty" jsonschema:"Document identifier (default: the file name)"`
+}
+
+// AddFileResult is the response returned after ingesting a file, listing the stored chunk IDs.
+type AddFileResult struct {
+	Success    bool     `json:"success"`
+	Message    string   `json:"message"`
+	DocumentID string   `json:"document_id,omitempty"`
+	ChunkIDs   []string `json:"chunk_ids,omitempty"`
+}
+
 // MCPServer wraps a RAG system and exposes it as an MCP server with tool-based document operations.
 type MCPServer struct {
-	rag    *RAGSystem
-	server *mcp.Server
+	logger     *slog.Logger
+	rag        *RAGSystem
+	server     *mcp.Server // all tools
+	readServer *mcp.Server // tools that do not modify the knowledge base
+	auth       AuthConfig
+	tls        TLSConfig
+	readOnly   bool
+	origins    []string
 }
 
 // NewMCPServer creates a new MCPServer that serves the given RAG system and registers all tools.
 func NewMCPServer(rag *RAGSystem) *MCPServer {
-	server := mcp.NewServer(&mcp.Implementation{
-		Name:    "ydrag",
-		Version: "1.0.0",
-	}, nil)
-
 	m := &MCPServer{
+		logger: slog.Default().With("component", "mcp"),
 		rag:    rag,
-		server: server,
+	}
+	m.server = m.newServer()
+	m.readServer = m.newServer()
+	if cfg != nil {
+		m.auth = cfg.Server.Auth
+		m.tls = cfg.Server.TLS
+		m.readOnly = cfg.Server.ReadOnly
+		m.origins = cfg.Server.AllowedOrigins
 	}
 
-	m.registerTools()
+	m.registerTools(m.server, scopeWrite)
+	m.registerTools(m.readServer, scopeRead)
 
 	return m
 }
 
-// registerTools registers all MCP tools (add, query, list, delete) on the server.
-func (m *MCPServer) registerTools() {
-	mcp.AddTool(m.server, &mcp.Tool{
-		Name:        "add_document",
-		Description: "Add a document to the RAG knowledge base with embeddings generated automatically",
-	}, m.addDocument)
+// newServer returns an MCP server describing ydrag, with no tools and with
+// request logging, tracing, and tool-call metrics enabled.
+func (m *MCPServer) newServer() *mcp.Server {
+	server := mcp.NewServer(&mcp.Implementation{
+		Name:    "ydrag",
+		Version: version,
+	}, nil)
+	server.AddReceivingMiddleware(logTools(m.logger), traceTools, instrumentTools)
+	return server
+}
 
-	mcp.AddTool(m.server, &mcp.Tool{
+// registerTools registers the MCP tools permitted by scope on server. The read
+// scope gets query, find similar, list, and stats; the write scope additionally gets add,
+// add file, and delete.
+func (m *MCPServer) registerTools(server *mcp.Server, scope string) {
+	mcp.AddTool(server, &mcp.Tool{
 		Name:        "query_documents",
-		Description: "Search the knowledge base for documents similar to the query text using vector similarity",
+		Description: "Search the knowledge base for documents similar to the query text using vector similarity, optionally only those with matching metadata",
 	}, m.queryDocuments)
 
-	mcp.AddTool(m.server, &mcp.Tool{
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "find_similar",
+		Description: "Find documents similar to an existing document or chunk, using its stored embedding as the query and excluding the document itself",
+	}, m.findSimilar)
+
+	mcp.AddTool(server, &mcp.Tool{
 		Name:        "list_documents",
 		Description: "List all documents in the knowledge base",
 	}, m.listDocuments)
 
-	mcp.AddTool(m.server, &mcp.Tool{
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "kb_stats",
+		Description: "Report knowledge base statistics: document and chunk counts per collection and source, content length distribution, embedding model, vector index status, and storage size",
+	}, m.kbStats)
+
+	if scope != scopeWrite {
+		return
+	}
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "add_document",
+		Description: "Add a document to the RAG knowledge base with embeddings generated automatically",
+	}, m.addDocument)
+
+	mcp.AddTool(server, &mcp.Tool{
+		Name:        "add_file",
+		Description: "Extract text from a file (PDF, Markdown, HTML, DOCX, ODT, PPTX, XLSX, or plain text), split it into chunks, and add them to the knowledge base",
+	}, m.addFile)
+
+	mcp.AddTool(server, &mcp.Tool{
 		Name:        "delete_document",
-		Description: "Delete a document from the knowledge base",
+		Description: "Delete a document, and any chunks stored under it, from the knowledge base",
 	}, m.deleteDocument)
 }
 
+// serverFor returns the MCP server for a new HTTP session, choosing the one
+// whose tools match the scopes of the request's bearer token. Without
+// authentication every session gets all tools unless the server is read-only.
+func (m *MCPServer) serverFor(r *http.Request) *mcp.Server {
+	if m.readOnly {
+		return m.readServer
+	}
+	info := auth.TokenInfoFromContext(r.Context())
+	if info == nil || slices.Contains(info.Scopes, scopeWrite) {
+		return m.server
+	}
+	return m.readServer
+}
+
+// authenticate returns middleware that verifies bearer tokens when tokens are
+// configured, and passes requests through unchanged otherwise.
+func authenticate(tokens []TokenConfig) func(http.Handler) http.Handler {
+	verifier := newTokenVerifier(tokens)
+	if verifier == nil {
+		return func(h http.Handler) http.Handler { return h }
+	}
+	return auth.RequireBearerToken(verifier, nil)
+}
+
 // addDocument handles the add_document tool call, validating inputs and storing the document with its embedding.
 func (m *MCPServer) addDocument(ctx context.Context, req *mcp.CallToolRequest, args AddDocumentArgs) (*mcp.CallToolResult, AddDocumentResult, error) {
 	if args.ID == "" {
@@ -127,7 +230,7 @@ func (m *MCPServer) addDocument(ctx context.Context, req *mcp.CallToolRequest, a
 		}, AddDocumentResult{Success: false, Message: "document content is required"}, nil
 	}
 
-	if err := m.rag.AddDocument(args.ID, args.Content); err != nil {
+	if err := m.rag.AddDocument(ctx, args.ID, args.Content); err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error adding document: %v", err)}},
 			IsError: true,
@@ -139,6 +242,74 @@ func (m *MCPServer) addDocument(ctx context.Context, req *mcp.CallToolRequest, a
 	}, AddDocumentResult{Success: true, Message: fmt.Sprintf("Document '%s' added successfully", args.ID)}, nil
 }
 
+// addFile handles the add_file tool call, reading the file from an allowed
+// server path or from base64 data, then chunking and storing its text.
+func (m *MCPServer) addFile(ctx context.Context, req *mcp.CallToolRequest, args AddFileArgs) (*mcp.CallToolResult, AddFileResult, error) {
+	fail := func(msg string) (*mcp.CallToolResult, AddFileResult, error) {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: " + msg}},
+			IsError: true,
+		}, AddFileResult{Success: false, Message: msg}, nil
+	}
+
+	ingest := DefaultConfig().Ingest
+	if cfg != nil {
+		ingest = cfg.Ingest
+	}
+
+	var name string
+	var data []byte
+	switch {
+	case args.Path != "" && args.Data != "":
+		return fail("specify either path or data, not both")
+	case args.Path != "":
+		path, err := resolveAllowedPath(args.Path, ingest.AllowedRoots)
+		if err != nil {
+			return fail(err.Error())
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return fail(err.Error())
+		}
+		if ingest.MaxFileSize > 0 && info.Size() > ingest.MaxFileSize {
+			return fail(fmt.Sprintf("file exceeds maximum size of %d bytes", ingest.MaxFileSize))
+		}
+		if data, err = os.ReadFile(path); err != nil {
+			return fail(err.Error())
+		}
+		name = path
+	case args.Data != "":
+		if ingest.MaxFileSize > 0 && int64(base64.StdEncoding.DecodedLen(len(args.Data))) > ingest.MaxFileSize {
+			return fail(fmt.Sprintf("file exceeds maximum size of %d bytes", ingest.MaxFileSize))
+		}
+		var err error
+		if data, err = base64.StdEncoding.DecodeString(args.Data); err != nil {
+			return fail(fmt.Sprintf("invalid base64 data: %v", err))
+		}
+		name = args.Filename
+	default:
+		return fail("either path or data is required")
+	}
+
+	id := args.ID
+	if id == "" {
+		id = filepath.Base(name)
+	}
+	if id == "" || id == "." {
+		return fail("document ID is required when no file name is given")
+	}
+
+	chunkIDs, err := m.rag.IngestFile(ctx, id, name, args.MIMEType, data)
+	if err != nil {
+		return fail(fmt.Sprintf("failed to add file: %v", err))
+	}
+
+	msg := fmt.Sprintf("File added as document '%s' (%d chunks)", id, len(chunkIDs))
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: msg}},
+	}, AddFileResult{Success: true, Message: msg, DocumentID: id, ChunkIDs: chunkIDs}, nil
+}
+
 // queryDocuments handles the query_documents tool call, performing vector similarity search and returning ranked results.
 func (m *MCPServer) queryDocuments(ctx context.Context, req *mcp.CallToolRequest, args QueryDocumentsArgs) (*mcp.CallToolResult, QueryDocumentsResult, error) {
 	if args.Query == "" {
@@ -153,7 +324,7 @@ func (m *MCPServer) queryDocuments(ctx context.Context, req *mcp.CallToolRequest
 		topK = 5
 	}
 
-	results, err := m.rag.Query(args.Query, topK)
+	results, err := m.rag.Query(ctx, args.Query, topK, args.Filter)
 	if err != nil {
 		return &mcp.CallToolResult{
 			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error querying documents: %v", err)}},
@@ -161,12 +332,44 @@ func (m *MCPServer) queryDocuments(ctx context.Context, req *mcp.CallToolRequest
 		}, QueryDocumentsResult{}, nil
 	}
 
+	return searchToolResult(results)
+}
+
+// findSimilar handles the find_similar tool call, searching with the stored
+// embedding of an existing document and returning ranked results.
+func (m *MCPServer) findSimilar(ctx context.Context, req *mcp.CallToolRequest, args FindSimilarArgs) (*mcp.CallToolResult, QueryDocumentsResult, error) {
+	if args.ID == "" {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: "Error: document ID is required"}},
+			IsError: true,
+		}, QueryDocumentsResult{}, nil
+	}
+
+	topK := args.TopK
+	if topK <= 0 {
+		topK = 5
+	}
+
+	results, err := m.rag.Similar(ctx, args.ID, topK)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error finding similar documents: %v", err)}},
+			IsError: true,
+		}, QueryDocumentsResult{}, nil
+	}
+	return searchToolResult(results)
+}
+
+// searchToolResult formats search results as the ranked text and structured
+// output shared by query_documents and find_similar.
+func searchToolResult(results []SearchResult) (*mcp.CallToolResult, QueryDocumentsResult, error) {
 	queryResults := make([]QueryResult, len(results))
 	for i, r := range results {
 		queryResults[i] = QueryResult{
-			ID:      r.ID,
-			Content: r.Content,
-			Score:   r.Score,
+			ID:       r.ID,
+			Content:  r.Content,
+			Score:    r.Score,
+			Citation: r.Citation(),
 		}
 	}
 
@@ -178,7 +381,7 @@ func (m *MCPServer) queryDocuments(ctx context.Context, req *mcp.CallToolRequest
 
 	var text string
 	for i, r := range results {
-		text += fmt.Sprintf("%d. [%.4f] %s: %s\n", i+1, r.Score, r.ID, truncate(r.Content, 100))
+		text += fmt.Sprintf("%d. [%.4f] %s: %s\n", i+1, r.Score, resultLabel(r), truncate(r.Content, 100))
 	}
 
 	return &mcp.CallToolResult{
@@ -221,6 +424,28 @@ func (m *MCPServer) listDocuments(ctx context.Context, req *mcp.CallToolRequest,
 	}, ListDocumentsResult{Documents: items, Total: len(items)}, nil
 }
 
+// kbStats handles the kb_stats tool call, returning statistics for the whole knowledge base.
+func (m *MCPServer) kbStats(ctx context.Context, req *mcp.CallToolRequest, args KBStatsArgs) (*mcp.CallToolResult, KBStats, error) {
+	top := args.Top
+	if top <= 0 {
+		top = 10
+	}
+
+	stats, err := m.rag.Stats(top)
+	if err != nil {
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{&mcp.TextContent{Text: fmt.Sprintf("Error computing statistics: %v", err)}},
+			IsError: true,
+		}, KBStats{}, nil
+	}
+
+	var text strings.Builder
+	printStats(&text, stats)
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{&mcp.TextContent{Text: text.String()}},
+	}, stats, nil
+}
+
 // deleteDocument handles the delete_document tool call, removing a document by ID from the knowledge base.
 func (m *MCPServer) deleteDocument(ctx context.Context, req *mcp.CallToolRequest, args DeleteDocumentArgs) (*mcp.CallToolResult, DeleteDocumentResult, error) {
 	if args.ID == "" {
@@ -242,40 +467,72 @@ func (m *MCPServer) deleteDocument(ctx context.Context, req *mcp.CallToolRequest
 	}, DeleteDocumentResult{Success: true, Message: fmt.Sprintf("Document '%s' deleted successfully", args.ID)}, nil
 }
 
-// Run starts the MCP server using the specified transport ("stdio", "sse", or "streamable-http") and address.
-func (m *MCPServer) Run(ctx context.Context, transport, addr string) error {
+// httpHandler returns the HTTP handler for the sse or streamable-http
+// transport. The MCP endpoint and /info sit behind authentication and the
+// origin check and are traced; /healthz, /readyz, and /metrics are open so
+// that probes and scrapers need no token. Authentication requires the
+// streamable-http transport.
+func (m *MCPServer) httpHandler(transport string) (http.Handler, error) {
+	tokens, err := loadTokens(m.auth)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth configuration: %w", err)
+	}
+
+	var handler http.Handler
 	switch transport {
-	case "stdio", "":
-		return m.server.Run(ctx, &mcp.StdioTransport{})
 	case "sse":
-		handler := mcp.NewSSEHandler(func(r *http.Request) *mcp.Server {
-			return m.server
-		}, nil)
-		fmt.Fprintf(os.Stderr, "MCP SSE server listening on %s\n", addr)
-		srv := &http.Server{Addr: addr, Handler: handler}
-		go func() {
-			<-ctx.Done()
-			srv.Close()
-		}()
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			return err
+		// The SSE handler chooses a session's tools when its stream opens but
+		// does not tie later messages to the token that opened it, so a read
+		// token that learned a write session's ID could call write tools.
+		if len(tokens) > 0 {
+			return nil, fmt.Errorf("the sse transport does not support authentication: use streamable-http")
 		}
-		return nil
+		handler = mcp.NewSSEHandler(m.serverFor, nil)
 	case "streamable-http":
-		handler := mcp.NewStreamableHTTPHandler(func(r *http.Request) *mcp.Server {
-			return m.server
-		}, nil)
-		fmt.Fprintf(os.Stderr, "MCP Streamable HTTP server listening on %s\n", addr)
-		srv := &http.Server{Addr: addr, Handler: handler}
-		go func() {
-			<-ctx.Done()
-			srv.Close()
-		}()
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			return err
-		}
-		return nil
+		handler = mcp.NewStreamableHTTPHandler(m.serverFor, &mcp.StreamableHTTPOptions{Logger: m.logger})
 	default:
-		return fmt.Errorf("unsupported transport: %q (use stdio, sse, or streamable-http)", transport)
+		return nil, fmt.Errorf("unsupported transport: %q (use stdio, sse, or streamable-http)", transport)
+	}
+
+	protect := authenticate(tokens)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /healthz", m.handleHealth)
+	mux.HandleFunc("GET /readyz", m.handleReady)
+	mux.Handle("GET /metrics", metricsHandler(m.rag))
+	mux.Handle("GET /info", traceHTTP(checkOrigin(m.origins, protect(http.HandlerFunc(m.handleInfo)))))
+	mux.Handle("/", traceHTTP(checkOrigin(m.origins, protect(handler))))
+	return mux, nil
+}
+
+// Run starts the MCP server using the specified transport ("stdio", "sse", or
+// "streamable-http"). The HTTP transports listen on addr, a TCP host:port or a
+// Unix socket written as "unix:<path>", and use TLS when configured.
+func (m *MCPServer) Run(ctx context.Context, transport, addr string) error {
+	if transport == "stdio" || transport == "" {
+		if m.readOnly {
+			return m.readServer.Run(ctx, &mcp.StdioTransport{})
+		}
+		return m.server.Run(ctx, &mcp.StdioTransport{})
+	}
+
+	handler, err := m.httpHandler(transport)
+	if err != nil {
+		return err
+	}
+	tlsConfig, err := buildTLSConfig(m.tls)
+	if err != nil {
+		return err
+	}
+	ln, err := listen(addr, tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	scheme := "http"
+	if tlsConfig != nil {
+		scheme = "https"
 	}
+	m.logger.Info("MCP server listening", "transport", transport, "addr", ln.Addr().String(), "scheme", scheme, "read_only", m.readOnly)
+	return serveHTTP(ctx, ln, handler)
 }
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Metrics recorded by the RAG core and MCP server. They are collected for
+// every command but only exported by the HTTP transports' /metrics endpoint.
+var (
+	embeddingDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ydrag_embedding_duration_seconds",
+		Help:    "Time taken by GenerateEmbedding, including tokenization and decode.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 14),
+	})
+	embeddingTokens = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "ydrag_embedding_tokens",
+		Help:    "Number of tokens per embedded text.",
+		Buckets: prometheus.ExponentialBuckets(4, 2, 12),
+	})
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ydrag_db_query_duration_seconds",
+		Help:    "Time taken by DuckDB statements, by operation.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 2, 14),
+	}, []string{"operation"})
+	toolCalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ydrag_mcp_tool_calls_total",
+		Help: "Number of MCP tool calls, by tool.",
+	}, []string{"tool"})
+	toolErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "ydrag_mcp_tool_errors_total",
+		Help: "Number of MCP tool calls that failed or returned an error result, by tool.",
+	}, []string{"tool"})
+	toolDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "ydrag_mcp_tool_duration_seconds",
+		Help:    "Time taken by MCP tool calls, by tool.",
+		Buckets: prometheus.ExponentialBuckets(0.001, 2, 16),
+	}, []string{"tool"})
+)
+
+// observeDB records the time since start as the duration of a DuckDB
+// operation. It is meant to be deferred: defer observeDB("search", time.Now()).
+func observeDB(operation string, start time.Time) {
+	dbQueryDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+}
+
+// instrumentTools is MCP server middleware that counts and times tool calls.
+// A call counts as an error if the handler fails or returns an error result.
+func instrumentTools(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		params, ok := req.GetParams().(*mcp.CallToolParamsRaw)
+		if method != "tools/call" || !ok {
+			return next(ctx, method, req)
+		}
+
+		start := time.Now()
+		result, err := next(ctx, method, req)
+
+		tool := params.Name
+		toolCalls.WithLabelValues(tool).Inc()
+		toolDuration.WithLabelValues(tool).Observe(time.Since(start).Seconds())
+		if res, ok := result.(*mcp.CallToolResult); err != nil || (ok && res.IsError) {
+			toolErrors.WithLabelValues(tool).Inc()
+		}
+		return result, err
+	}
+}
+
+// kbCollector reports the size of a RAG system's knowledge base at scrape time.
+type kbCollector struct {
+	rag       *RAGSystem
+	documents *prometheus.Desc
+	chunks    *prometheus.Desc
+	dbSize    *prometheus.Desc
+}
+
+// newKBCollector returns a collector for the document totals and database
+// file size of rag.
+func newKBCollector(rag *RAGSystem) *kbCollector {
+	return &kbCollector{
+		rag:       rag,
+		documents: prometheus.NewDesc("ydrag_documents", "Number of documents, counting a chunked file once.", nil, nil),
+		chunks:    prometheus.NewDesc("ydrag_chunks", "Number of stored rows, including every chunk.", nil, nil),
+		dbSize:    prometheus.NewDesc("ydrag_database_size_bytes", "Size of the DuckDB database file and its WAL.", nil, nil),
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (c *kbCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.documents
+	ch <- c.chunks
+	ch <- c.dbSize
+}
+
+// Collect implements prometheus.Collector. Values that cannot be read are
+// omitted rather than failing the scrape.
+func (c *kbCollector) Collect(ch chan<- prometheus.Metric) {
+	if info, err := c.rag.Info(); err == nil {
+		ch <- prometheus.MustNewConstMetric(c.documents, prometheus.GaugeValue, float64(info.Documents))
+		ch <- prometheus.MustNewConstMetric(c.chunks, prometheus.GaugeValue, float64(info.Chunks))
+	}
+	if size, ok := databaseSize(c.rag.dbPath); ok {
+		ch <- prometheus.MustNewConstMetric(c.dbSize, prometheus.GaugeValue, float64(size))
+	}
+}
+
+// databaseSize returns the combined size of the DuckDB file at path and its
+// write-ahead log. It reports false for in-memory databases.
+func databaseSize(path string) (int64, bool) {
+	if path == "" || path == ":memory:" {
+		return 0, false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, false
+	}
+	size := info.Size()
+	if wal, err := os.Stat(path + ".wal"); err == nil {
+		size += wal.Size()
+	}
+	return size, true
+}
+
+// metricsHandler returns an http.Handler exposing the ydrag metrics, the
+// knowledge base totals of rag, and the standard Go and process metrics.
+func metricsHandler(rag *RAGSystem) http.Handler {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		embeddingDuration,
+		embeddingTokens,
+		dbQueryDuration,
+		toolCalls,
+		toolErrors,
+		toolDuration,
+		newKBCollector(rag),
+	)
+	return promhttp.HandlerFor(reg, promhttp.HandlerOpts{})
+}
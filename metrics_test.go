@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// connectInMemory connects a client to server over in-memory transports.
+func connectInMemory(t *testing.T, server *mcp.Server) *mcp.ClientSession {
+	t.Helper()
+	ctx := context.Background()
+	serverTransport, clientTransport := mcp.NewInMemoryTransports()
+	if _, err := server.Connect(ctx, serverTransport, nil); err != nil {
+		t.Fatalf("server connect failed: %v", err)
+	}
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	session, err := client.Connect(ctx, clientTransport, nil)
+	if err != nil {
+		t.Fatalf("client connect failed: %v", err)
+	}
+	t.Cleanup(func() { session.Close() })
+	return session
+}
+
+func TestInstrumentTools(t *testing.T) {
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "doc1", "content", "", "", []float32{1, 0, 0})
+	m := NewMCPServer(rag)
+	session := connectInMemory(t, m.server)
+	ctx := context.Background()
+
+	callsBefore := testutil.ToFloat64(toolCalls.WithLabelValues("list_documents"))
+	errorsBefore := testutil.ToFloat64(toolErrors.WithLabelValues("delete_document"))
+
+	if _, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "list_documents", Arguments: map[string]any{}}); err != nil {
+		t.Fatalf("list_documents failed: %v", err)
+	}
+	res, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "delete_document", Arguments: map[string]any{"id": "missing"}})
+	if err != nil {
+		t.Fatalf("delete_document failed: %v", err)
+	}
+	if !res.IsError {
+		t.Fatal("expected delete of missing document to return an error result")
+	}
+
+	if got := testutil.ToFloat64(toolCalls.WithLabelValues("list_documents")) - callsBefore; got != 1 {
+		t.Errorf("list_documents calls increased by %v, want 1", got)
+	}
+	if got := testutil.ToFloat64(toolErrors.WithLabelValues("delete_document")) - errorsBefore; got != 1 {
+		t.Errorf("delete_document errors increased by %v, want 1", got)
+	}
+}
+
+func TestMetricsEndpoint(t *testing.T) {
+	ts := newHealthTestServer(t, AuthConfig{Tokens: []TokenConfig{{Token: "secret"}}})
+
+	resp, err := http.Get(ts.URL + "/metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want %d", resp.StatusCode, http.StatusOK)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"ydrag_documents 2",
+		"ydrag_chunks 3",
+		"ydrag_db_query_duration_seconds",
+		"go_goroutines",
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output missing %q", want)
+		}
+	}
+}
+
+func TestDatabaseSize(t *testing.T) {
+	if _, ok := databaseSize(":memory:"); ok {
+		t.Error("expected no size for in-memory database")
+	}
+	if _, ok := databaseSize(""); ok {
+		t.Error("expected no size for empty path")
+	}
+
+	path := filepath.Join(t.TempDir(), "rag.db")
+	os.WriteFile(path, make([]byte, 100), 0644)
+	os.WriteFile(path+".wal", make([]byte, 20), 0644)
+	size, ok := databaseSize(path)
+	if !ok || size != 120 {
+		t.Errorf("databaseSize = %d, %v; want 120, true", size, ok)
+	}
+}
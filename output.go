@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// Output formats accepted by the global -format flag.
+const (
+	formatText  = "text"
+	formatJSON  = "json"
+	formatJSONL = "jsonl"
+	formatCSV   = "csv"
+	formatTable = "table"
+)
+
+// outputFormats lists the valid output formats in the order shown to users.
+var outputFormats = []string{formatText, formatJSON, formatJSONL, formatCSV, formatTable}
+
+// Output writes command results in the format chosen with the global -format
+// flag. The text format is each command's own human-readable output; the
+// other formats carry full, untruncated values.
+type Output struct {
+	W      io.Writer
+	Format string
+}
+
+// output is the destination for command results, configured in main.
+var output = &Output{W: os.Stdout, Format: formatText}
+
+// SetOutputFormat selects the format used by output for all commands.
+func SetOutputFormat(format string) error {
+	format = strings.ToLower(format)
+	for _, f := range outputFormats {
+		if f == format {
+			output.Format = format
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown output format %q (use %s)", format, strings.Join(outputFormats, ", "))
+}
+
+// Result is a command's output. Value is encoded by the json format, and by
+// jsonl as one line per element when it is a slice. Rows, a slice of structs,
+// is encoded by csv and table with one column per field; it defaults to
+// Value. Text prints the human-readable form.
+type Result struct {
+	Value any
+	Rows  any
+	Text  func(w io.Writer)
+}
+
+// Print writes r in the output format.
+func (o *Output) Print(r Result) error {
+	rows := r.Rows
+	if rows == nil {
+		rows = r.Value
+	}
+
+	switch o.Format {
+	case formatJSON:
+		enc := json.NewEncoder(o.W)
+		enc.SetIndent("", "  ")
+		return enc.Encode(r.Value)
+	case formatJSONL:
+		enc := json.NewEncoder(o.W)
+		v := reflect.ValueOf(r.Value)
+		if v.Kind() != reflect.Slice {
+			return enc.Encode(r.Value)
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := enc.Encode(v.Index(i).Interface()); err != nil {
+				return err
+			}
+		}
+		return nil
+	case formatCSV:
+		header, records := tabulate(rows)
+		w := csv.NewWriter(o.W)
+		w.Write(header)
+		w.WriteAll(records)
+		return w.Error()
+	case formatTable:
+		header, records := tabulate(rows)
+		tw := tabwriter.NewWriter(o.W, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, strings.Join(header, "\t"))
+		for _, rec := range records {
+			for i, cell := range rec {
+				rec[i] = tableEscaper.Replace(cell)
+			}
+			fmt.Fprintln(tw, strings.Join(rec, "\t"))
+		}
+		return tw.Flush()
+	default:
+		if r.Text != nil {
+			r.Text(o.W)
+		}
+		return nil
+	}
+}
+
+// tableEscaper keeps each table row on one line with aligned columns.
+var tableEscaper = strings.NewReplacer("\t", " ", "\n", `\n`, "\r", `\r`)
+
+// StatusResult reports the outcome of a command that changes one document.
+type StatusResult struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+// column is a field of a row struct, named by its JSON key. Nested structs are
+// flattened into dotted names such as "search_latency.p50_ms".
+type column struct {
+	name  string
+	index []int
+}
+
+// tabulate converts rows, a struct or a slice of structs or struct pointers,
+// into a header and string records. Maps and slices are encoded as JSON; nil
+// values and missing pointers become empty cells. Rows that are not structs
+// form a single "value" column.
+func tabulate(rows any) ([]string, [][]string) {
+	v := reflect.ValueOf(rows)
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Kind() != reflect.Slice {
+		v = reflect.Append(reflect.MakeSlice(reflect.SliceOf(v.Type()), 0, 1), v)
+	}
+	elem := v.Type().Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+
+	if elem.Kind() != reflect.Struct {
+		records := make([][]string, v.Len())
+		for i := range records {
+			records[i] = []string{formatCell(v.Index(i))}
+		}
+		return []string{"value"}, records
+	}
+
+	cols := structColumns(elem, "", nil)
+	header := make([]string, len(cols))
+	for i, c := range cols {
+		header[i] = c.name
+	}
+
+	records := make([][]string, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		row := reflect.Indirect(v.Index(i))
+		rec := make([]string, len(cols))
+		if row.IsValid() {
+			for j, c := range cols {
+				rec[j] = formatCell(row.FieldByIndex(c.index))
+			}
+		}
+		records = append(records, rec)
+	}
+	return header, records
+}
+
+// structColumns lists the exported, JSON-visible fields of t.
+func structColumns(t reflect.Type, prefix string, index []int) []column {
+	var cols []column
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		idx := append(append([]int(nil), index...), i)
+		if f.Type.Kind() == reflect.Struct {
+			cols = append(cols, structColumns(f.Type, prefix+name+".", idx)...)
+			continue
+		}
+		cols = append(cols, column{name: prefix + name, index: idx})
+	}
+	return cols
+}
+
+// formatCell renders a single field value for csv and table output.
+func formatCell(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, v.Type().Bits())
+	case reflect.Map, reflect.Slice, reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return ""
+		}
+		data, err := json.Marshal(v.Interface())
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	default:
+		return fmt.Sprint(v.Interface())
+	}
+}
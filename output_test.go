@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+// captureOutput directs command results to a buffer in the given format for
+// the duration of the test.
+func captureOutput(t *testing.T, format string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	prev := output
+	output = &Output{W: &buf, Format: formatText}
+	t.Cleanup(func() { output = prev })
+	if err := SetOutputFormat(format); err != nil {
+		t.Fatalf("SetOutputFormat(%q) failed: %v", format, err)
+	}
+	return &buf
+}
+
+type testRow struct {
+	Name    string            `json:"name"`
+	Score   float32           `json:"score"`
+	Tags    []string          `json:"tags,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+	Latency BenchLatency      `json:"latency"`
+	Hidden  string            `json:"-"`
+	private string
+}
+
+func TestSetOutputFormat(t *testing.T) {
+	captureOutput(t, "JSON")
+	if output.Format != formatJSON {
+		t.Errorf("Format = %q, want %q", output.Format, formatJSON)
+	}
+	if err := SetOutputFormat("xml"); err == nil {
+		t.Error("expected error for unknown format")
+	}
+	if output.Format != formatJSON {
+		t.Error("invalid format changed the current format")
+	}
+}
+
+func TestTabulate(t *testing.T) {
+	rows := []testRow{
+		{Name: "a", Score: 0.9, Tags: []string{"x"}, Latency: BenchLatency{P50: 1.5}, Hidden: "h", private: "p"},
+		{Name: "b"},
+	}
+	header, records := tabulate(rows)
+
+	wantHeader := "name,score,tags,meta,latency.mean_ms,latency.p50_ms,latency.p90_ms,latency.p99_ms,latency.max_ms"
+	if got := strings.Join(header, ","); got != wantHeader {
+		t.Errorf("header = %s, want %s", got, wantHeader)
+	}
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+	if got := strings.Join(records[0][:6], "|"); got != `a|0.9|["x"]||0|1.5` {
+		t.Errorf("record 0 = %s", got)
+	}
+	if records[1][2] != "" {
+		t.Errorf("nil slice rendered as %q, want empty", records[1][2])
+	}
+}
+
+func TestTabulate_SingleStructAndScalars(t *testing.T) {
+	header, records := tabulate(StatusResult{ID: "doc", Status: "added"})
+	if strings.Join(header, ",") != "id,status" || len(records) != 1 || records[0][1] != "added" {
+		t.Errorf("struct: header %v records %v", header, records)
+	}
+
+	header, records = tabulate([]string{"x", "y"})
+	if strings.Join(header, ",") != "value" || len(records) != 2 || records[1][0] != "y" {
+		t.Errorf("scalars: header %v records %v", header, records)
+	}
+}
+
+func TestOutputPrint_Formats(t *testing.T) {
+	rows := []testRow{{Name: "first\nline", Score: 1}, {Name: "second", Score: 0.5}}
+	text := func(w io.Writer) { io.WriteString(w, "human\n") }
+
+	buf := captureOutput(t, formatText)
+	output.Print(Result{Value: rows, Text: text})
+	if buf.String() != "human\n" {
+		t.Errorf("text output = %q", buf.String())
+	}
+
+	buf = captureOutput(t, formatJSON)
+	output.Print(Result{Value: rows, Text: text})
+	var decoded []testRow
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || len(decoded) != 2 || decoded[0].Name != "first\nline" {
+		t.Errorf("json output %q: %v", buf.String(), err)
+	}
+
+	buf = captureOutput(t, formatJSONL)
+	output.Print(Result{Value: rows, Text: text})
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 || !strings.Contains(lines[1], `"name":"second"`) {
+		t.Errorf("jsonl output = %q", buf.String())
+	}
+
+	buf = captureOutput(t, formatCSV)
+	output.Print(Result{Value: rows, Text: text})
+	records, err := csv.NewReader(buf).ReadAll()
+	if err != nil {
+		t.Fatalf("csv output is not valid CSV: %v", err)
+	}
+	if len(records) != 3 || records[1][0] != "first\nline" || records[2][1] != "0.5" {
+		t.Errorf("csv records = %q", records)
+	}
+
+	buf = captureOutput(t, formatTable)
+	output.Print(Result{Value: rows, Text: text})
+	lines = strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "name") || !strings.Contains(lines[1], `first\nline`) {
+		t.Errorf("table output = %q", buf.String())
+	}
+}
+
+func TestOutputPrint_RowsOverrideValue(t *testing.T) {
+	report := EvalReport{K: 3, PerQuery: []QueryEval{{Query: "q", Metrics: EvalMetrics{Recall: 1}}}}
+
+	buf := captureOutput(t, formatCSV)
+	output.Print(Result{Value: report, Rows: report.PerQuery})
+	if !strings.HasPrefix(buf.String(), "id,query,relevant,retrieved,metrics.recall") {
+		t.Errorf("csv header = %q", strings.SplitN(buf.String(), "\n", 2)[0])
+	}
+
+	buf = captureOutput(t, formatJSONL)
+	output.Print(Result{Value: report, Rows: report.PerQuery})
+	if n := strings.Count(buf.String(), "\n"); n != 1 || !strings.Contains(buf.String(), `"k":3`) {
+		t.Errorf("jsonl report = %q", buf.String())
+	}
+}
+
+func TestListCommand_JSON(t *testing.T) {
+	rag := newTestRAG(t)
+	long := strings.Repeat("long content ", 20)
+	insertTestDoc(t, rag, "file#0", long, "file", `{"source":"file.txt"}`, []float32{1, 0, 0})
+	buf := captureOutput(t, formatJSON)
+
+	if err := RunCommand(&ListCommand{}, rag, nil); err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	var docs []Document
+	if err := json.Unmarshal(buf.Bytes(), &docs); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if len(docs) != 1 || docs[0].Content != long || docs[0].ParentID != "file" || docs[0].Metadata["source"] != "file.txt" {
+		t.Errorf("unexpected documents: %+v", docs)
+	}
+	if strings.Contains(buf.String(), "Embedding") {
+		t.Error("embedding included in JSON output")
+	}
+}
+
+func TestListCommand_EmptyJSON(t *testing.T) {
+	buf := captureOutput(t, formatJSON)
+	if err := RunCommand(&ListCommand{}, newTestRAG(t), nil); err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("empty list = %q, want []", buf.String())
+	}
+}
+
+func TestDeleteCommand_CSV(t *testing.T) {
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "doc1", "content", "", "", []float32{1, 0, 0})
+	buf := captureOutput(t, formatCSV)
+
+	if err := RunCommand(&DeleteCommand{}, rag, []string{"doc1"}); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if buf.String() != "id,status\ndoc1,deleted\n" {
+		t.Errorf("csv output = %q", buf.String())
+	}
+}
@@ -1,134 +1,318 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
+	"maps"
 	"math"
+	"path/filepath"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
 	_ "github.com/marcboeker/go-duckdb/v2"
 
-	"github.com/hybridgroup/yzma/pkg/llama"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Document represents a stored document with its content and embedding vector.
+// Chunks of an ingested file are stored as documents whose ParentID names the
+// file's document ID.
 type Document struct {
-	ID        string
-	Content   string
-	Embedding []float32
+	ID         string            `json:"id"`
+	Content    string            `json:"content"`
+	Embedding  []float32         `json:"-"`
+	ParentID   string            `json:"parent_id,omitempty"`
+	ChunkIndex int               `json:"chunk_index"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Collection string            `json:"collection"`
 }
 
+// defaultCollection holds documents added without selecting a collection,
+// including those stored before collections existed.
+const defaultCollection = "default"
+
 // RAGSystem provides retrieval-augmented generation backed by a llama embedding model and DuckDB.
+// The model is loaded on first use, so commands that only read or delete
+// stored documents do not need one.
 type RAGSystem struct {
-	db           *sql.DB
-	model        llama.Model
-	vocab        llama.Vocab
-	ctx          llama.Context
+	logger    *slog.Logger
+	db        *sql.DB
+	dbPath    string
+	modelPath string
+	libPath   string
+
+	// embeddingDim is the size of the stored embedding vectors. It is zero
+	// until the documents table exists, which happens when the model is first
+	// loaded for a new database.
 	embeddingDim int32
+
+	// collection scopes reads and writes to one collection of documents;
+	// empty selects defaultCollection. Document IDs are unique within a
+	// collection.
+	collection string
+
+	// embedderMu guards the lazy loading of embedder.
+	embedderMu sync.Mutex
+	embedder   *Embedder
 }
 
-// NewRAGSystem creates a new RAGSystem by loading the llama model and opening the DuckDB database.
+// NewRAGSystem opens the DuckDB database and prepares a RAGSystem that loads
+// the llama library and model from modelPath and libPath when an embedding is
+// first needed. modelPath may be empty for commands that do not embed text.
 func NewRAGSystem(modelPath, libPath, dbPath string) (*RAGSystem, error) {
-	if err := llama.Load(libPath); err != nil {
-		return nil, fmt.Errorf("unable to load llama library: %w", err)
+	db, err := sql.Open("duckdb", dbPath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open database: %w", err)
 	}
 
-	if cfg != nil && !cfg.Verbose {
-		llama.LogSet(llama.LogSilent())
+	rag := &RAGSystem{
+		logger:    slog.Default().With("component", "rag"),
+		db:        db,
+		dbPath:    dbPath,
+		modelPath: modelPath,
+		libPath:   libPath,
 	}
-	llama.Init()
 
-	model, err := llama.ModelLoadFromFile(modelPath, llama.ModelDefaultParams())
+	dim, err := rag.storedEmbeddingDim()
 	if err != nil {
-		return nil, fmt.Errorf("unable to load model from %s: %w", modelPath, err)
+		rag.Close()
+		return nil, err
 	}
-	if model == 0 {
-		return nil, fmt.Errorf("failed to load model from %s", modelPath)
+	if dim > 0 {
+		rag.embeddingDim = dim
+		if err := rag.initDB(); err != nil {
+			rag.Close()
+			return nil, err
+		}
 	}
 
-	ctxParams := llama.ContextDefaultParams()
-	if cfg != nil {
-		ctxParams.NCtx = uint32(cfg.ContextSize)
-		ctxParams.NBatch = uint32(cfg.BatchSize)
-	}
-	ctxParams.PoolingType = llama.PoolingTypeMean
-	ctxParams.Embeddings = 1
+	return rag, nil
+}
 
-	lctx, err := llama.InitFromModel(model, ctxParams)
+// storedEmbeddingDim returns the size of the embedding column of an existing
+// documents table, or zero if the table does not exist yet.
+func (r *RAGSystem) storedEmbeddingDim() (int32, error) {
+	var dataType string
+	err := r.db.QueryRow(`
+		SELECT data_type FROM information_schema.columns
+		WHERE table_name = 'documents' AND column_name = 'embedding'
+	`).Scan(&dataType)
+	if errors.Is(err, sql.ErrNoRows) {
+		return 0, nil
+	}
 	if err != nil {
-		llama.ModelFree(model)
-		return nil, fmt.Errorf("unable to initialize context: %w", err)
+		return 0, fmt.Errorf("failed to inspect documents table: %w", err)
 	}
 
-	vocab := llama.ModelGetVocab(model)
-	embeddingDim := llama.ModelNEmbd(model)
-
-	db, err := sql.Open("duckdb", dbPath)
-	if err != nil {
-		llama.Free(lctx)
-		llama.ModelFree(model)
-		return nil, fmt.Errorf("unable to open database: %w", err)
+	var dim int32
+	if _, err := fmt.Sscanf(dataType, "FLOAT[%d]", &dim); err != nil {
+		return 0, fmt.Errorf("unexpected embedding column type %s", dataType)
 	}
+	return dim, nil
+}
 
-	rag := &RAGSystem{
-		db:           db,
-		model:        model,
-		vocab:        vocab,
-		ctx:          lctx,
-		embeddingDim: embeddingDim,
+// LoadModel loads the embedding model if it is not loaded already. Commands
+// that embed text load it implicitly; the server calls LoadModel at startup
+// so that a misconfigured model fails fast.
+func (r *RAGSystem) LoadModel() error {
+	_, err := r.loadEmbedder()
+	return err
+}
+
+// loadEmbedder returns the embedder, loading the model on first use. For a
+// new database it creates the documents table sized to the model; for an
+// existing one the model must produce embeddings of the stored size.
+func (r *RAGSystem) loadEmbedder() (*Embedder, error) {
+	r.embedderMu.Lock()
+	defer r.embedderMu.Unlock()
+
+	if r.embedder != nil {
+		return r.embedder, nil
+	}
+	if r.modelPath == "" {
+		return nil, fmt.Errorf("no embedding model configured (set -model, model in config.yaml, or YDRAG_MODEL)")
 	}
 
-	if err := rag.initDB(); err != nil {
-		rag.Close()
+	e, err := NewEmbedder(r.modelPath, r.libPath, r.log())
+	if err != nil {
 		return nil, err
 	}
 
-	return rag, nil
+	switch {
+	case r.embeddingDim == 0:
+		r.embeddingDim = e.Dim()
+		if err := r.initDB(); err != nil {
+			e.Close()
+			return nil, err
+		}
+	case r.embeddingDim != e.Dim():
+		e.Close()
+		return nil, fmt.Errorf("model %s produces %d-dimensional embeddings but the database stores %d", r.modelPath, e.Dim(), r.embeddingDim)
+	}
+
+	r.embedder = e
+	return e, nil
+}
+
+// hasTable reports whether the documents table exists. It does not until a
+// model has been loaded for a new database, and until then the knowledge base
+// is empty.
+func (r *RAGSystem) hasTable() bool {
+	return r.embeddingDim > 0
+}
+
+// ModelLoaded reports whether the embedding model has been loaded.
+func (r *RAGSystem) ModelLoaded() bool {
+	r.embedderMu.Lock()
+	defer r.embedderMu.Unlock()
+	return r.embedder != nil
 }
 
 // initDB creates the documents table in DuckDB if it does not already exist.
 func (r *RAGSystem) initDB() error {
-	createTableSQL := fmt.Sprintf(`
-		CREATE TABLE IF NOT EXISTS documents (
-			id VARCHAR PRIMARY KEY,
+	if _, err := r.db.Exec(r.documentsTableSQL("documents")); err != nil {
+		return fmt.Errorf("failed to create documents table: %w", err)
+	}
+
+	// Columns added after the original schema; existing databases are
+	// upgraded in place.
+	migrations := []string{
+		`ALTER TABLE documents ADD COLUMN IF NOT EXISTS parent_id VARCHAR`,
+		`ALTER TABLE documents ADD COLUMN IF NOT EXISTS chunk_index INTEGER`,
+		`ALTER TABLE documents ADD COLUMN IF NOT EXISTS metadata JSON`,
+		`ALTER TABLE documents ADD COLUMN IF NOT EXISTS collection VARCHAR`,
+		`ALTER TABLE documents ADD COLUMN IF NOT EXISTS created_at TIMESTAMP`,
+	}
+	for _, m := range migrations {
+		if _, err := r.db.Exec(m); err != nil {
+			return fmt.Errorf("failed to migrate documents table: %w", err)
+		}
+	}
+	return r.migrateKey()
+}
+
+// documentsTableSQL returns the statement creating the documents table under
+// the given name. Document IDs are unique within a collection.
+func (r *RAGSystem) documentsTableSQL(name string) string {
+	return fmt.Sprintf(`
+		CREATE TABLE IF NOT EXISTS %s (
+			id VARCHAR NOT NULL,
 			content VARCHAR,
-			embedding FLOAT[%d]
+			embedding FLOAT[%d],
+			parent_id VARCHAR,
+			chunk_index INTEGER,
+			metadata JSON,
+			collection VARCHAR NOT NULL DEFAULT '%s',
+			created_at TIMESTAMP,
+			PRIMARY KEY (collection, id)
 		)
-	`, r.embeddingDim)
+	`, name, r.embeddingDim, defaultCollection)
+}
 
-	_, err := r.db.Exec(createTableSQL)
+// migrateKey rebuilds a documents table keyed by id alone, as created before
+// collections existed, so that it is keyed by collection and id. DuckDB
+// cannot change a primary key in place. Rows without a collection move to
+// the default collection.
+func (r *RAGSystem) migrateKey() error {
+	var keyed bool
+	err := r.db.QueryRow(`
+		SELECT count(*) > 0 FROM duckdb_constraints()
+		WHERE table_name = 'documents' AND constraint_type = 'PRIMARY KEY'
+			AND list_contains(constraint_column_names, 'collection')
+	`).Scan(&keyed)
 	if err != nil {
-		return fmt.Errorf("failed to create documents table: %w", err)
+		return fmt.Errorf("failed to read documents table key: %w", err)
 	}
+	if keyed {
+		return nil
+	}
+
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+	for _, stmt := range []string{
+		r.documentsTableSQL("documents_rekeyed"),
+		`INSERT INTO documents_rekeyed
+			SELECT id, content, embedding, parent_id, chunk_index, metadata,
+				COALESCE(collection, '` + defaultCollection + `'), created_at
+			FROM documents`,
+		`DROP TABLE documents`,
+		`ALTER TABLE documents_rekeyed RENAME TO documents`,
+	} {
+		if _, err := tx.Exec(stmt); err != nil {
+			return fmt.Errorf("failed to migrate documents table key: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to migrate documents table key: %w", err)
+	}
+	r.log().Info("migrated documents table to per-collection document IDs")
 	return nil
 }
 
-// Close releases all resources held by the RAGSystem, including the database, context, and model.
+// SetCollection selects the collection that subsequent operations read from
+// and write to. An empty name selects the default collection.
+func (r *RAGSystem) SetCollection(name string) {
+	r.collection = name
+}
+
+// Collection returns the name of the selected collection.
+func (r *RAGSystem) Collection() string {
+	if r.collection == "" {
+		return defaultCollection
+	}
+	return r.collection
+}
+
+// collectionFilter is a SQL condition matching documents in the selected
+// collection, taking the collection name as its only parameter. Rows stored
+// before collections existed have a NULL collection and belong to the default.
+const collectionFilter = `COALESCE(collection, '` + defaultCollection + `') = ?`
+
+// documentKey is a SQL expression identifying the document a row belongs to,
+// the file of a chunk or the row itself, across collections. IDs are only
+// unique within a collection, so the key includes it.
+const documentKey = `(COALESCE(collection, '` + defaultCollection + `'), coalesce(parent_id, id))`
+
+// Close releases all resources held by the RAGSystem, including the database
+// and, if it was loaded, the model.
 func (r *RAGSystem) Close() {
 	if r.db != nil {
 		r.db.Close()
 	}
-	if r.ctx != 0 {
-		llama.Free(r.ctx)
+	if r.embedder != nil {
+		r.embedder.Close()
 	}
-	if r.model != 0 {
-		llama.ModelFree(r.model)
-	}
-	llama.Close()
 }
 
-// GenerateEmbedding returns a normalized embedding vector for the given text using the loaded model.
-func (r *RAGSystem) GenerateEmbedding(text string) ([]float32, error) {
-	tokens := llama.Tokenize(r.vocab, text, true, true)
+// GenerateEmbedding returns a normalized embedding vector for the given text,
+// loading the model if needed.
+func (r *RAGSystem) GenerateEmbedding(ctx context.Context, text string) (vec []float32, err error) {
+	ctx, span := tracer().Start(ctx, "GenerateEmbedding", trace.WithAttributes(attribute.Int("text.length", len(text))))
+	defer func() { endSpan(span, err) }()
 
-	batch := llama.BatchGetOne(tokens)
-	llama.Decode(r.ctx, batch)
+	e, err := r.loadEmbedder()
+	if err != nil {
+		return nil, err
+	}
 
-	vec, err := llama.GetEmbeddingsSeq(r.ctx, 0, r.embeddingDim)
+	start := time.Now()
+	vec, tokens, err := e.Embed(ctx, text)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get embeddings: %w", err)
+		return nil, err
 	}
 
+	span.SetAttributes(attribute.Int("tokens", tokens))
+	r.log().Debug("generated embedding", "tokens", tokens, "duration", time.Since(start))
 	return normalizeVector(vec), nil
 }
 
@@ -151,93 +335,454 @@ func normalizeVector(vec []float32) []float32 {
 }
 
 // AddDocument generates an embedding for content and stores the document in the database with the given id.
-func (r *RAGSystem) AddDocument(id, content string) error {
-	embedding, err := r.GenerateEmbedding(content)
+// When duplicate rejection is configured, a near-duplicate of another document
+// is not stored and a *DuplicateError is returned.
+func (r *RAGSystem) AddDocument(ctx context.Context, id, content string) error {
+	embedding, err := r.GenerateEmbedding(ctx, content)
 	if err != nil {
 		return fmt.Errorf("failed to generate embedding: %w", err)
 	}
+	if err := r.checkDuplicate(ctx, id, [][]float32{embedding}); err != nil {
+		return err
+	}
+	return r.insertDocument(ctx, id, content, embedding)
+}
 
+// insertDocument stores a document with a precomputed embedding, replacing any
+// document with the same id in the selected collection, and any chunks stored
+// under it, but keeping its creation time.
+func (r *RAGSystem) insertDocument(ctx context.Context, id, content string, embedding []float32) error {
 	embeddingStr := floatArrayToSQL(embedding)
 
-	_, err = r.db.Exec(`
-		INSERT OR REPLACE INTO documents (id, content, embedding)
-		VALUES (?, ?, ?::FLOAT[])
-	`, id, content, embeddingStr)
+	defer observeDB("insert", time.Now())
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
+	if err := r.deleteChunks(ctx, tx, id); err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO documents (id, content, embedding, collection, created_at)
+		VALUES (?, ?, ?::FLOAT[], ?, current_timestamp)
+		ON CONFLICT (collection, id) DO UPDATE SET
+			content = excluded.content,
+			embedding = excluded.embedding,
+			parent_id = NULL,
+			chunk_index = NULL,
+			metadata = NULL
+	`, id, content, embeddingStr, r.Collection())
 	if err != nil {
 		return fmt.Errorf("failed to insert document: %w", err)
 	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit document: %w", err)
+	}
+	r.log().Debug("stored document", "document", id)
+	return nil
+}
+
+// deleteChunks deletes the chunks of a file or long text stored under
+// parentID in the selected collection, before a single document replaces it.
+func (r *RAGSystem) deleteChunks(ctx context.Context, tx *sql.Tx, parentID string) error {
+	_, err := tx.ExecContext(ctx, `DELETE FROM documents WHERE parent_id = ? AND `+collectionFilter, parentID, r.Collection())
+	if err != nil {
+		return fmt.Errorf("failed to replace document '%s': %w", parentID, err)
+	}
 	return nil
 }
 
+// Chunk is a piece of an ingested file, with metadata that applies to it
+// alone, such as the section it came from.
+type Chunk struct {
+	Text     string
+	Metadata map[string]string
+}
+
+// AddChunks embeds each chunk and stores it as a document with the ID
+// "<parentID>#<index>", replacing any chunks previously stored under parentID
+// in the selected collection but keeping their creation time. The metadata is
+// attached to every chunk, merged with the chunk's own metadata, which takes
+// precedence. It returns the IDs of the stored chunks. When duplicate
+// rejection is configured, a near-duplicate of another document is not
+// stored and a *DuplicateError is returned.
+func (r *RAGSystem) AddChunks(ctx context.Context, parentID string, chunks []Chunk, metadata map[string]string) ([]string, error) {
+	vecs := make([][]float32, len(chunks))
+	embeddings := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		embedding, err := r.GenerateEmbedding(ctx, chunk.Text)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate embedding for chunk %d: %w", i, err)
+		}
+		vecs[i] = embedding
+		embeddings[i] = floatArrayToSQL(embedding)
+	}
+	if err := r.checkDuplicate(ctx, parentID, vecs); err != nil {
+		return nil, err
+	}
+
+	metaJSON := make([]any, len(chunks))
+	for i, chunk := range chunks {
+		meta := metadata
+		if len(chunk.Metadata) > 0 {
+			meta = make(map[string]string, len(metadata)+len(chunk.Metadata))
+			maps.Copy(meta, metadata)
+			maps.Copy(meta, chunk.Metadata)
+		}
+		if len(meta) == 0 {
+			continue
+		}
+		data, err := json.Marshal(meta)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode metadata: %w", err)
+		}
+		metaJSON[i] = string(data)
+	}
+
+	defer observeDB("insert", time.Now())
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var created sql.NullTime
+	err = tx.QueryRowContext(ctx, `SELECT min(created_at) FROM documents WHERE (id = ? OR parent_id = ?) AND `+collectionFilter,
+		parentID, parentID, r.Collection()).Scan(&created)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `DELETE FROM documents WHERE (id = ? OR parent_id = ?) AND `+collectionFilter,
+		parentID, parentID, r.Collection()); err != nil {
+		return nil, fmt.Errorf("failed to replace document: %w", err)
+	}
+
+	ids := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		ids[i] = fmt.Sprintf("%s#%d", parentID, i)
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO documents (id, content, embedding, parent_id, chunk_index, metadata, collection, created_at)
+			VALUES (?, ?, ?::FLOAT[], ?, ?, ?, ?, coalesce(?::TIMESTAMP, current_timestamp))
+		`, ids[i], chunk.Text, embeddings[i], parentID, i, metaJSON[i], r.Collection(), created)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert chunk %d: %w", i, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit chunks: %w", err)
+	}
+	r.log().Info("stored chunks", "document", parentID, "chunks", len(ids))
+	return ids, nil
+}
+
 // SearchResult holds a document returned by a similarity query along with its cosine similarity score.
+// ParentID and Metadata are set when the document is a chunk of an ingested file.
 type SearchResult struct {
-	ID      string
-	Content string
-	Score   float64
+	ID       string            `json:"id"`
+	Content  string            `json:"content"`
+	Score    float64           `json:"score"`
+	ParentID string            `json:"parent_id,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// Citation returns where a chunk came from, such as "spec.pdf p.42" or
+// "spec.pdf pp.42-43", or "" if it has no recorded source.
+func (r SearchResult) Citation() string {
+	source := r.Metadata["source"]
+	if source == "" {
+		return ""
+	}
+	cite := filepath.Base(source)
+	if pages := r.Metadata["pages"]; strings.Contains(pages, "-") {
+		cite += " pp." + pages
+	} else if pages != "" {
+		cite += " p." + pages
+	}
+	return cite
+}
+
+// resultLabel returns the ID of a search result followed by its citation in
+// parentheses, if it has one.
+func resultLabel(r SearchResult) string {
+	if cite := r.Citation(); cite != "" {
+		return fmt.Sprintf("%s (%s)", r.ID, cite)
+	}
+	return r.ID
 }
 
 // Query returns the topK documents most similar to queryText, ordered by descending cosine similarity.
-func (r *RAGSystem) Query(queryText string, topK int) ([]SearchResult, error) {
-	queryEmbedding, err := r.GenerateEmbedding(queryText)
+// When where is not empty, only documents whose metadata has every key in where set to its value
+// are searched.
+func (r *RAGSystem) Query(ctx context.Context, queryText string, topK int, where map[string]string) ([]SearchResult, error) {
+	queryEmbedding, err := r.GenerateEmbedding(ctx, queryText)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate query embedding: %w", err)
 	}
+	return r.search(ctx, queryEmbedding, topK, "", where)
+}
 
-	embeddingStr := floatArrayToSQL(queryEmbedding)
+// Similar returns the topK documents most similar to the stored document or
+// chunk with the given id, using its embedding as the query vector without
+// loading the model. When id names an ingested file, the query vector is the
+// normalized mean of its chunk embeddings. The source document, including all
+// chunks of the file it belongs to, is excluded from the results.
+func (r *RAGSystem) Similar(ctx context.Context, id string, topK int) ([]SearchResult, error) {
+	embedding, source, err := r.documentEmbedding(id)
+	if err != nil {
+		return nil, err
+	}
+	return r.search(ctx, embedding, topK, source, nil)
+}
+
+// documentEmbedding returns the query vector for Similar and the ID of the
+// document it belongs to: the document itself, or the parent of a chunk.
+func (r *RAGSystem) documentEmbedding(id string) ([]float32, string, error) {
+	if !r.hasTable() {
+		return nil, "", fmt.Errorf("document '%s' not found", id)
+	}
+	rows, err := r.db.Query(`
+		SELECT coalesce(parent_id, id), CAST(embedding AS VARCHAR)
+		FROM documents
+		WHERE (id = ? OR (parent_id = ? AND NOT EXISTS (SELECT 1 FROM documents WHERE id = ? AND `+collectionFilter+`)))
+			AND embedding IS NOT NULL AND `+collectionFilter+`
+	`, id, id, id, r.Collection(), r.Collection())
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read document: %w", err)
+	}
+	defer rows.Close()
+
+	var source string
+	var sum []float32
+	n := 0
+	for rows.Next() {
+		var data string
+		var vec []float32
+		if err := rows.Scan(&source, &data); err != nil {
+			return nil, "", fmt.Errorf("failed to scan embedding: %w", err)
+		}
+		if err := json.Unmarshal([]byte(data), &vec); err != nil {
+			return nil, "", fmt.Errorf("failed to decode embedding of %s: %w", id, err)
+		}
+		if sum == nil {
+			sum = make([]float32, len(vec))
+		}
+		for i, v := range vec {
+			sum[i] += v
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", fmt.Errorf("failed to read document: %w", err)
+	}
+	if n == 0 {
+		return nil, "", fmt.Errorf("document '%s' not found", id)
+	}
+	if n == 1 {
+		return sum, source, nil
+	}
+	return normalizeVector(sum), source, nil
+}
+
+// searchByVector returns the topK documents in the selected collection whose
+// embeddings are most similar to embedding, ordered by descending cosine
+// similarity.
+func (r *RAGSystem) searchByVector(ctx context.Context, embedding []float32, topK int) ([]SearchResult, error) {
+	return r.search(ctx, embedding, topK, "", nil)
+}
+
+// search is searchByVector leaving out the document exclude and its chunks
+// when exclude is not empty, and documents whose metadata does not match
+// where, as for Query.
+func (r *RAGSystem) search(ctx context.Context, embedding []float32, topK int, exclude string, where map[string]string) (results []SearchResult, err error) {
+	ctx, span := tracer().Start(ctx, "duckdb.search", trace.WithAttributes(
+		attribute.String("db.system", "duckdb"),
+		attribute.Int("top_k", topK),
+	))
+	defer func() { endSpan(span, err) }()
+
+	embeddingStr := floatArrayToSQL(embedding)
+	filter := collectionFilter
+	args := []any{r.Collection()}
+	if exclude != "" {
+		filter += " AND coalesce(parent_id, id) <> ?"
+		args = append(args, exclude)
+	}
+	for _, key := range slices.Sorted(maps.Keys(where)) {
+		filter += " AND json_extract_string(metadata, ?) = ?"
+		args = append(args, metadataPath(key), where[key])
+	}
 
 	query := fmt.Sprintf(`
 		SELECT 
 			id, 
 			content, 
-			array_cosine_similarity(embedding, %s::FLOAT[%d]) AS score
+			array_cosine_similarity(embedding, %s::FLOAT[%d]) AS score,
+			parent_id,
+			CAST(metadata AS VARCHAR)
 		FROM documents
-		WHERE embedding IS NOT NULL
+		WHERE embedding IS NOT NULL AND %s
 		ORDER BY score DESC
 		LIMIT ?
-	`, embeddingStr, r.embeddingDim)
+	`, embeddingStr, r.embeddingDim, filter)
 
-	rows, err := r.db.Query(query, topK)
+	defer observeDB("search", time.Now())
+	rows, err := r.db.QueryContext(ctx, query, append(args, topK)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query documents: %w", err)
 	}
 	defer rows.Close()
 
-	var results []SearchResult
 	for rows.Next() {
 		var result SearchResult
-		if err := rows.Scan(&result.ID, &result.Content, &result.Score); err != nil {
+		var parentID, metadata sql.NullString
+		if err := rows.Scan(&result.ID, &result.Content, &result.Score, &parentID, &metadata); err != nil {
 			return nil, fmt.Errorf("failed to scan result: %w", err)
 		}
+		result.ParentID = parentID.String
+		result.Metadata = decodeMetadata(metadata)
 		results = append(results, result)
 	}
 
+	span.SetAttributes(attribute.Int("results", len(results)))
+	r.log().Debug("query", "top_k", topK, "results", len(results))
 	return results, nil
 }
 
-// ListDocuments returns all documents in the database ordered by id.
+// metadataPath returns the JSON path of the metadata field key. The key is
+// quoted, so it may contain dots.
+func metadataPath(key string) string {
+	return `$."` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key) + `"`
+}
+
+// ListDocuments returns all documents in the selected collection ordered by id.
 func (r *RAGSystem) ListDocuments() ([]Document, error) {
-	rows, err := r.db.Query(`SELECT id, content FROM documents ORDER BY id`)
+	if !r.hasTable() {
+		return nil, nil
+	}
+	defer observeDB("list", time.Now())
+	rows, err := r.db.Query(`
+		SELECT id, content, parent_id, chunk_index, CAST(metadata AS VARCHAR)
+		FROM documents
+		WHERE `+collectionFilter+`
+		ORDER BY id
+	`, r.Collection())
 	if err != nil {
 		return nil, fmt.Errorf("failed to list documents: %w", err)
 	}
+	return r.scanDocuments(rows)
+}
+
+// GetDocument returns the document with the given id in the selected
+// collection or, when id names an ingested file, its chunks in order.
+func (r *RAGSystem) GetDocument(id string) ([]Document, error) {
+	if !r.hasTable() {
+		return nil, fmt.Errorf("document '%s' not found", id)
+	}
+	defer observeDB("get", time.Now())
+	rows, err := r.db.Query(`
+		SELECT id, content, parent_id, chunk_index, CAST(metadata AS VARCHAR)
+		FROM documents
+		WHERE (id = ? OR parent_id = ?) AND `+collectionFilter+`
+		ORDER BY COALESCE(chunk_index, 0), id
+	`, id, id, r.Collection())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+	docs, err := r.scanDocuments(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("document '%s' not found", id)
+	}
+	return docs, nil
+}
+
+// scanDocuments reads documents from rows selecting id, content, parent_id,
+// chunk_index, and metadata, and closes rows.
+func (r *RAGSystem) scanDocuments(rows *sql.Rows) ([]Document, error) {
 	defer rows.Close()
 
 	var docs []Document
 	for rows.Next() {
-		var doc Document
-		if err := rows.Scan(&doc.ID, &doc.Content); err != nil {
+		doc := Document{Collection: r.Collection()}
+		var parentID, metadata sql.NullString
+		var chunkIndex sql.NullInt64
+		if err := rows.Scan(&doc.ID, &doc.Content, &parentID, &chunkIndex, &metadata); err != nil {
 			return nil, fmt.Errorf("failed to scan document: %w", err)
 		}
+		doc.ParentID = parentID.String
+		doc.ChunkIndex = int(chunkIndex.Int64)
+		doc.Metadata = decodeMetadata(metadata)
 		docs = append(docs, doc)
 	}
-
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read documents: %w", err)
+	}
 	return docs, nil
 }
 
-// DeleteDocument removes the document with the given id from the database.
+// ExportDocuments calls fn with each document in the selected collection,
+// ordered by id, stopping at the first error fn returns. When withEmbeddings is
+// set, each document's Embedding is filled in.
+func (r *RAGSystem) ExportDocuments(withEmbeddings bool, fn func(Document) error) error {
+	if !r.hasTable() {
+		return nil
+	}
+	embeddingCol := "NULL"
+	if withEmbeddings {
+		embeddingCol = "CAST(embedding AS VARCHAR)"
+	}
+
+	defer observeDB("export", time.Now())
+	rows, err := r.db.Query(`
+		SELECT id, content, parent_id, chunk_index, CAST(metadata AS VARCHAR), `+embeddingCol+`
+		FROM documents
+		WHERE `+collectionFilter+`
+		ORDER BY id
+	`, r.Collection())
+	if err != nil {
+		return fmt.Errorf("failed to export documents: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		doc := Document{Collection: r.Collection()}
+		var parentID, metadata, embedding sql.NullString
+		var chunkIndex sql.NullInt64
+		if err := rows.Scan(&doc.ID, &doc.Content, &parentID, &chunkIndex, &metadata, &embedding); err != nil {
+			return fmt.Errorf("failed to scan document: %w", err)
+		}
+		doc.ParentID = parentID.String
+		doc.ChunkIndex = int(chunkIndex.Int64)
+		doc.Metadata = decodeMetadata(metadata)
+		if embedding.Valid {
+			if err := json.Unmarshal([]byte(embedding.String), &doc.Embedding); err != nil {
+				return fmt.Errorf("failed to decode embedding of %s: %w", doc.ID, err)
+			}
+		}
+		if err := fn(doc); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read documents: %w", err)
+	}
+	return nil
+}
+
+// DeleteDocument removes the document with the given id, along with any chunks
+// stored under it, from the selected collection.
 func (r *RAGSystem) DeleteDocument(id string) error {
-	result, err := r.db.Exec(`DELETE FROM documents WHERE id = ?`, id)
+	if !r.hasTable() {
+		return fmt.Errorf("document '%s' not found", id)
+	}
+	defer observeDB("delete", time.Now())
+	result, err := r.db.Exec(`DELETE FROM documents WHERE (id = ? OR parent_id = ?) AND `+collectionFilter, id, id, r.Collection())
 	if err != nil {
 		return fmt.Errorf("failed to delete document: %w", err)
 	}
@@ -246,9 +791,32 @@ func (r *RAGSystem) DeleteDocument(id string) error {
 	if affected == 0 {
 		return fmt.Errorf("document '%s' not found", id)
 	}
+	r.log().Debug("deleted document", "document", id, "rows", affected)
 	return nil
 }
 
+// log returns the RAG system's logger, falling back to the default logger
+// for systems not created by NewRAGSystem.
+func (r *RAGSystem) log() *slog.Logger {
+	if r.logger == nil {
+		return slog.Default()
+	}
+	return r.logger
+}
+
+// decodeMetadata parses a JSON metadata column value, returning nil when the
+// column is NULL or malformed.
+func decodeMetadata(ns sql.NullString) map[string]string {
+	if !ns.Valid || ns.String == "" {
+		return nil
+	}
+	var m map[string]string
+	if err := json.Unmarshal([]byte(ns.String), &m); err != nil {
+		return nil
+	}
+	return m
+}
+
 // floatArrayToSQL formats a float32 slice as a DuckDB array literal (e.g. "[1.0, 2.0]").
 func floatArrayToSQL(arr []float32) string {
 	var sb strings.Builder
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"context"
+	"database/sql"
 	"math"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -125,3 +130,395 @@ func TestTruncate_Empty(t *testing.T) {
 		t.Errorf("expected empty string, got %q", result)
 	}
 }
+
+// newTestRAG returns a RAGSystem backed by an in-memory DuckDB database with
+// 3-dimensional embeddings and no model loaded.
+func newTestRAG(t *testing.T) *RAGSystem {
+	t.Helper()
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	rag := &RAGSystem{db: db, embeddingDim: 3}
+	if err := rag.initDB(); err != nil {
+		t.Fatalf("initDB failed: %v", err)
+	}
+	return rag
+}
+
+// insertTestDoc stores a document row directly, bypassing embedding generation.
+func insertTestDoc(t *testing.T, rag *RAGSystem, id, content, parentID, metadata string, embedding []float32) {
+	t.Helper()
+	var parent, meta any
+	if parentID != "" {
+		parent = parentID
+	}
+	if metadata != "" {
+		meta = metadata
+	}
+	_, err := rag.db.Exec(`
+		INSERT INTO documents (id, content, embedding, parent_id, metadata)
+		VALUES (?, ?, ?::FLOAT[], ?, ?)
+	`, id, content, floatArrayToSQL(embedding), parent, meta)
+	if err != nil {
+		t.Fatalf("failed to insert %s: %v", id, err)
+	}
+}
+
+func TestInitDB_MigratesExistingTable(t *testing.T) {
+	db, err := sql.Open("duckdb", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(`CREATE TABLE documents (id VARCHAR PRIMARY KEY, content VARCHAR, embedding FLOAT[3])`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO documents VALUES ('old', 'legacy row', [1, 0, 0])`); err != nil {
+		t.Fatal(err)
+	}
+
+	rag := &RAGSystem{db: db, embeddingDim: 3}
+	if err := rag.initDB(); err != nil {
+		t.Fatalf("initDB failed on legacy table: %v", err)
+	}
+	// Running the migrations twice must be harmless.
+	if err := rag.initDB(); err != nil {
+		t.Fatalf("initDB failed on second run: %v", err)
+	}
+
+	docs, err := rag.ListDocuments()
+	if err != nil {
+		t.Fatalf("ListDocuments failed: %v", err)
+	}
+	if len(docs) != 1 || docs[0].ID != "old" || docs[0].ParentID != "" || docs[0].Metadata != nil {
+		t.Fatalf("unexpected documents after migration: %+v", docs)
+	}
+
+	// The table is now keyed by collection and id.
+	rag.SetCollection("notes")
+	if err := rag.insertDocument(context.Background(), "old", "same id, other collection", []float32{0, 1, 0}); err != nil {
+		t.Fatalf("insertDocument in another collection failed: %v", err)
+	}
+	rag.SetCollection("")
+	if docs, err := rag.GetDocument("old"); err != nil || len(docs) != 1 || docs[0].Content != "legacy row" {
+		t.Errorf("default collection after insert = %+v, %v", docs, err)
+	}
+}
+
+func TestListDocuments_ChunkFields(t *testing.T) {
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "file#0", "first chunk", "file", `{"source":"file.txt"}`, []float32{1, 0, 0})
+
+	docs, err := rag.ListDocuments()
+	if err != nil {
+		t.Fatalf("ListDocuments failed: %v", err)
+	}
+	if len(docs) != 1 {
+		t.Fatalf("expected 1 document, got %d", len(docs))
+	}
+	if docs[0].ParentID != "file" {
+		t.Errorf("ParentID = %q, want %q", docs[0].ParentID, "file")
+	}
+	if docs[0].Metadata["source"] != "file.txt" {
+		t.Errorf("Metadata[source] = %q, want %q", docs[0].Metadata["source"], "file.txt")
+	}
+}
+
+func TestDeleteDocument_RemovesChunks(t *testing.T) {
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "file#0", "first", "file", "", []float32{1, 0, 0})
+	insertTestDoc(t, rag, "file#1", "second", "file", "", []float32{0, 1, 0})
+	insertTestDoc(t, rag, "other", "unrelated", "", "", []float32{0, 0, 1})
+
+	if err := rag.DeleteDocument("file"); err != nil {
+		t.Fatalf("DeleteDocument failed: %v", err)
+	}
+
+	docs, err := rag.ListDocuments()
+	if err != nil {
+		t.Fatalf("ListDocuments failed: %v", err)
+	}
+	if len(docs) != 1 || docs[0].ID != "other" {
+		t.Fatalf("expected only 'other' to remain, got %+v", docs)
+	}
+
+	if err := rag.DeleteDocument("missing"); err == nil {
+		t.Error("expected error deleting a missing document")
+	}
+}
+
+func TestSearchByVector(t *testing.T) {
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "file#0", "chunk", "file", "", []float32{1, 0, 0})
+	insertTestDoc(t, rag, "note", "standalone", "", "", []float32{0.6, 0.8, 0})
+	insertTestDoc(t, rag, "far", "unrelated", "", "", []float32{0, 0, 1})
+
+	results, err := rag.searchByVector(context.Background(), []float32{1, 0, 0}, 2)
+	if err != nil {
+		t.Fatalf("searchByVector failed: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %d", len(results))
+	}
+	if results[0].ID != "file#0" || results[0].ParentID != "file" {
+		t.Errorf("first result = %+v, want file#0 with parent file", results[0])
+	}
+	if results[1].ID != "note" || results[1].ParentID != "" {
+		t.Errorf("second result = %+v, want note with no parent", results[1])
+	}
+	if results[0].Score < results[1].Score {
+		t.Errorf("results not ordered by score: %+v", results)
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "file#0", "chunk one", "file", "", []float32{1, 0, 0})
+	insertTestDoc(t, rag, "file#1", "chunk two", "file", "", []float32{0, 1, 0})
+	insertTestDoc(t, rag, "near", "close to x", "", "", []float32{0.9, 0.1, 0})
+	insertTestDoc(t, rag, "mid", "between x and y", "", "", []float32{0.7, 0.7, 0})
+	insertTestDoc(t, rag, "far", "unrelated", "", "", []float32{0, 0, 1})
+	ctx := context.Background()
+
+	results, err := rag.Similar(ctx, "near", 2)
+	if err != nil {
+		t.Fatalf("Similar failed: %v", err)
+	}
+	if len(results) != 2 || results[0].ID != "file#0" || results[1].ID != "mid" {
+		t.Errorf("Similar(near) = %+v, want file#0 then mid", results)
+	}
+
+	results, err = rag.Similar(ctx, "file#0", 5)
+	if err != nil {
+		t.Fatalf("Similar of a chunk failed: %v", err)
+	}
+	for _, r := range results {
+		if r.ParentID == "file" {
+			t.Errorf("chunk of the source file returned: %+v", r)
+		}
+	}
+	if len(results) != 3 || results[0].ID != "near" {
+		t.Errorf("Similar(file#0) = %+v, want near first", results)
+	}
+
+	results, err = rag.Similar(ctx, "file", 1)
+	if err != nil || len(results) != 1 || results[0].ID != "mid" || !approxEqual(results[0].Score, 1) {
+		t.Errorf("Similar(file) = %+v, %v, want mid matching the mean of its chunks", results, err)
+	}
+
+	if _, err := rag.Similar(ctx, "missing", 5); err == nil {
+		t.Error("expected error for a missing document")
+	}
+}
+
+func TestGetDocument(t *testing.T) {
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "file#1", "second", "file", `{"source":"a.txt"}`, []float32{1, 0, 0})
+	insertTestDoc(t, rag, "file#0", "first", "file", `{"source":"a.txt"}`, []float32{1, 0, 0})
+	if _, err := rag.db.Exec(`UPDATE documents SET chunk_index = CAST(right(id, 1) AS INTEGER)`); err != nil {
+		t.Fatal(err)
+	}
+	insertTestDoc(t, rag, "note", "standalone", "", "", []float32{0, 1, 0})
+
+	docs, err := rag.GetDocument("file")
+	if err != nil {
+		t.Fatalf("GetDocument failed: %v", err)
+	}
+	if len(docs) != 2 || docs[0].Content != "first" || docs[1].ChunkIndex != 1 || docs[1].Metadata["source"] != "a.txt" {
+		t.Errorf("chunks = %+v", docs)
+	}
+
+	docs, err = rag.GetDocument("note")
+	if err != nil || len(docs) != 1 || docs[0].Collection != defaultCollection {
+		t.Errorf("GetDocument(note) = %+v, %v", docs, err)
+	}
+
+	if _, err := rag.GetDocument("missing"); err == nil {
+		t.Error("expected error for a missing document")
+	}
+}
+
+func TestCollections(t *testing.T) {
+	rag := newTestRAG(t)
+	ctx := context.Background()
+	insertTestDoc(t, rag, "legacy", "stored before collections", "", "", []float32{1, 0, 0})
+	rag.SetCollection("notes")
+	if err := rag.insertDocument(ctx, "note", "a note", []float32{1, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+
+	docs, err := rag.ListDocuments()
+	if err != nil || len(docs) != 1 || docs[0].ID != "note" || docs[0].Collection != "notes" {
+		t.Fatalf("notes collection = %+v, %v", docs, err)
+	}
+	results, err := rag.searchByVector(ctx, []float32{1, 0, 0}, 5)
+	if err != nil || len(results) != 1 || results[0].ID != "note" {
+		t.Errorf("search in notes = %+v, %v", results, err)
+	}
+	if err := rag.DeleteDocument("legacy"); err == nil {
+		t.Error("deleted a document outside the selected collection")
+	}
+
+	rag.SetCollection("")
+	docs, err = rag.ListDocuments()
+	if err != nil || len(docs) != 1 || docs[0].ID != "legacy" {
+		t.Errorf("default collection = %+v, %v", docs, err)
+	}
+	if _, err := rag.GetDocument("note"); err == nil {
+		t.Error("got a document outside the selected collection")
+	}
+}
+
+func TestCollections_SameID(t *testing.T) {
+	rag := newTestRAG(t)
+	ctx := context.Background()
+	rag.SetCollection("a")
+	if err := rag.insertDocument(ctx, "x", "in a", []float32{1, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	rag.SetCollection("b")
+	if err := rag.insertDocument(ctx, "x", "in b", []float32{0, 1, 0}); err != nil {
+		t.Fatalf("insertDocument of the same id in another collection failed: %v", err)
+	}
+	if err := rag.insertDocument(ctx, "x", "in b again", []float32{0, 1, 0}); err != nil {
+		t.Fatalf("replacing a document failed: %v", err)
+	}
+
+	for collection, want := range map[string]string{"a": "in a", "b": "in b again"} {
+		rag.SetCollection(collection)
+		docs, err := rag.GetDocument("x")
+		if err != nil || len(docs) != 1 || docs[0].Content != want || docs[0].Collection != collection {
+			t.Errorf("collection %s: GetDocument(x) = %+v, %v, want content %q", collection, docs, err, want)
+		}
+	}
+
+	rag.SetCollection("a")
+	if err := rag.DeleteDocument("x"); err != nil {
+		t.Fatal(err)
+	}
+	rag.SetCollection("b")
+	if docs, err := rag.GetDocument("x"); err != nil || len(docs) != 1 {
+		t.Errorf("deleting x in a removed it from b: %+v, %v", docs, err)
+	}
+}
+
+func TestInsertDocument_ReplacesChunks(t *testing.T) {
+	rag := newTestRAG(t)
+	ctx := context.Background()
+	insertTestDoc(t, rag, "file#0", "old chunk", "file", "", []float32{1, 0, 0})
+	insertTestDoc(t, rag, "file#1", "old chunk", "file", "", []float32{1, 0, 0})
+	if _, err := rag.db.Exec(`
+		INSERT INTO documents (id, content, embedding, parent_id, collection)
+		VALUES ('file#0', 'other collection', [1, 0, 0], 'file', 'notes')
+	`); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := rag.insertDocument(ctx, "file", "replacement", []float32{0, 1, 0}); err != nil {
+		t.Fatal(err)
+	}
+	docs, err := rag.GetDocument("file")
+	if err != nil || len(docs) != 1 || docs[0].ID != "file" || docs[0].Content != "replacement" {
+		t.Errorf("GetDocument(file) = %+v, %v", docs, err)
+	}
+	results, err := rag.searchByVector(ctx, []float32{1, 0, 0}, 5)
+	if err != nil || len(results) != 1 || results[0].ID != "file" {
+		t.Errorf("old chunks still searchable: %+v, %v", results, err)
+	}
+
+	rag.SetCollection("notes")
+	if docs, err := rag.GetDocument("file"); err != nil || len(docs) != 1 || docs[0].ID != "file#0" {
+		t.Errorf("chunks in another collection were deleted: %+v, %v", docs, err)
+	}
+}
+
+func TestSearch_MetadataFilter(t *testing.T) {
+	rag := newTestRAG(t)
+	ctx := context.Background()
+	insertTestDoc(t, rag, "m1", "release plan", "", `{"from": "Alice <alice@example.com>", "thread": "t1"}`, []float32{1, 0, 0})
+	insertTestDoc(t, rag, "m2", "re: release plan", "", `{"from": "Bob <bob@example.com>", "thread": "t1"}`, []float32{0.9, 0.1, 0})
+	insertTestDoc(t, rag, "m3", "lunch", "", `{"from": "Alice <alice@example.com>", "thread": "t2", "x.y": "dotted"}`, []float32{0, 1, 0})
+	insertTestDoc(t, rag, "plain", "no metadata", "", "", []float32{1, 0, 0})
+
+	ids := func(where map[string]string) []string {
+		t.Helper()
+		results, err := rag.search(ctx, []float32{1, 0, 0}, 5, "", where)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, r := range results {
+			ids = append(ids, r.ID)
+		}
+		return ids
+	}
+	if got, want := ids(map[string]string{"from": "Alice <alice@example.com>"}), []string{"m1", "m3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("from = alice: %q, want %q", got, want)
+	}
+	if got, want := ids(map[string]string{"from": "Alice <alice@example.com>", "thread": "t1"}), []string{"m1"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("from = alice, thread = t1: %q, want %q", got, want)
+	}
+	if got, want := ids(map[string]string{"x.y": "dotted"}), []string{"m3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("x.y = dotted: %q, want %q", got, want)
+	}
+	if got := ids(map[string]string{"thread": "t3"}); len(got) != 0 {
+		t.Errorf("thread = t3: %q, want none", got)
+	}
+	if got := ids(nil); len(got) != 4 {
+		t.Errorf("no filter: %q, want all 4", got)
+	}
+}
+
+func TestNewRAGSystem_WithoutModel(t *testing.T) {
+	rag, err := NewRAGSystem("", "", "")
+	if err != nil {
+		t.Fatalf("NewRAGSystem failed: %v", err)
+	}
+	defer rag.Close()
+
+	docs, err := rag.ListDocuments()
+	if err != nil || len(docs) != 0 {
+		t.Errorf("ListDocuments on a new database = %v, %v", docs, err)
+	}
+	if err := rag.DeleteDocument("doc"); err == nil {
+		t.Error("expected error deleting from an empty database")
+	}
+	if _, err := rag.GenerateEmbedding(context.Background(), "text"); err == nil || !strings.Contains(err.Error(), "no embedding model") {
+		t.Errorf("GenerateEmbedding without a model: %v", err)
+	}
+	if rag.ModelLoaded() {
+		t.Error("ModelLoaded reported true without a model")
+	}
+}
+
+func TestNewRAGSystem_ExistingDatabase(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "kb.db")
+	db, err := sql.Open("duckdb", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	setup := &RAGSystem{db: db, embeddingDim: 3}
+	if err := setup.initDB(); err != nil {
+		t.Fatal(err)
+	}
+	if err := setup.insertDocument(context.Background(), "doc", "stored", []float32{1, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	rag, err := NewRAGSystem("", "", path)
+	if err != nil {
+		t.Fatalf("NewRAGSystem failed: %v", err)
+	}
+	defer rag.Close()
+	if rag.embeddingDim != 3 {
+		t.Errorf("embeddingDim = %d, want 3 from the stored table", rag.embeddingDim)
+	}
+	if err := rag.DeleteDocument("doc"); err != nil {
+		t.Errorf("DeleteDocument without a model failed: %v", err)
+	}
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// EPUBChapter is the text of one spine document of an EPUB book. Text is
+// Markdown-style, as in HTMLDocument.
+type EPUBChapter struct {
+	Number int // position among the chapters read, from 1
+	Title  string
+	Text   string
+}
+
+// EPUBBook is the text of an EPUB file, chapter by chapter in reading order.
+type EPUBBook struct {
+	Title    string
+	Author   string
+	Language string
+	Chapters []EPUBChapter
+}
+
+func init() {
+	registerFormat(&fileFormat{
+		name:       "EPUB",
+		extensions: []string{".epub"},
+		mimeTypes:  []string{"application/epub+zip"},
+		sniff: func(data []byte) bool {
+			// Like ODF, an EPUB starts with an uncompressed "mimetype" member.
+			return bytes.Contains(data[:min(len(data), 128)], []byte("mimetypeapplication/epub+zip"))
+		},
+		read: func(data []byte) ([]section, map[string]string, error) {
+			book, err := ReadEPUBBytes(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			return book.sections(), book.metadata(), nil
+		},
+	})
+}
+
+// ReadEPUBBytes extracts the text of each chapter of an EPUB book by walking
+// its spine in order. Chapter titles come from the table of contents (the
+// EPUB 3 navigation document, else the EPUB 2 NCX), falling back to the
+// chapter's first heading. Chapters are rendered like HTML pages, except that
+// headers and footers are kept. Spine items marked linear="no" and the
+// navigation document itself are skipped.
+func ReadEPUBBytes(data []byte) (EPUBBook, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return EPUBBook{}, err
+	}
+	container, err := readZipEntry(zr, "META-INF/container.xml")
+	if err != nil {
+		return EPUBBook{}, err
+	}
+	var c struct {
+		Rootfiles []struct {
+			Path      string `xml:"full-path,attr"`
+			MediaType string `xml:"media-type,attr"`
+		} `xml:"rootfiles>rootfile"`
+	}
+	if err := xml.Unmarshal(container, &c); err != nil {
+		return EPUBBook{}, fmt.Errorf("invalid META-INF/container.xml: %w", err)
+	}
+	opfPath := ""
+	for _, r := range c.Rootfiles {
+		if r.MediaType == "" || r.MediaType == "application/oebps-package+xml" {
+			opfPath = r.Path
+			break
+		}
+	}
+	if opfPath == "" {
+		return EPUBBook{}, fmt.Errorf("no package document in META-INF/container.xml")
+	}
+
+	opf, err := readZipEntry(zr, opfPath)
+	if err != nil {
+		return EPUBBook{}, err
+	}
+	var pkg struct {
+		Titles    []string `xml:"metadata>title"`
+		Creators  []string `xml:"metadata>creator"`
+		Languages []string `xml:"metadata>language"`
+		Items     []struct {
+			ID         string `xml:"id,attr"`
+			Href       string `xml:"href,attr"`
+			MediaType  string `xml:"media-type,attr"`
+			Properties string `xml:"properties,attr"`
+		} `xml:"manifest>item"`
+		Spine struct {
+			TOC      string `xml:"toc,attr"`
+			Itemrefs []struct {
+				IDRef  string `xml:"idref,attr"`
+				Linear string `xml:"linear,attr"`
+			} `xml:"itemref"`
+		} `xml:"spine"`
+	}
+	if err := xml.Unmarshal(opf, &pkg); err != nil {
+		return EPUBBook{}, fmt.Errorf("invalid %s: %w", opfPath, err)
+	}
+
+	var book EPUBBook
+	first := func(values []string) string {
+		if len(values) == 0 {
+			return ""
+		}
+		return collapseSpace(values[0])
+	}
+	book.Title = first(pkg.Titles)
+	book.Author = first(pkg.Creators)
+	book.Language = first(pkg.Languages)
+
+	// Manifest hrefs are relative to the package document.
+	dir := path.Dir(opfPath)
+	hrefs := make(map[string]string, len(pkg.Items))
+	nav, ncx := "", ""
+	for _, item := range pkg.Items {
+		name := resolveHref(dir, item.Href)
+		hrefs[item.ID] = name
+		if slices.Contains(strings.Fields(item.Properties), "nav") {
+			nav = name
+		}
+		if item.ID == pkg.Spine.TOC || ncx == "" && item.MediaType == "application/x-dtbncx+xml" {
+			ncx = name
+		}
+	}
+
+	var titles map[string]string
+	if nav != "" {
+		if titles, err = readNavTitles(zr, nav); err != nil {
+			return EPUBBook{}, err
+		}
+	}
+	if len(titles) == 0 && ncx != "" {
+		if titles, err = readNCXTitles(zr, ncx); err != nil {
+			return EPUBBook{}, err
+		}
+	}
+
+	for _, ref := range pkg.Spine.Itemrefs {
+		name, ok := hrefs[ref.IDRef]
+		if !ok {
+			return EPUBBook{}, fmt.Errorf("spine item %q is not in the manifest", ref.IDRef)
+		}
+		if ref.Linear == "no" || name == nav {
+			continue
+		}
+		content, err := readZipEntry(zr, name)
+		if err != nil {
+			return EPUBBook{}, err
+		}
+		// EPUB content documents are UTF-8 or UTF-16 with a byte order mark,
+		// and hold no page chrome.
+		doc, err := renderHTML(content, "application/xhtml+xml; charset=utf-8", true)
+		if err != nil {
+			return EPUBBook{}, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if strings.TrimSpace(doc.Text) == "" {
+			continue
+		}
+		title := titles[name]
+		if title == "" {
+			title = firstHeading(doc.Text)
+		}
+		book.Chapters = append(book.Chapters, EPUBChapter{
+			Number: len(book.Chapters) + 1,
+			Title:  title,
+			Text:   doc.Text,
+		})
+	}
+	return book, nil
+}
+
+// sections splits each chapter like Markdown and leads the heading path of
+// each section with the chapter title. A top-level heading that the title
+// ends with, such as "Deploys" in a chapter titled "2. Deploys", is replaced
+// by the title rather than repeated. Every section records its "chapter"
+// title and "chapter_number".
+func (b EPUBBook) sections() []section {
+	var sections []section
+	for _, ch := range b.Chapters {
+		for _, s := range splitMarkdown(ch.Text) {
+			if ch.Title != "" {
+				top, rest, nested := strings.Cut(s.title, " > ")
+				switch {
+				case s.title == "":
+					s.title = ch.Title
+				case strings.HasSuffix(ch.Title, top) && nested:
+					s.title = ch.Title + " > " + rest
+				case strings.HasSuffix(ch.Title, top):
+					s.title = ch.Title
+				default:
+					s.title = ch.Title + " > " + s.title
+				}
+			}
+			meta := maps.Clone(s.metadata)
+			if meta == nil {
+				meta = make(map[string]string)
+			}
+			if ch.Title != "" {
+				meta["chapter"] = ch.Title
+			}
+			meta["chapter_number"] = strconv.Itoa(ch.Number)
+			s.metadata = meta
+			sections = append(sections, s)
+		}
+	}
+	return sections
+}
+
+// metadata returns the non-empty "title", "author", and "language" of the
+// book.
+func (b EPUBBook) metadata() map[string]string {
+	m := make(map[string]string)
+	if b.Title != "" {
+		m["title"] = b.Title
+	}
+	if b.Author != "" {
+		m["author"] = b.Author
+	}
+	if b.Language != "" {
+		m["language"] = b.Language
+	}
+	return m
+}
+
+// readNavTitles returns the entries of the "toc" nav of an EPUB 3 navigation
+// document as titles keyed by archive member name. A document linked more
+// than once takes the title of its first entry. A missing navigation
+// document, or one without a "toc" nav, has no titles.
+func readNavTitles(zr *zip.Reader, name string) (map[string]string, error) {
+	data, err := readZipEntry(zr, name)
+	if errors.Is(err, errMissingEntry) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	root, err := html.Parse(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	var toc *html.Node
+	var find func(*html.Node)
+	find = func(n *html.Node) {
+		if toc != nil {
+			return
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Nav &&
+			slices.Contains(strings.Fields(attr(n, "epub:type")), "toc") {
+			toc = n
+			return
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			find(c)
+		}
+	}
+	find(root)
+	if toc == nil {
+		return nil, nil
+	}
+
+	titles := make(map[string]string)
+	dir := path.Dir(name)
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode && n.DataAtom == atom.A && attr(n, "href") != "" {
+			target := resolveHref(dir, attr(n, "href"))
+			if title := collapseSpace(textContent(n)); title != "" && titles[target] == "" {
+				titles[target] = title
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(toc)
+	return titles, nil
+}
+
+// ncxPoint is a navigation point of an EPUB 2 NCX table of contents.
+type ncxPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	Points []ncxPoint `xml:"navPoint"`
+}
+
+// readNCXTitles returns the navigation points of an EPUB 2 NCX file as
+// titles keyed by archive member name, like readNavTitles.
+func readNCXTitles(zr *zip.Reader, name string) (map[string]string, error) {
+	data, err := readZipEntry(zr, name)
+	if errors.Is(err, errMissingEntry) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ncx struct {
+		Points []ncxPoint `xml:"navMap>navPoint"`
+	}
+	if err := xml.Unmarshal(data, &ncx); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", name, err)
+	}
+
+	titles := make(map[string]string)
+	dir := path.Dir(name)
+	var walk func([]ncxPoint)
+	walk = func(points []ncxPoint) {
+		for _, p := range points {
+			target := resolveHref(dir, p.Content.Src)
+			if title := collapseSpace(p.Label); title != "" && titles[target] == "" {
+				titles[target] = title
+			}
+			walk(p.Points)
+		}
+	}
+	walk(ncx.Points)
+	return titles, nil
+}
+
+// resolveHref returns the archive member name of href, a URL relative to the
+// directory dir, with any fragment removed.
+func resolveHref(dir, href string) string {
+	href, _, _ = strings.Cut(href, "#")
+	if p, err := url.PathUnescape(href); err == nil {
+		href = p
+	}
+	return path.Join(dir, href)
+}
+
+// firstHeading returns the text of the first Markdown heading in text.
+func firstHeading(text string) string {
+	for line := range strings.SplitSeq(text, "\n") {
+		if h, ok := strings.CutPrefix(line, "#"); ok {
+			return strings.TrimSpace(strings.TrimLeft(h, "#"))
+		}
+	}
+	return ""
+}
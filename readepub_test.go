@@ -0,0 +1,152 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testContainerXML = `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+<rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles></container>`
+
+// epubChapter returns an XHTML spine document with the given body.
+func epubChapter(body string) string {
+	return `<?xml version="1.0" encoding="UTF-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops">
+<head><title>Handbook</title></head><body>` + body + `</body></html>`
+}
+
+// testEPUB returns an EPUB 3 book whose spine lists the chapters out of
+// archive order, with a cover excluded from the reading order and a chapter
+// missing from the navigation document.
+func testEPUB(t *testing.T) []byte {
+	opf := `<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:title>Ops Handbook</dc:title><dc:creator>Platform Team</dc:creator><dc:language>en</dc:language>
+</metadata>
+<manifest>
+<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+<item id="cover" href="cover.xhtml" media-type="application/xhtml+xml"/>
+<item id="c1" href="text/on%20call.xhtml" media-type="application/xhtml+xml"/>
+<item id="c2" href="text/deploys.xhtml" media-type="application/xhtml+xml"/>
+<item id="c3" href="text/appendix.xhtml" media-type="application/xhtml+xml"/>
+</manifest>
+<spine><itemref idref="cover" linear="no"/><itemref idref="nav"/><itemref idref="c1"/><itemref idref="c2"/><itemref idref="c3"/></spine>
+</package>`
+	nav := epubChapter(`<nav epub:type="toc"><ol>
+<li><a href="text/on%20call.xhtml">1. Being On Call</a></li>
+<li><a href="text/deploys.xhtml#top">2. Deploys</a><ol><li><a href="text/deploys.xhtml#rollback">Rollback</a></li></ol></li>
+</ol></nav>`)
+	files := map[string]string{
+		"mimetype":               "application/epub+zip",
+		"META-INF/container.xml": testContainerXML,
+		"OEBPS/content.opf":      opf,
+		"OEBPS/nav.xhtml":        nav,
+		"OEBPS/cover.xhtml":      epubChapter(`<p>Cover</p>`),
+		"OEBPS/text/on call.xhtml": epubChapter(`<h1>Being On Call</h1>
+<p>Acknowledge pages within five minutes.</p>`),
+		"OEBPS/text/deploys.xhtml": epubChapter(`<section epub:type="chapter"><header><h1 id="top">Deploys</h1></header><p>Deploy on weekdays.</p>
+<h2 id="rollback">Rollback</h2><p>Revert the release tag.</p></section>`),
+		"OEBPS/text/appendix.xhtml": epubChapter(`<header><h1>Glossary</h1></header><p>SLO: service level objective – ünïcode.</p>`),
+	}
+	names := []string{"mimetype", "META-INF/container.xml", "OEBPS/content.opf", "OEBPS/nav.xhtml",
+		"OEBPS/cover.xhtml", "OEBPS/text/appendix.xhtml", "OEBPS/text/deploys.xhtml", "OEBPS/text/on call.xhtml"}
+	return buildZip(t, names, files)
+}
+
+func TestReadEPUBBytes(t *testing.T) {
+	book, err := ReadEPUBBytes(testEPUB(t))
+	if err != nil {
+		t.Fatalf("ReadEPUBBytes failed: %v", err)
+	}
+	if book.Title != "Ops Handbook" || book.Author != "Platform Team" || book.Language != "en" {
+		t.Errorf("book = %+v", book)
+	}
+	var titles []string
+	for _, ch := range book.Chapters {
+		titles = append(titles, ch.Title)
+	}
+	if want := []string{"1. Being On Call", "2. Deploys", "Glossary"}; !reflect.DeepEqual(titles, want) {
+		t.Errorf("chapter titles = %q, want %q", titles, want)
+	}
+	if len(book.Chapters) == 3 && !strings.Contains(book.Chapters[0].Text, "five minutes") {
+		t.Errorf("chapter 1 text = %q", book.Chapters[0].Text)
+	}
+	// Headings inside <header> are kept, and text is read as UTF-8.
+	if want := "# Glossary\n\nSLO: service level objective – ünïcode."; len(book.Chapters) == 3 && book.Chapters[2].Text != want {
+		t.Errorf("chapter 3 text = %q, want %q", book.Chapters[2].Text, want)
+	}
+}
+
+func TestReadEPUBBytes_NCX(t *testing.T) {
+	opf := `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="2.0">
+<metadata xmlns:dc="http://purl.org/dc/elements/1.1/"><dc:title>Vendor Guide</dc:title></metadata>
+<manifest>
+<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>
+<item id="a" href="a.html" media-type="application/xhtml+xml"/>
+</manifest>
+<spine toc="ncx"><itemref idref="a"/></spine>
+</package>`
+	ncx := `<?xml version="1.0"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1"><navMap>
+<navPoint id="p1"><navLabel><text>Installation</text></navLabel><content src="a.html"/></navPoint>
+</navMap></ncx>`
+	files := map[string]string{
+		"mimetype":               "application/epub+zip",
+		"META-INF/container.xml": strings.Replace(testContainerXML, "OEBPS/content.opf", "content.opf", 1),
+		"content.opf":            opf,
+		"toc.ncx":                ncx,
+		"a.html":                 epubChapter(`<p>Run the installer.</p>`),
+	}
+	data := buildZip(t, []string{"mimetype", "META-INF/container.xml", "content.opf", "toc.ncx", "a.html"}, files)
+
+	book, err := ReadEPUBBytes(data)
+	if err != nil {
+		t.Fatalf("ReadEPUBBytes failed: %v", err)
+	}
+	want := []EPUBChapter{{Number: 1, Title: "Installation", Text: "Run the installer."}}
+	if book.Title != "Vendor Guide" || !reflect.DeepEqual(book.Chapters, want) {
+		t.Errorf("book = %+v", book)
+	}
+}
+
+func TestEPUBSections(t *testing.T) {
+	data := testEPUB(t)
+	if f := formatFor("upload", "", data); f == nil || f.name != "EPUB" {
+		t.Fatalf("sniffed format = %+v, want EPUB", f)
+	}
+	sections, metadata, err := extractSections("handbook.epub", "", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata["title"] != "Ops Handbook" || metadata["author"] != "Platform Team" || metadata["language"] != "en" {
+		t.Errorf("metadata = %v", metadata)
+	}
+	type got struct{ title, chapter, number string }
+	var gots []got
+	for _, s := range sections {
+		gots = append(gots, got{s.title, s.metadata["chapter"], s.metadata["chapter_number"]})
+	}
+	want := []got{
+		{"1. Being On Call", "1. Being On Call", "1"},
+		{"2. Deploys", "2. Deploys", "2"},
+		{"2. Deploys > Rollback", "2. Deploys", "2"},
+		{"Glossary", "Glossary", "3"},
+	}
+	if !reflect.DeepEqual(gots, want) {
+		t.Errorf("sections = %+v, want %+v", gots, want)
+	}
+}
+
+func TestReadEPUBBytes_Errors(t *testing.T) {
+	files := map[string]string{"mimetype": "application/epub+zip"}
+	if _, err := ReadEPUBBytes(buildZip(t, []string{"mimetype"}, files)); err == nil || !strings.Contains(err.Error(), "container.xml") {
+		t.Errorf("missing container: err = %v", err)
+	}
+	if _, err := ReadEPUBBytes([]byte("not a zip")); err == nil {
+		t.Error("expected error for non-ZIP data")
+	}
+}
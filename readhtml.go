@@ -0,0 +1,393 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+	"golang.org/x/net/html/charset"
+)
+
+// HTMLDocument is the readable content of an HTML page.
+type HTMLDocument struct {
+	Title       string
+	Description string
+	// Text is the page body as Markdown-style text: headings start with "#",
+	// list items with "-" or "1.", table rows are "| a | b |", and
+	// preformatted text is fenced with "```".
+	Text string
+}
+
+// ReadHTML extracts the readable content of the HTML file at the given path.
+func ReadHTML(path string) (HTMLDocument, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return HTMLDocument{}, err
+	}
+	return ReadHTMLBytes(data)
+}
+
+// ReadHTMLBytes extracts the readable content of HTML data held in memory,
+// decoding it from the charset declared in the page. Scripts, styles, forms,
+// navigation, hidden elements, and headers and footers outside the main
+// content or an article are dropped. When the page marks its main content
+// with <main>, role="main", or a single <article>, only that content is kept.
+func ReadHTMLBytes(data []byte) (HTMLDocument, error) {
+	return readHTML(data, "text/html")
+}
+
+// readHTML reads HTML data like ReadHTMLBytes, decoding it from the charset
+// given in contentType if there is one, and otherwise from the charset
+// declared in the page.
+func readHTML(data []byte, contentType string) (HTMLDocument, error) {
+	return renderHTML(data, contentType, false)
+}
+
+// renderHTML reads HTML data like readHTML. When whole is set, the whole body
+// counts as main content, so headers and footers are kept everywhere; this
+// suits documents without page chrome, such as ebook chapters.
+func renderHTML(data []byte, contentType string, whole bool) (HTMLDocument, error) {
+	r, err := charset.NewReader(bytes.NewReader(data), contentType)
+	if err != nil {
+		return HTMLDocument{}, err
+	}
+	root, err := html.Parse(r)
+	if err != nil {
+		return HTMLDocument{}, err
+	}
+
+	var doc HTMLDocument
+	readHTMLHead(root, &doc)
+
+	body := mainContent(root)
+	if body == nil {
+		body = root
+	}
+	w := &htmlWriter{}
+	if whole {
+		w.content = 1
+	}
+	w.node(body)
+	doc.Text = w.text()
+	return doc, nil
+}
+
+// readHTMLHead fills in the document title and description from <title> and
+// the description meta tags.
+func readHTMLHead(n *html.Node, doc *HTMLDocument) {
+	switch {
+	case n.Type == html.ElementNode && n.DataAtom == atom.Title && doc.Title == "":
+		doc.Title = collapseSpace(textContent(n))
+	case n.Type == html.ElementNode && n.DataAtom == atom.Meta:
+		name := strings.ToLower(attr(n, "name") + attr(n, "property"))
+		if (name == "description" || name == "og:description") && doc.Description == "" {
+			doc.Description = collapseSpace(attr(n, "content"))
+		}
+	case n.Type == html.ElementNode && n.DataAtom == atom.Body:
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		readHTMLHead(c, doc)
+	}
+}
+
+// mainContent returns the element holding the page's main content, or nil if
+// the page does not mark one.
+func mainContent(root *html.Node) *html.Node {
+	var main *html.Node
+	var articles []*html.Node
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			if main == nil && (n.DataAtom == atom.Main || attr(n, "role") == "main") {
+				main = n
+				return
+			}
+			if n.DataAtom == atom.Article {
+				articles = append(articles, n)
+				return
+			}
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(root)
+	if main != nil {
+		return main
+	}
+	if len(articles) == 1 {
+		return articles[0]
+	}
+	return nil
+}
+
+// skippedElements are dropped from the extracted text with their content.
+var skippedElements = map[atom.Atom]bool{
+	atom.Head: true, atom.Script: true, atom.Style: true, atom.Noscript: true,
+	atom.Template: true, atom.Svg: true, atom.Canvas: true, atom.Iframe: true,
+	atom.Nav: true, atom.Aside: true,
+	atom.Form: true, atom.Button: true, atom.Select: true, atom.Textarea: true,
+}
+
+// chromeElements are dropped with their content outside the main content and
+// articles, where they hold the page's banner and footer. Inside, they hold
+// the article's own heading and byline, which are kept.
+var chromeElements = map[atom.Atom]bool{atom.Header: true, atom.Footer: true}
+
+// skippedRoles are ARIA roles of navigation and page chrome.
+var skippedRoles = map[string]bool{
+	"navigation": true, "banner": true, "contentinfo": true, "complementary": true, "search": true,
+}
+
+// blockElements start on a new paragraph.
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true,
+	atom.Blockquote: true, atom.Figure: true, atom.Figcaption: true, atom.Dl: true,
+	atom.Dt: true, atom.Dd: true, atom.Address: true, atom.Details: true, atom.Summary: true,
+	atom.Hr: true,
+}
+
+// htmlWriter renders an HTML tree as Markdown-style text.
+type htmlWriter struct {
+	b     strings.Builder
+	space bool  // a collapsed space is pending before the next word
+	lists []int // item counters of the enclosing lists; -1 for unordered
+	cell  bool  // inside a table cell, where blocks are joined by spaces
+
+	content int // depth of enclosing <main>, role="main", and <article> elements
+}
+
+// node writes n and its descendants.
+func (w *htmlWriter) node(n *html.Node) {
+	switch n.Type {
+	case html.TextNode:
+		w.textNode(n.Data)
+		return
+	case html.ElementNode:
+	case html.DocumentNode:
+		w.children(n)
+		return
+	default:
+		return
+	}
+
+	if skippedElements[n.DataAtom] || skippedRoles[attr(n, "role")] ||
+		hasAttr(n, "hidden") || attr(n, "aria-hidden") == "true" ||
+		chromeElements[n.DataAtom] && w.content == 0 {
+		return
+	}
+	if n.DataAtom == atom.Main || n.DataAtom == atom.Article || attr(n, "role") == "main" {
+		w.content++
+		defer func() { w.content-- }()
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		title := collapseSpace(textContent(n))
+		if title == "" {
+			return
+		}
+		if w.cell {
+			w.word(title)
+			return
+		}
+		w.paragraph()
+		w.b.WriteString(strings.Repeat("#", level) + " " + title)
+		w.paragraph()
+	case atom.Br:
+		if w.cell {
+			w.space = true
+		} else {
+			w.line()
+		}
+	case atom.Pre:
+		if w.cell {
+			w.word(collapseSpace(textContent(n)))
+			return
+		}
+		w.paragraph()
+		w.b.WriteString("```\n" + strings.Trim(textContent(n), "\n") + "\n```")
+		w.paragraph()
+	case atom.Ul, atom.Ol:
+		counter := -1
+		if n.DataAtom == atom.Ol {
+			counter = 0
+		}
+		if len(w.lists) == 0 {
+			w.paragraph()
+		}
+		w.lists = append(w.lists, counter)
+		w.children(n)
+		w.lists = w.lists[:len(w.lists)-1]
+		if len(w.lists) == 0 {
+			w.paragraph()
+		}
+	case atom.Li:
+		w.line()
+		depth := max(len(w.lists)-1, 0)
+		w.b.WriteString(strings.Repeat("  ", depth))
+		marker := "- "
+		if len(w.lists) > 0 && w.lists[len(w.lists)-1] >= 0 {
+			w.lists[len(w.lists)-1]++
+			marker = fmt.Sprintf("%d. ", w.lists[len(w.lists)-1])
+		}
+		w.b.WriteString(marker)
+		w.space = false
+		w.children(n)
+	case atom.Table:
+		w.paragraph()
+		w.children(n)
+		w.paragraph()
+	case atom.Tr:
+		w.line()
+		w.b.WriteString("|")
+		w.children(n)
+	case atom.Td, atom.Th:
+		w.b.WriteString(" ")
+		w.space = false
+		w.cell = true
+		w.children(n)
+		w.cell = false
+		w.b.WriteString(" |")
+	default:
+		if blockElements[n.DataAtom] {
+			if w.cell {
+				w.space = true
+				w.children(n)
+				w.space = true
+				return
+			}
+			w.paragraph()
+			w.children(n)
+			w.paragraph()
+			return
+		}
+		w.children(n)
+	}
+}
+
+// children writes the children of n.
+func (w *htmlWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.node(c)
+	}
+}
+
+// textNode writes text with runs of whitespace collapsed to single spaces.
+func (w *htmlWriter) textNode(s string) {
+	if s == "" {
+		return
+	}
+	if strings.TrimSpace(s) == "" {
+		w.space = true
+		return
+	}
+	if isHTMLSpace(s[0]) {
+		w.space = true
+	}
+	fields := strings.Fields(s)
+	for i, f := range fields {
+		if i > 0 {
+			w.space = true
+		}
+		w.word(f)
+	}
+	if isHTMLSpace(s[len(s)-1]) {
+		w.space = true
+	}
+}
+
+// word writes s, preceded by a space if one is pending. Words are written
+// without trailing spaces, so output ending in a newline or space is at the
+// start of a line, list item, or table cell, where the space is dropped.
+func (w *htmlWriter) word(s string) {
+	out := w.b.String()
+	if w.space && out != "" && !strings.HasSuffix(out, "\n") && !strings.HasSuffix(out, " ") {
+		w.b.WriteByte(' ')
+	}
+	w.space = false
+	w.b.WriteString(s)
+}
+
+// line ends the current line unless the output is already at a line start.
+func (w *htmlWriter) line() {
+	w.space = false
+	if out := w.b.String(); out != "" && !strings.HasSuffix(out, "\n") {
+		w.b.WriteByte('\n')
+	}
+}
+
+// paragraph ends the current paragraph with a blank line.
+func (w *htmlWriter) paragraph() {
+	w.line()
+	if out := w.b.String(); out != "" && !strings.HasSuffix(out, "\n\n") {
+		w.b.WriteByte('\n')
+	}
+}
+
+var blankLines = regexp.MustCompile(`\n[ \t]*\n(?:[ \t]*\n)+`)
+
+// text returns the written text with trailing spaces and repeated blank
+// lines removed.
+func (w *htmlWriter) text() string {
+	lines := strings.Split(w.b.String(), "\n")
+	for i, l := range lines {
+		lines[i] = strings.TrimRight(l, " \t")
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(lines, "\n"), "\n\n"))
+}
+
+// textContent returns the concatenated text of n's descendants.
+func textContent(n *html.Node) string {
+	var b strings.Builder
+	var walk func(*html.Node)
+	walk = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		if n.Type == html.ElementNode && n.DataAtom == atom.Br {
+			b.WriteByte('\n')
+		}
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			walk(c)
+		}
+	}
+	walk(n)
+	return b.String()
+}
+
+// attr returns the value of n's attribute key, or "" if it is not set.
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// hasAttr reports whether n has the attribute key.
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// collapseSpace trims s and replaces runs of whitespace with single spaces.
+func collapseSpace(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// isHTMLSpace reports whether c is HTML whitespace.
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const testHTML = `<!DOCTYPE html>
+<html><head><meta charset="iso-8859-1"><title> Team   Wiki: Setup </title>
+<meta name="description" content="How to set up the service.">
+<style>body { color: red }</style><script>var tracking = 1;</script></head>
+<body>
+<nav><a href="/">Home</a> | <a href="/about">About</a></nav>
+<header>Site header</header>
+<div id="content">
+<h1>Setup</h1>
+<p>Install the <b>service</b> and
+   configure it at the caf` + "\xe9" + `.</p>
+<h2>Steps</h2>
+<ol><li>Download</li><li>Run <code>make</code>
+<ul><li>on Linux</li><li>on macOS</li></ul></li></ol>
+<table><tr><th>Key</th><th>Value</th></tr><tr><td>port</td><td><p>8080</p><p>default</p></td></tr></table>
+<pre>
+func main() {
+    run()
+}
+</pre>
+<div hidden>hidden text</div>
+<div role="navigation">Breadcrumbs</div>
+</div>
+<footer>Copyright</footer>
+</body></html>`
+
+func TestReadHTMLBytes(t *testing.T) {
+	doc, err := ReadHTMLBytes([]byte(testHTML))
+	if err != nil {
+		t.Fatalf("ReadHTMLBytes failed: %v", err)
+	}
+	if doc.Title != "Team Wiki: Setup" || doc.Description != "How to set up the service." {
+		t.Errorf("title %q, description %q", doc.Title, doc.Description)
+	}
+
+	want := "# Setup\n\n" +
+		"Install the service and configure it at the café.\n\n" +
+		"## Steps\n\n" +
+		"1. Download\n2. Run make\n  - on Linux\n  - on macOS\n\n" +
+		"| Key | Value |\n| port | 8080 default |\n\n" +
+		"```\nfunc main() {\n    run()\n}\n```"
+	if doc.Text != want {
+		t.Errorf("text =\n%s\nwant\n%s", doc.Text, want)
+	}
+	for _, dropped := range []string{"color", "tracking", "Home", "Site header", "hidden text", "Breadcrumbs", "Copyright"} {
+		if strings.Contains(doc.Text, dropped) {
+			t.Errorf("boilerplate %q not removed", dropped)
+		}
+	}
+}
+
+func TestReadHTMLBytes_MainContent(t *testing.T) {
+	doc, err := ReadHTMLBytes([]byte(`<body><div>Sidebar links</div><main><p>Article body</p></main></body>`))
+	if err != nil || doc.Text != "Article body" {
+		t.Errorf("main content = %q, %v", doc.Text, err)
+	}
+	doc, err = ReadHTMLBytes([]byte(`<body><p>Related</p><article><p>Story</p></article></body>`))
+	if err != nil || doc.Text != "Story" {
+		t.Errorf("article content = %q, %v", doc.Text, err)
+	}
+
+	// Headers and footers are page chrome outside the main content, but hold
+	// the article heading and byline inside it.
+	doc, err = ReadHTMLBytes([]byte(`<body><header>Site</header><main>
+<header><h1>Release notes</h1><p>By Ops</p></header><p>Body</p><footer>Updated today</footer>
+</main><footer>Copyright</footer></body>`))
+	if want := "# Release notes\n\nBy Ops\n\nBody\n\nUpdated today"; err != nil || doc.Text != want {
+		t.Errorf("main content with header = %q, %v, want %q", doc.Text, err, want)
+	}
+	doc, err = ReadHTMLBytes([]byte(`<body><header>Site</header>
+<article><header><h2>First</h2></header><p>One</p></article>
+<article><header><h2>Second</h2></header><p>Two</p></article></body>`))
+	if want := "## First\n\nOne\n\n## Second\n\nTwo"; err != nil || doc.Text != want {
+		t.Errorf("articles with headers = %q, %v, want %q", doc.Text, err, want)
+	}
+}
+
+func TestExtractSections_HTML(t *testing.T) {
+	sections, metadata, err := extractSections("page.html", "", []byte(testHTML))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata["title"] != "Team Wiki: Setup" || metadata["description"] == "" {
+		t.Errorf("metadata = %v", metadata)
+	}
+	if len(sections) != 2 || sections[0].title != "Setup" || sections[1].title != "Setup > Steps" {
+		t.Errorf("sections = %+v", sections)
+	}
+}
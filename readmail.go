@@ -0,0 +1,387 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html/charset"
+)
+
+// EmailMessage is the readable content of an email message.
+type EmailMessage struct {
+	MessageID string
+	From      string
+	To        []string
+	Cc        []string
+	Date      time.Time // zero if missing or unparsable
+	Subject   string
+	// ThreadID identifies the conversation: the first message referenced, the
+	// message replied to, or the message itself.
+	ThreadID string
+	// Body is the text of the message without quoted replies or signature.
+	// HTML-only messages are rendered as by ReadHTMLBytes.
+	Body string
+}
+
+func init() {
+	registerFormat(&fileFormat{
+		name:       "email",
+		extensions: []string{".eml"},
+		mimeTypes:  []string{"message/rfc822"},
+		sniff:      isEmail,
+		read: func(data []byte) ([]section, map[string]string, error) {
+			msg, err := ReadEmailBytes(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			metadata := map[string]string{}
+			if msg.Subject != "" {
+				metadata["title"] = msg.Subject
+			}
+			return []section{msg.section()}, metadata, nil
+		},
+	})
+	registerFormat(&fileFormat{
+		name:       "mbox",
+		extensions: []string{".mbox", ".mbx"},
+		mimeTypes:  []string{"application/mbox"},
+		sniff: func(data []byte) bool {
+			first, rest, _ := bytes.Cut(data, []byte("\n"))
+			return bytes.HasPrefix(first, []byte("From ")) && isEmail(rest)
+		},
+		read: func(data []byte) ([]section, map[string]string, error) {
+			msgs, err := ReadMboxBytes(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			var sections []section
+			for _, msg := range msgs {
+				sections = append(sections, msg.section())
+			}
+			return sections, nil, nil
+		},
+	})
+}
+
+// ReadEmailBytes reads an email message in RFC 5322 format, decoding
+// MIME-encoded headers, multipart bodies, transfer encodings, and charsets.
+// The plain text part is preferred over HTML; attachments are ignored.
+func ReadEmailBytes(data []byte) (EmailMessage, error) {
+	m, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return EmailMessage{}, err
+	}
+
+	h := m.Header
+	msg := EmailMessage{
+		MessageID: messageID(h.Get("Message-Id")),
+		From:      formatAddresses(h.Get("From")),
+		To:        addressList(h.Get("To")),
+		Cc:        addressList(h.Get("Cc")),
+		Subject:   decodeHeader(h.Get("Subject")),
+	}
+	if d, err := mail.ParseDate(h.Get("Date")); err == nil {
+		msg.Date = d
+	}
+	msg.ThreadID = msg.MessageID
+	if refs := strings.Fields(h.Get("References")); len(refs) > 0 {
+		msg.ThreadID = messageID(refs[0])
+	} else if id := messageID(h.Get("In-Reply-To")); id != "" {
+		msg.ThreadID = id
+	}
+
+	body, err := partText(h.Get("Content-Type"), h.Get("Content-Transfer-Encoding"), m.Body)
+	if err != nil {
+		return EmailMessage{}, err
+	}
+	msg.Body = stripReply(body)
+	return msg, nil
+}
+
+// ReadMboxBytes reads the messages of an mbox file, unescaping ">From " lines.
+// Messages that cannot be parsed are skipped; it is an error if none can.
+func ReadMboxBytes(data []byte) ([]EmailMessage, error) {
+	var msgs []EmailMessage
+	var firstErr error
+	raw := splitMbox(data)
+	for _, m := range raw {
+		msg, err := ReadEmailBytes(m)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		msgs = append(msgs, msg)
+	}
+	if len(msgs) == 0 {
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		return nil, fmt.Errorf("no messages found")
+	}
+	return msgs, nil
+}
+
+// splitMbox splits an mbox file at its "From " separator lines, which start
+// the file or follow a blank line, and drops them.
+func splitMbox(data []byte) [][]byte {
+	var msgs [][]byte
+	var cur []byte
+	blank := true
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for sc.Scan() {
+		line := bytes.TrimSuffix(sc.Bytes(), []byte("\r"))
+		if blank && bytes.HasPrefix(line, []byte("From ")) {
+			if len(bytes.TrimSpace(cur)) > 0 {
+				msgs = append(msgs, cur)
+			}
+			cur = nil
+			continue
+		}
+		if escapedFrom.Match(line) {
+			line = line[1:]
+		}
+		cur = append(append(cur, line...), '\n')
+		blank = len(line) == 0
+	}
+	if len(bytes.TrimSpace(cur)) > 0 {
+		msgs = append(msgs, cur)
+	}
+	return msgs
+}
+
+// escapedFrom matches body lines starting with "From " that mbox writers
+// escaped with ">".
+var escapedFrom = regexp.MustCompile(`^>+From `)
+
+// headerField matches the start of a header field: a name of printable
+// characters other than ":", followed by ":".
+var headerField = regexp.MustCompile(`^[!-9;-~]+:`)
+
+// isEmail reports whether data starts with an email header that has a From
+// field and a Date or Message-ID field.
+func isEmail(data []byte) bool {
+	if !headerField.Match(data) {
+		return false
+	}
+	m, err := mail.ReadMessage(bytes.NewReader(data[:min(len(data), 64*1024)]))
+	if err != nil {
+		return false
+	}
+	return m.Header.Get("From") != "" && (m.Header.Get("Date") != "" || m.Header.Get("Message-Id") != "")
+}
+
+// section returns the message as a section titled with its subject, with its
+// headers as metadata.
+func (msg EmailMessage) section() section {
+	meta := map[string]string{}
+	set := func(key, value string) {
+		if value != "" {
+			meta[key] = value
+		}
+	}
+	set("from", msg.From)
+	set("to", strings.Join(msg.To, ", "))
+	set("cc", strings.Join(msg.Cc, ", "))
+	set("subject", msg.Subject)
+	set("message_id", msg.MessageID)
+	set("thread", msg.ThreadID)
+	if !msg.Date.IsZero() {
+		meta["date"] = msg.Date.Format(time.RFC3339)
+	}
+	return section{title: msg.Subject, text: msg.Body, metadata: meta}
+}
+
+// wordDecoder decodes RFC 2047 encoded words in any charset known to the
+// HTML charset package.
+var wordDecoder = &mime.WordDecoder{
+	CharsetReader: func(label string, r io.Reader) (io.Reader, error) {
+		return charset.NewReaderLabel(label, r)
+	},
+}
+
+// decodeHeader decodes the encoded words in a header value, returning it
+// unchanged if it is malformed.
+func decodeHeader(s string) string {
+	if d, err := wordDecoder.DecodeHeader(s); err == nil {
+		s = d
+	}
+	return collapseSpace(s)
+}
+
+// addressList returns the addresses in a header value as "Name <address>"
+// or "address".
+func addressList(s string) []string {
+	if strings.TrimSpace(s) == "" {
+		return nil
+	}
+	p := mail.AddressParser{WordDecoder: wordDecoder}
+	list, err := p.ParseList(s)
+	if err != nil {
+		return []string{decodeHeader(s)}
+	}
+	addrs := make([]string, len(list))
+	for i, a := range list {
+		addrs[i] = a.Address
+		if a.Name != "" {
+			addrs[i] = a.Name + " <" + a.Address + ">"
+		}
+	}
+	return addrs
+}
+
+// formatAddresses returns the addresses in a header value joined by commas.
+func formatAddresses(s string) string {
+	return strings.Join(addressList(s), ", ")
+}
+
+// messageID returns a Message-ID without its angle brackets.
+func messageID(s string) string {
+	return strings.Trim(strings.TrimSpace(s), "<>")
+}
+
+// partText returns the text of a MIME part with the given content type and
+// transfer encoding. Multipart alternatives prefer text/plain to text/html;
+// other multiparts join the text of their inline parts. Non-text parts and
+// attachments yield no text.
+func partText(contentType, encoding string, body io.Reader) (string, error) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	body = transferDecoder(encoding, body)
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(body, params["boundary"])
+		var plain, html []string
+		for {
+			p, err := mr.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return "", fmt.Errorf("failed to read MIME part: %w", err)
+			}
+			if disp, _, _ := mime.ParseMediaType(p.Header.Get("Content-Disposition")); disp == "attachment" {
+				continue
+			}
+			ct := p.Header.Get("Content-Type")
+			text, err := partText(ct, p.Header.Get("Content-Transfer-Encoding"), p)
+			if err != nil {
+				return "", err
+			}
+			if text == "" {
+				continue
+			}
+			if mt, _, _ := mime.ParseMediaType(ct); mt == "text/html" {
+				html = append(html, text)
+			} else {
+				plain = append(plain, text)
+			}
+		}
+		if mediaType == "multipart/alternative" {
+			if len(plain) > 0 {
+				return plain[len(plain)-1], nil
+			}
+			if len(html) > 0 {
+				return html[len(html)-1], nil
+			}
+			return "", nil
+		}
+		return strings.Join(append(plain, html...), "\n\n"), nil
+	}
+
+	if mediaType != "text/plain" && mediaType != "text/html" {
+		return "", nil
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s part: %w", mediaType, err)
+	}
+	if mediaType == "text/html" {
+		doc, err := readHTML(data, contentType)
+		if err != nil {
+			return "", err
+		}
+		return doc.Text, nil
+	}
+	r, err := charset.NewReaderLabel(params["charset"], bytes.NewReader(data))
+	if err != nil {
+		// An unknown charset is read as it is.
+		r = bytes.NewReader(data)
+	}
+	text, err := io.ReadAll(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode %s charset: %w", params["charset"], err)
+	}
+	return strings.ReplaceAll(string(text), "\r\n", "\n"), nil
+}
+
+// transferDecoder returns a reader decoding body from the given
+// Content-Transfer-Encoding.
+func transferDecoder(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	case "base64":
+		// The decoder skips line breaks.
+		return base64.NewDecoder(base64.StdEncoding, body)
+	}
+	return body
+}
+
+var (
+	// attribution matches the line introducing a quoted reply, such as
+	// "On Mon, 3 Jun 2024, Bob <bob@example.com> wrote:".
+	attribution = regexp.MustCompile(`(?i)(wrote|writes|schrieb|a écrit|escribió)\s*:\s*$`)
+	// originalMessage matches the separator Outlook puts above the message
+	// replied to.
+	originalMessage = regexp.MustCompile(`(?i)^\s*-{2,}\s*original message\s*-{2,}\s*$`)
+	// mobileSignature matches signatures added by mail apps.
+	mobileSignature = regexp.MustCompile(`(?i)^\s*sent from my \w+`)
+)
+
+// stripReply removes quoted replies, the text below an "Original Message"
+// separator, and the signature from the text of a message.
+func stripReply(text string) string {
+	var out []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "--" || originalMessage.MatchString(line) {
+			break
+		}
+		if strings.HasPrefix(strings.TrimSpace(line), ">") {
+			// Drop the attribution above the quote, which mail clients may
+			// wrap over two lines.
+			n := len(out)
+			for n > 0 && strings.TrimSpace(out[n-1]) == "" {
+				n--
+			}
+			if n > 0 && attribution.MatchString(out[n-1]) {
+				n--
+				if n > 0 && !strings.HasPrefix(out[n], "On ") && strings.HasPrefix(out[n-1], "On ") {
+					n--
+				}
+				out = out[:n]
+			}
+			continue
+		}
+		out = append(out, line)
+	}
+	for len(out) > 0 && (strings.TrimSpace(out[len(out)-1]) == "" || mobileSignature.MatchString(out[len(out)-1])) {
+		out = out[:len(out)-1]
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(strings.Join(out, "\n"), "\n\n"))
+}
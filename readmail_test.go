@@ -0,0 +1,183 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// crlf converts the line endings of s to CRLF, as in messages on the wire.
+func crlf(s string) string {
+	return strings.ReplaceAll(s, "\n", "\r\n")
+}
+
+const replyEmail = `From: =?ISO-8859-1?Q?Ren=E9_Dupr=E9?= <rene@example.com>
+To: Team <team@example.com>, bob@example.com
+Cc: "Carol C." <carol@example.com>
+Subject: =?UTF-8?B?UmU6IENhY2hlIGRlY2lzaW9u?=
+Date: Mon, 3 Jun 2024 10:15:00 +0200
+Message-ID: <m2@example.com>
+In-Reply-To: <m1@example.com>
+References: <m0@example.com> <m1@example.com>
+MIME-Version: 1.0
+Content-Type: multipart/alternative; boundary="b1"
+
+--b1
+Content-Type: text/plain; charset=ISO-8859-1
+Content-Transfer-Encoding: quoted-printable
+
+We agreed to keep Redis as the cach=E9 for now.
+
+On Sun, 2 Jun 2024 at 09:00, Bob <bob@example.com>
+wrote:
+> Should we switch to Memcached?
+> It is simpler.
+
+--=20
+Ren=E9
+--b1
+Content-Type: text/html; charset=UTF-8
+
+<p>HTML version</p>
+--b1--
+`
+
+func TestReadEmailBytes(t *testing.T) {
+	msg, err := ReadEmailBytes([]byte(crlf(replyEmail)))
+	if err != nil {
+		t.Fatalf("ReadEmailBytes failed: %v", err)
+	}
+	want := EmailMessage{
+		MessageID: "m2@example.com",
+		From:      "René Dupré <rene@example.com>",
+		To:        []string{"Team <team@example.com>", "bob@example.com"},
+		Cc:        []string{"Carol C. <carol@example.com>"},
+		Date:      time.Date(2024, 6, 3, 10, 15, 0, 0, time.FixedZone("", 2*3600)),
+		Subject:   "Re: Cache decision",
+		ThreadID:  "m0@example.com",
+		Body:      "We agreed to keep Redis as the caché for now.",
+	}
+	if !msg.Date.Equal(want.Date) {
+		t.Errorf("date = %v, want %v", msg.Date, want.Date)
+	}
+	msg.Date = want.Date
+	if !reflect.DeepEqual(msg, want) {
+		t.Errorf("message = %+v\nwant %+v", msg, want)
+	}
+
+	meta := msg.section().metadata
+	if meta["thread"] != "m0@example.com" || meta["date"] != "2024-06-03T10:15:00+02:00" || meta["cc"] != "Carol C. <carol@example.com>" {
+		t.Errorf("metadata = %v", meta)
+	}
+}
+
+func TestReadEmailBytes_HTMLOnly(t *testing.T) {
+	data := `From: alice@example.com
+Date: Tue, 4 Jun 2024 08:00:00 +0000
+Subject: Launch
+Content-Type: multipart/mixed; boundary=outer
+
+--outer
+Content-Type: text/html; charset=windows-1252
+Content-Transfer-Encoding: base64
+
+PGgxPkxhdW5jaDwvaDE+PHA+V2UgbGF1bmNoIG9uIEZyaWRheSCWIHNlZSB5b3UgdGhl
+cmUuPC9wPg==
+--outer
+Content-Type: text/plain
+Content-Disposition: attachment; filename="notes.txt"
+
+attached notes
+--outer--
+`
+	msg, err := ReadEmailBytes([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "# Launch\n\nWe launch on Friday – see you there."; msg.Body != want {
+		t.Errorf("body = %q, want %q", msg.Body, want)
+	}
+	if msg.ThreadID != "" || msg.MessageID != "" {
+		t.Errorf("thread = %q, message ID = %q", msg.ThreadID, msg.MessageID)
+	}
+}
+
+func TestReadMboxBytes(t *testing.T) {
+	data := `From alice@example.com Mon Jun  3 09:00:00 2024
+From: Alice <alice@example.com>
+Date: Mon, 3 Jun 2024 09:00:00 +0000
+Message-ID: <a1@example.com>
+Subject: Release plan
+
+Ship on Friday.
+>From now on, releases are weekly.
+
+From bob@example.com Mon Jun  3 10:00:00 2024
+From: Bob <bob@example.com>
+Date: Mon, 3 Jun 2024 10:00:00 +0000
+Message-ID: <b1@example.com>
+In-Reply-To: <a1@example.com>
+Subject: Re: Release plan
+
+Agreed.
+
+Sent from my iPhone
+`
+	msgs, err := ReadMboxBytes([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(msgs) != 2 {
+		t.Fatalf("got %d messages, want 2", len(msgs))
+	}
+	if msgs[0].Body != "Ship on Friday.\nFrom now on, releases are weekly." || msgs[0].ThreadID != "a1@example.com" {
+		t.Errorf("first message = %+v", msgs[0])
+	}
+	if msgs[1].Body != "Agreed." || msgs[1].ThreadID != "a1@example.com" || msgs[1].From != "Bob <bob@example.com>" {
+		t.Errorf("second message = %+v", msgs[1])
+	}
+
+	sections, _, err := extractSections("", "", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	chunks := chunkSections(sections, 1000, 0)
+	if len(chunks) != 2 || chunks[1].Text != "Re: Release plan\n\nAgreed." || chunks[1].Metadata["from"] != "Bob <bob@example.com>" {
+		t.Errorf("chunks = %+v", chunks)
+	}
+
+	if _, err := ReadMboxBytes([]byte("From nobody\n\n")); err == nil {
+		t.Error("expected error for an mbox without messages")
+	}
+}
+
+func TestStripReply(t *testing.T) {
+	tests := map[string]string{
+		"Yes.\n\nOn Mon, Bob wrote:\n> Ok?\n> Sure?\n":               "Yes.",
+		"Yes.\n> quoted\nMore after the quote.\n":                    "Yes.\nMore after the quote.",
+		"On Monday we decided.\n\n> old\n":                           "On Monday we decided.",
+		"Done.\n\n-----Original Message-----\nFrom: Bob\nOld text\n": "Done.",
+		"Thanks,\nAnn\n-- \nAnn Lee | Ops\n":                         "Thanks,\nAnn",
+		"First\n\n\n\nSecond\n\nSent from my Android\n":              "First\n\nSecond",
+		"Le lun. 3 juin 2024, Bob a écrit :\n> Bonjour\nMerci\n":     "Merci",
+	}
+	for in, want := range tests {
+		if got := stripReply(in); got != want {
+			t.Errorf("stripReply(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestFormatFor_Email(t *testing.T) {
+	eml := []byte("From: a@example.com\nDate: Mon, 3 Jun 2024 09:00:00 +0000\n\nHi\n")
+	if f := formatFor("message", "", eml); f == nil || f.name != "email" {
+		t.Errorf("format = %v, want email", f)
+	}
+	if f := formatFor("notes", "", []byte("From: the desk of Ann\n\nHi\n")); f != plainText {
+		t.Errorf("format = %v, want text", f)
+	}
+	if f := formatFor("archive", "", append([]byte("From a@example.com Mon Jun  3 09:00:00 2024\n"), eml...)); f == nil || f.name != "mbox" {
+		t.Errorf("format = %v, want mbox", f)
+	}
+}
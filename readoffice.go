@@ -0,0 +1,873 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// maxZipEntrySize limits how much of a single archive member is decompressed,
+// guarding against zip bombs.
+const maxZipEntrySize = 256 << 20
+
+// OfficeProperties are the document properties of an Office file.
+type OfficeProperties struct {
+	Title  string
+	Author string
+}
+
+// OfficeDocument is the text of a DOCX or ODT document. Text is Markdown-style:
+// headings start with "#", list items with "-", and table rows are
+// "| a | b |".
+type OfficeDocument struct {
+	OfficeProperties
+	Text string
+}
+
+// Slide is the text of one PPTX slide. Number counts from 1 in presentation
+// order.
+type Slide struct {
+	Number int
+	Title  string
+	Text   string
+}
+
+// Presentation is the text of a PPTX file.
+type Presentation struct {
+	OfficeProperties
+	Slides []Slide
+}
+
+// Sheet is the rows of one XLSX worksheet, with trailing empty cells and
+// empty rows dropped.
+type Sheet struct {
+	Name string
+	Rows [][]string
+}
+
+// Workbook is the contents of an XLSX file.
+type Workbook struct {
+	OfficeProperties
+	Sheets []Sheet
+}
+
+func init() {
+	registerFormat(&fileFormat{
+		name:       "DOCX",
+		extensions: []string{".docx"},
+		mimeTypes:  []string{"application/vnd.openxmlformats-officedocument.wordprocessingml.document"},
+		sniff:      zipContains("word/document.xml"),
+		read: func(data []byte) ([]section, map[string]string, error) {
+			doc, err := ReadDOCXBytes(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			return splitMarkdown(doc.Text), doc.metadata(), nil
+		},
+	})
+	registerFormat(&fileFormat{
+		name:       "ODT",
+		extensions: []string{".odt"},
+		mimeTypes:  []string{"application/vnd.oasis.opendocument.text"},
+		sniff: func(data []byte) bool {
+			// The first member of an ODF package is an uncompressed "mimetype".
+			return bytes.Contains(data[:min(len(data), 128)], []byte("mimetypeapplication/vnd.oasis.opendocument.text"))
+		},
+		read: func(data []byte) ([]section, map[string]string, error) {
+			doc, err := ReadODTBytes(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			return splitMarkdown(doc.Text), doc.metadata(), nil
+		},
+	})
+	registerFormat(&fileFormat{
+		name:       "PPTX",
+		extensions: []string{".pptx"},
+		mimeTypes:  []string{"application/vnd.openxmlformats-officedocument.presentationml.presentation"},
+		sniff:      zipContains("ppt/presentation.xml"),
+		read: func(data []byte) ([]section, map[string]string, error) {
+			p, err := ReadPPTXBytes(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			var sections []section
+			for _, s := range p.Slides {
+				title := fmt.Sprintf("Slide %d", s.Number)
+				if s.Title != "" {
+					title += ": " + s.Title
+				}
+				sections = append(sections, section{
+					title:    title,
+					text:     s.Text,
+					metadata: map[string]string{"slide": strconv.Itoa(s.Number)},
+				})
+			}
+			return sections, p.metadata(), nil
+		},
+	})
+	registerFormat(&fileFormat{
+		name:       "XLSX",
+		extensions: []string{".xlsx"},
+		mimeTypes:  []string{"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"},
+		sniff:      zipContains("xl/workbook.xml"),
+		read: func(data []byte) ([]section, map[string]string, error) {
+			wb, err := ReadXLSXBytes(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			var sections []section
+			for _, s := range wb.Sheets {
+				lines := make([]string, len(s.Rows))
+				for i, row := range s.Rows {
+					lines[i] = tableRow(row)
+				}
+				sections = append(sections, section{
+					title:    s.Name,
+					text:     strings.Join(lines, "\n"),
+					metadata: map[string]string{"sheet": s.Name},
+				})
+			}
+			return sections, wb.metadata(), nil
+		},
+	})
+}
+
+// metadata returns the non-empty properties as "title" and "author"
+// metadata.
+func (p OfficeProperties) metadata() map[string]string {
+	m := make(map[string]string)
+	if p.Title != "" {
+		m["title"] = p.Title
+	}
+	if p.Author != "" {
+		m["author"] = p.Author
+	}
+	return m
+}
+
+// ReadDOCXBytes extracts the paragraphs and tables of a Word document, with
+// heading styles rendered as Markdown headings.
+func ReadDOCXBytes(data []byte) (OfficeDocument, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return OfficeDocument{}, err
+	}
+	body, err := readZipEntry(zr, "word/document.xml")
+	if err != nil {
+		return OfficeDocument{}, err
+	}
+
+	var w officeWriter
+	var style string
+	var outline int
+	list := false
+	d := xml.NewDecoder(bytes.NewReader(body))
+	inText := false
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return OfficeDocument{}, fmt.Errorf("invalid document.xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "p":
+				w.startParagraph()
+				style, outline, list = "", 0, false
+			case "pStyle":
+				style = xmlAttr(t, "val")
+			case "outlineLvl":
+				if n, err := strconv.Atoi(xmlAttr(t, "val")); err == nil && n < 9 {
+					outline = n + 1
+				}
+			case "numPr":
+				list = true
+			case "t":
+				inText = true
+			case "tab":
+				w.write("\t")
+			case "br", "cr":
+				w.write("\n")
+			case "tbl":
+				w.startTable()
+			case "tr":
+				w.startRow()
+			case "tc":
+				w.startCell()
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				level := docxHeadingLevel(style)
+				if level == 0 {
+					level = outline
+				}
+				w.endParagraph(level, list)
+			case "t":
+				inText = false
+			case "tc":
+				w.endCell()
+			case "tr":
+				w.endRow()
+			case "tbl":
+				w.endTable()
+			}
+		case xml.CharData:
+			if inText {
+				w.write(string(t))
+			}
+		}
+	}
+
+	props, err := readCoreProperties(zr, "docProps/core.xml")
+	if err != nil {
+		return OfficeDocument{}, err
+	}
+	return OfficeDocument{OfficeProperties: props, Text: w.text()}, nil
+}
+
+// docxHeadingLevel returns the heading level of a paragraph style ID such as
+// "Heading2" or "Title", or 0 for other styles.
+func docxHeadingLevel(style string) int {
+	s := strings.ToLower(strings.ReplaceAll(style, " ", ""))
+	if s == "title" {
+		return 1
+	}
+	if n, ok := strings.CutPrefix(s, "heading"); ok {
+		if level, err := strconv.Atoi(n); err == nil && level >= 1 && level <= 6 {
+			return level
+		}
+	}
+	return 0
+}
+
+// ReadODTBytes extracts the headings, paragraphs, lists, and tables of an
+// OpenDocument text file.
+func ReadODTBytes(data []byte) (OfficeDocument, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return OfficeDocument{}, err
+	}
+	content, err := readZipEntry(zr, "content.xml")
+	if err != nil {
+		return OfficeDocument{}, err
+	}
+
+	var w officeWriter
+	var headings []int // outline level of each open paragraph or heading, 0 for paragraphs
+	lists := 0
+	skip := 0
+	d := xml.NewDecoder(bytes.NewReader(content))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return OfficeDocument{}, fmt.Errorf("invalid content.xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if skip > 0 {
+				skip++
+				continue
+			}
+			switch t.Name.Local {
+			case "annotation", "note", "tracked-changes":
+				skip = 1
+			case "h":
+				level, _ := strconv.Atoi(xmlAttr(t, "outline-level"))
+				headings = append(headings, max(min(level, 6), 1))
+				w.startParagraph()
+			case "p":
+				headings = append(headings, 0)
+				w.startParagraph()
+			case "list":
+				lists++
+			case "s":
+				n, err := strconv.Atoi(xmlAttr(t, "c"))
+				if err != nil || n < 1 {
+					n = 1
+				}
+				w.write(strings.Repeat(" ", n))
+			case "tab":
+				w.write("\t")
+			case "line-break":
+				w.write("\n")
+			case "table":
+				w.startTable()
+			case "table-row":
+				w.startRow()
+			case "table-cell":
+				w.startCell()
+			}
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			switch t.Name.Local {
+			case "h", "p":
+				if n := len(headings); n > 0 {
+					w.endParagraph(headings[n-1], lists > 0)
+					headings = headings[:n-1]
+				}
+			case "list":
+				lists--
+			case "table-cell":
+				w.endCell()
+			case "table-row":
+				w.endRow()
+			case "table":
+				w.endTable()
+			}
+		case xml.CharData:
+			if skip == 0 && len(headings) > 0 {
+				w.write(string(t))
+			}
+		}
+	}
+
+	var meta struct {
+		Title          string `xml:"meta>title"`
+		Creator        string `xml:"meta>creator"`
+		InitialCreator string `xml:"meta>initial-creator"`
+	}
+	if data, err := readZipEntry(zr, "meta.xml"); err == nil {
+		if err := xml.Unmarshal(data, &meta); err != nil {
+			return OfficeDocument{}, fmt.Errorf("invalid meta.xml: %w", err)
+		}
+	}
+	props := OfficeProperties{Title: strings.TrimSpace(meta.Title), Author: strings.TrimSpace(meta.InitialCreator)}
+	if props.Author == "" {
+		props.Author = strings.TrimSpace(meta.Creator)
+	}
+	return OfficeDocument{OfficeProperties: props, Text: w.text()}, nil
+}
+
+// ReadPPTXBytes extracts the text of each slide of a PowerPoint file in
+// presentation order. The text of title placeholders becomes the slide's
+// title; speaker notes are not included.
+func ReadPPTXBytes(data []byte) (Presentation, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Presentation{}, err
+	}
+	pres, err := readZipEntry(zr, "ppt/presentation.xml")
+	if err != nil {
+		return Presentation{}, err
+	}
+	var list struct {
+		Slides []struct {
+			RID string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sldIdLst>sldId"`
+	}
+	if err := xml.Unmarshal(pres, &list); err != nil {
+		return Presentation{}, fmt.Errorf("invalid presentation.xml: %w", err)
+	}
+	rels, err := readRelationships(zr, "ppt/presentation.xml")
+	if err != nil {
+		return Presentation{}, err
+	}
+
+	var p Presentation
+	for i, s := range list.Slides {
+		part, ok := rels[s.RID]
+		if !ok {
+			return Presentation{}, fmt.Errorf("slide %d: unknown relationship %q", i+1, s.RID)
+		}
+		data, err := readZipEntry(zr, part)
+		if err != nil {
+			return Presentation{}, err
+		}
+		slide, err := readSlide(data)
+		if err != nil {
+			return Presentation{}, fmt.Errorf("slide %d: %w", i+1, err)
+		}
+		slide.Number = i + 1
+		p.Slides = append(p.Slides, slide)
+	}
+
+	if p.OfficeProperties, err = readCoreProperties(zr, "docProps/core.xml"); err != nil {
+		return Presentation{}, err
+	}
+	return p, nil
+}
+
+// readSlide returns the title and text of a slide part.
+func readSlide(data []byte) (Slide, error) {
+	var slide Slide
+	var w officeWriter
+	var title []string
+	isTitle := false
+	inText := false
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return Slide{}, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "sp":
+				isTitle = false
+			case "ph":
+				typ := xmlAttr(t, "type")
+				isTitle = typ == "title" || typ == "ctrTitle"
+			case "p":
+				w.startParagraph()
+			case "t":
+				inText = true
+			case "br":
+				w.write("\n")
+			case "tbl":
+				w.startTable()
+			case "tr":
+				w.startRow()
+			case "tc":
+				w.startCell()
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "p":
+				if isTitle && w.tables == 0 {
+					if s := strings.TrimSpace(w.para.String()); s != "" {
+						title = append(title, s)
+					}
+					w.para.Reset()
+					w.inPara = false
+					continue
+				}
+				w.endParagraph(0, false)
+			case "t":
+				inText = false
+			case "tc":
+				w.endCell()
+			case "tr":
+				w.endRow()
+			case "tbl":
+				w.endTable()
+			}
+		case xml.CharData:
+			if inText {
+				w.write(string(t))
+			}
+		}
+	}
+	slide.Title = strings.Join(title, " ")
+	slide.Text = w.text()
+	return slide, nil
+}
+
+// ReadXLSXBytes extracts the cell values of each worksheet of an Excel file,
+// in workbook order. Numbers and dates are returned as stored, without
+// formatting.
+func ReadXLSXBytes(data []byte) (Workbook, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return Workbook{}, err
+	}
+	book, err := readZipEntry(zr, "xl/workbook.xml")
+	if err != nil {
+		return Workbook{}, err
+	}
+	var sheets struct {
+		Sheets []struct {
+			Name  string `xml:"name,attr"`
+			RID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+			State string `xml:"state,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := xml.Unmarshal(book, &sheets); err != nil {
+		return Workbook{}, fmt.Errorf("invalid workbook.xml: %w", err)
+	}
+	rels, err := readRelationships(zr, "xl/workbook.xml")
+	if err != nil {
+		return Workbook{}, err
+	}
+	shared, err := readSharedStrings(zr)
+	if err != nil {
+		return Workbook{}, err
+	}
+
+	var wb Workbook
+	for _, s := range sheets.Sheets {
+		if s.State == "hidden" || s.State == "veryHidden" {
+			continue
+		}
+		part, ok := rels[s.RID]
+		if !ok {
+			return Workbook{}, fmt.Errorf("sheet %q: unknown relationship %q", s.Name, s.RID)
+		}
+		data, err := readZipEntry(zr, part)
+		if err != nil {
+			return Workbook{}, err
+		}
+		rows, err := readSheetRows(data, shared)
+		if err != nil {
+			return Workbook{}, fmt.Errorf("sheet %q: %w", s.Name, err)
+		}
+		wb.Sheets = append(wb.Sheets, Sheet{Name: s.Name, Rows: rows})
+	}
+
+	if wb.OfficeProperties, err = readCoreProperties(zr, "docProps/core.xml"); err != nil {
+		return Workbook{}, err
+	}
+	return wb, nil
+}
+
+// readSharedStrings returns the shared string table of a workbook, which may
+// be absent.
+func readSharedStrings(zr *zip.Reader) ([]string, error) {
+	data, err := readZipEntry(zr, "xl/sharedStrings.xml")
+	if errors.Is(err, errMissingEntry) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var strs []string
+	var cur strings.Builder
+	inText, phonetic := false, false
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid sharedStrings.xml: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				cur.Reset()
+			case "t":
+				inText = true
+			case "rPh":
+				phonetic = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				strs = append(strs, cur.String())
+			case "t":
+				inText = false
+			case "rPh":
+				phonetic = false
+			}
+		case xml.CharData:
+			if inText && !phonetic {
+				cur.Write(t)
+			}
+		}
+	}
+	return strs, nil
+}
+
+// readSheetRows returns the non-empty rows of a worksheet part, placing each
+// cell in the column given by its reference.
+func readSheetRows(data []byte, shared []string) ([][]string, error) {
+	var rows [][]string
+	var row []string
+	var cellType, cellRef string
+	var value strings.Builder
+	inValue := false
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := d.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				row = nil
+			case "c":
+				cellType, cellRef = xmlAttr(t, "t"), xmlAttr(t, "r")
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				v := value.String()
+				switch cellType {
+				case "s":
+					i, err := strconv.Atoi(strings.TrimSpace(v))
+					if err != nil || i < 0 || i >= len(shared) {
+						return nil, fmt.Errorf("cell %s: invalid shared string index %q", cellRef, v)
+					}
+					v = shared[i]
+				case "b":
+					v = map[string]string{"0": "FALSE", "1": "TRUE"}[v]
+				}
+				col := len(row)
+				if c, ok := columnIndex(cellRef); ok {
+					col = c
+				}
+				for len(row) <= col {
+					row = append(row, "")
+				}
+				row[col] = strings.TrimSpace(v)
+			case "row":
+				for len(row) > 0 && row[len(row)-1] == "" {
+					row = row[:len(row)-1]
+				}
+				if len(row) > 0 {
+					rows = append(rows, row)
+				}
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+	return rows, nil
+}
+
+// columnIndex returns the zero-based column of a cell reference such as
+// "AB12".
+func columnIndex(ref string) (int, bool) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A'+1)
+	}
+	if i == 0 || col > 1<<14 {
+		return 0, false
+	}
+	return col - 1, true
+}
+
+// readCoreProperties returns the title and creator from an Office Open XML
+// core properties part, which may be absent.
+func readCoreProperties(zr *zip.Reader, name string) (OfficeProperties, error) {
+	data, err := readZipEntry(zr, name)
+	if errors.Is(err, errMissingEntry) {
+		return OfficeProperties{}, nil
+	}
+	if err != nil {
+		return OfficeProperties{}, err
+	}
+	var core struct {
+		Title   string `xml:"title"`
+		Creator string `xml:"creator"`
+	}
+	if err := xml.Unmarshal(data, &core); err != nil {
+		return OfficeProperties{}, fmt.Errorf("invalid %s: %w", name, err)
+	}
+	return OfficeProperties{Title: strings.TrimSpace(core.Title), Author: strings.TrimSpace(core.Creator)}, nil
+}
+
+// readRelationships returns the targets of the relationships of an Office
+// Open XML part, keyed by relationship ID, as archive member names.
+func readRelationships(zr *zip.Reader, part string) (map[string]string, error) {
+	dir, base := path.Split(part)
+	data, err := readZipEntry(zr, dir+"_rels/"+base+".rels")
+	if err != nil {
+		return nil, err
+	}
+	var rels struct {
+		Rels []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := xml.Unmarshal(data, &rels); err != nil {
+		return nil, fmt.Errorf("invalid relationships of %s: %w", part, err)
+	}
+	targets := make(map[string]string, len(rels.Rels))
+	for _, r := range rels.Rels {
+		if t, ok := strings.CutPrefix(r.Target, "/"); ok {
+			targets[r.ID] = t
+		} else {
+			targets[r.ID] = path.Join(dir, r.Target)
+		}
+	}
+	return targets, nil
+}
+
+// errMissingEntry is returned by readZipEntry for a member that does not
+// exist.
+var errMissingEntry = errors.New("missing archive member")
+
+// readZipEntry returns the contents of the archive member name, failing if
+// it decompresses to more than maxZipEntrySize bytes.
+func readZipEntry(zr *zip.Reader, name string) ([]byte, error) {
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s", errMissingEntry, name)
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxZipEntrySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(data) > maxZipEntrySize {
+		return nil, fmt.Errorf("%s exceeds %d bytes", name, maxZipEntrySize)
+	}
+	return data, nil
+}
+
+// zipContains returns a sniff function reporting whether data is a ZIP
+// archive with a member called name.
+func zipContains(name string) func([]byte) bool {
+	return func(data []byte) bool {
+		if !bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+			return false
+		}
+		zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return false
+		}
+		return slices.ContainsFunc(zr.File, func(f *zip.File) bool { return f.Name == name })
+	}
+}
+
+// xmlAttr returns the value of the attribute of e with the given local name.
+func xmlAttr(e xml.StartElement, local string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == local {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+// officeWriter collects the paragraphs, list items, and tables of an Office
+// document as Markdown-style text.
+type officeWriter struct {
+	blocks []string
+	para   strings.Builder
+	inPara bool
+
+	tables int             // depth of nested tables; inner tables are flattened
+	rows   []string        // rendered rows of the outermost open table
+	row    []string        // cells of the open row
+	cell   strings.Builder // text of the open cell
+}
+
+// startParagraph begins a paragraph.
+func (w *officeWriter) startParagraph() {
+	w.para.Reset()
+	w.inPara = true
+}
+
+// write appends text to the open paragraph.
+func (w *officeWriter) write(s string) {
+	if w.inPara {
+		w.para.WriteString(s)
+	}
+}
+
+// endParagraph finishes the open paragraph as a heading of the given level,
+// a list item, or plain text. Inside a table it is added to the open cell.
+func (w *officeWriter) endParagraph(heading int, list bool) {
+	text := strings.TrimSpace(w.para.String())
+	w.para.Reset()
+	w.inPara = false
+	if text == "" {
+		return
+	}
+	if w.tables > 0 {
+		if w.cell.Len() > 0 {
+			w.cell.WriteByte(' ')
+		}
+		w.cell.WriteString(collapseSpace(text))
+		return
+	}
+	switch {
+	case heading > 0:
+		w.blocks = append(w.blocks, strings.Repeat("#", heading)+" "+collapseSpace(text))
+	case list:
+		item := "- " + text
+		if n := len(w.blocks); n > 0 && strings.HasPrefix(w.blocks[n-1], "- ") {
+			w.blocks[n-1] += "\n" + item
+		} else {
+			w.blocks = append(w.blocks, item)
+		}
+	default:
+		w.blocks = append(w.blocks, text)
+	}
+}
+
+// startTable begins a table, or a table nested in the open one.
+func (w *officeWriter) startTable() {
+	w.tables++
+	if w.tables == 1 {
+		w.rows = nil
+	}
+}
+
+// startRow begins a row of the outermost table.
+func (w *officeWriter) startRow() {
+	if w.tables == 1 {
+		w.row = nil
+	}
+}
+
+// startCell begins a cell of the outermost table.
+func (w *officeWriter) startCell() {
+	if w.tables == 1 {
+		w.cell.Reset()
+	}
+}
+
+// endCell finishes a cell of the outermost table.
+func (w *officeWriter) endCell() {
+	if w.tables == 1 {
+		w.row = append(w.row, w.cell.String())
+		w.cell.Reset()
+	}
+}
+
+// endRow finishes a row of the outermost table, dropping it if it is empty.
+func (w *officeWriter) endRow() {
+	if w.tables == 1 && slices.ContainsFunc(w.row, func(c string) bool { return c != "" }) {
+		w.rows = append(w.rows, tableRow(w.row))
+	}
+}
+
+// endTable finishes a table, adding the outermost one as a block.
+func (w *officeWriter) endTable() {
+	w.tables--
+	if w.tables == 0 && len(w.rows) > 0 {
+		w.blocks = append(w.blocks, strings.Join(w.rows, "\n"))
+	}
+}
+
+// text returns the collected blocks separated by blank lines.
+func (w *officeWriter) text() string {
+	return strings.Join(w.blocks, "\n\n")
+}
+
+// tableRow renders cells as "| a | b |".
+func tableRow(cells []string) string {
+	return "| " + strings.Join(cells, " | ") + " |"
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// buildZip returns a ZIP archive holding the given members, written in the
+// order given by names.
+func buildZip(t *testing.T, names []string, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range names {
+		method := zip.Deflate
+		if name == "mimetype" {
+			method = zip.Store
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(files[name])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+const testCoreXML = `<?xml version="1.0"?>
+<cp:coreProperties xmlns:cp="http://schemas.openxmlformats.org/package/2006/metadata/core-properties" xmlns:dc="http://purl.org/dc/elements/1.1/">
+<dc:title>Runbook</dc:title><dc:creator>Ops Team</dc:creator></cp:coreProperties>`
+
+func testDOCX(t *testing.T) []byte {
+	doc := `<?xml version="1.0"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:pPr><w:pStyle w:val="Heading1"/></w:pPr><w:r><w:t>Deploy</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">Run the </w:t></w:r><w:r><w:rPr><w:b/></w:rPr><w:t>script</w:t></w:r><w:del><w:r><w:delText>old</w:delText></w:r></w:del></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/></w:numPr></w:pPr><w:r><w:t>first</w:t></w:r></w:p>
+<w:p><w:pPr><w:numPr><w:ilvl w:val="0"/></w:numPr></w:pPr><w:r><w:t>second</w:t></w:r></w:p>
+<w:p><w:pPr><w:pStyle w:val="Heading2"/></w:pPr><w:r><w:t>Ports</w:t></w:r></w:p>
+<w:tbl><w:tr><w:tc><w:p><w:r><w:t>Service</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>Port</w:t></w:r></w:p></w:tc></w:tr>
+<w:tr><w:tc><w:p><w:r><w:t>api</w:t></w:r></w:p></w:tc><w:tc><w:p><w:r><w:t>8080</w:t></w:r></w:p><w:p><w:r><w:t>tcp</w:t></w:r></w:p></w:tc></w:tr></w:tbl>
+</w:body></w:document>`
+	return buildZip(t, []string{"word/document.xml", "docProps/core.xml"},
+		map[string]string{"word/document.xml": doc, "docProps/core.xml": testCoreXML})
+}
+
+func TestReadDOCXBytes(t *testing.T) {
+	doc, err := ReadDOCXBytes(testDOCX(t))
+	if err != nil {
+		t.Fatalf("ReadDOCXBytes failed: %v", err)
+	}
+	want := "# Deploy\n\nRun the script\n\n- first\n- second\n\n## Ports\n\n| Service | Port |\n| api | 8080 tcp |"
+	if doc.Text != want {
+		t.Errorf("text =\n%s\nwant\n%s", doc.Text, want)
+	}
+	if doc.Title != "Runbook" || doc.Author != "Ops Team" {
+		t.Errorf("properties = %+v", doc.OfficeProperties)
+	}
+}
+
+func TestReadODTBytes(t *testing.T) {
+	content := `<?xml version="1.0"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0" xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"><office:body><office:text>
+<text:h text:outline-level="1">Overview</text:h>
+<text:p>Two<text:s text:c="2"/>spaces<office:annotation><text:p>a comment</text:p></office:annotation></text:p>
+<text:list><text:list-item><text:p>item one</text:p></text:list-item><text:list-item><text:p>item two</text:p></text:list-item></text:list>
+<table:table><table:table-row><table:table-cell><text:p>k</text:p></table:table-cell><table:table-cell><text:p>v</text:p></table:table-cell></table:table-row></table:table>
+</office:text></office:body></office:document-content>`
+	meta := `<?xml version="1.0"?>
+<office:document-meta xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:meta="urn:oasis:names:tc:opendocument:xmlns:meta:1.0"><office:meta>
+<dc:title>Handbook</dc:title><meta:initial-creator>Ana</meta:initial-creator><dc:creator>Ben</dc:creator></office:meta></office:document-meta>`
+	data := buildZip(t, []string{"mimetype", "content.xml", "meta.xml"}, map[string]string{
+		"mimetype": "application/vnd.oasis.opendocument.text", "content.xml": content, "meta.xml": meta,
+	})
+
+	doc, err := ReadODTBytes(data)
+	if err != nil {
+		t.Fatalf("ReadODTBytes failed: %v", err)
+	}
+	want := "# Overview\n\nTwo  spaces\n\n- item one\n- item two\n\n| k | v |"
+	if doc.Text != want {
+		t.Errorf("text =\n%s\nwant\n%s", doc.Text, want)
+	}
+	if doc.Title != "Handbook" || doc.Author != "Ana" {
+		t.Errorf("properties = %+v", doc.OfficeProperties)
+	}
+	if f := formatFor("upload", "", data); f == nil || f.name != "ODT" {
+		t.Errorf("sniffed format = %+v, want ODT", f)
+	}
+}
+
+func testPPTX(t *testing.T) []byte {
+	slide := func(title, body string) string {
+		return `<?xml version="1.0"?>
+<p:sld xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:a="http://schemas.openxmlformats.org/drawingml/2006/main"><p:cSld><p:spTree>
+<p:sp><p:nvSpPr><p:nvPr><p:ph type="title"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>` + title + `</a:t></a:r></a:p></p:txBody></p:sp>
+<p:sp><p:nvSpPr><p:nvPr><p:ph idx="1"/></p:nvPr></p:nvSpPr><p:txBody><a:p><a:r><a:t>` + body + `</a:t></a:r></a:p></p:txBody></p:sp>
+</p:spTree></p:cSld></p:sld>`
+	}
+	pres := `<?xml version="1.0"?>
+<p:presentation xmlns:p="http://schemas.openxmlformats.org/presentationml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<p:sldIdLst><p:sldId id="256" r:id="rId3"/><p:sldId id="257" r:id="rId2"/></p:sldIdLst></p:presentation>`
+	rels := `<?xml version="1.0"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId2" Target="slides/slide1.xml"/><Relationship Id="rId3" Target="/ppt/slides/slide2.xml"/></Relationships>`
+	files := map[string]string{
+		"ppt/presentation.xml":            pres,
+		"ppt/_rels/presentation.xml.rels": rels,
+		"ppt/slides/slide1.xml":           slide("Roadmap", "Ship v2"),
+		"ppt/slides/slide2.xml":           slide("Welcome", "Agenda"),
+	}
+	return buildZip(t, []string{"ppt/presentation.xml", "ppt/_rels/presentation.xml.rels", "ppt/slides/slide1.xml", "ppt/slides/slide2.xml"}, files)
+}
+
+func TestReadPPTXBytes(t *testing.T) {
+	p, err := ReadPPTXBytes(testPPTX(t))
+	if err != nil {
+		t.Fatalf("ReadPPTXBytes failed: %v", err)
+	}
+	want := []Slide{{Number: 1, Title: "Welcome", Text: "Agenda"}, {Number: 2, Title: "Roadmap", Text: "Ship v2"}}
+	if !reflect.DeepEqual(p.Slides, want) {
+		t.Errorf("slides = %+v, want %+v", p.Slides, want)
+	}
+
+	sections, _, err := extractSections("deck.pptx", "", testPPTX(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 2 || sections[1].title != "Slide 2: Roadmap" || sections[1].metadata["slide"] != "2" {
+		t.Errorf("sections = %+v", sections)
+	}
+}
+
+func TestReadXLSXBytes(t *testing.T) {
+	book := `<?xml version="1.0"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+<sheets><sheet name="FAQ" sheetId="1" r:id="rId1"/><sheet name="Scratch" sheetId="2" state="hidden" r:id="rId2"/></sheets></workbook>`
+	rels := `<?xml version="1.0"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet1.xml"/><Relationship Id="rId2" Target="worksheets/sheet2.xml"/></Relationships>`
+	shared := `<?xml version="1.0"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>Question</t></si><si><r><t>Ans</t></r><r><t>wer</t></r></si><si><t>How?</t><rPh><t>ignored</t></rPh></si></sst>`
+	sheet := `<?xml version="1.0"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="2"></row>
+<row r="3"><c r="A3" t="s"><v>2</v></c><c r="C3"><v>42</v></c><c r="D3" t="b"><v>1</v></c><c r="E3" t="inlineStr"><is><t>note</t></is></c><c r="F3"/></row>
+</sheetData></worksheet>`
+	names := []string{"xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/sharedStrings.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml"}
+	data := buildZip(t, names, map[string]string{
+		names[0]: book, names[1]: rels, names[2]: shared, names[3]: sheet, names[4]: sheet,
+	})
+
+	wb, err := ReadXLSXBytes(data)
+	if err != nil {
+		t.Fatalf("ReadXLSXBytes failed: %v", err)
+	}
+	want := []Sheet{{Name: "FAQ", Rows: [][]string{{"Question", "Answer"}, {"How?", "", "42", "TRUE", "note"}}}}
+	if !reflect.DeepEqual(wb.Sheets, want) {
+		t.Errorf("sheets = %q, want %q", wb.Sheets, want)
+	}
+
+	mime := "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	sections, _, err := extractSections("export", mime, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sections) != 1 || sections[0].title != "FAQ" || sections[0].text != "| Question | Answer |\n| How? |  | 42 | TRUE | note |" {
+		t.Errorf("sections = %+v", sections)
+	}
+}
+
+func TestColumnIndex(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AB1": 27} {
+		if got, ok := columnIndex(ref); !ok || got != want {
+			t.Errorf("columnIndex(%q) = %d, %v, want %d", ref, got, ok, want)
+		}
+	}
+	if _, ok := columnIndex("12"); ok {
+		t.Error("expected no column for a reference without letters")
+	}
+}
+
+func TestFormatFor(t *testing.T) {
+	docx := testDOCX(t)
+	tests := []struct {
+		name, mime string
+		data       []byte
+		want       string
+	}{
+		{"report.PDF", "", nil, "PDF"},
+		{"notes.md", "", []byte("# x"), "Markdown"},
+		{"notes.txt", "", []byte("<html><body>x</body></html>"), "text"},
+		{"upload", "text/html; charset=utf-8", []byte("x"), "HTML"},
+		{"upload", "", []byte("<!DOCTYPE html><html><body>x</body></html>"), "HTML"},
+		{"upload", "", docx, "DOCX"},
+		{"upload.bin", "", []byte("%PDF-1.7"), "PDF"},
+		{"upload", "", []byte("plain words"), "text"},
+	}
+	for _, tt := range tests {
+		f := formatFor(tt.name, tt.mime, tt.data)
+		if f == nil || f.name != tt.want {
+			t.Errorf("formatFor(%q, %q) = %+v, want %s", tt.name, tt.mime, f, tt.want)
+		}
+	}
+	if f := formatFor("image.bin", "", []byte{0xff, 0xfe, 0x00}); f != nil {
+		t.Errorf("binary data matched format %s", f.name)
+	}
+}
+
+func TestReadZipEntry_Missing(t *testing.T) {
+	data := buildZip(t, []string{"a.xml"}, map[string]string{"a.xml": "<a/>"})
+	if _, err := ReadDOCXBytes(data); err == nil || !strings.Contains(err.Error(), "word/document.xml") {
+		t.Errorf("expected missing member error, got %v", err)
+	}
+}
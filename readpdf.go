@@ -1,11 +1,23 @@
 package main
 
 import (
-	"io"
+	"bytes"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/ledongthuc/pdf"
 )
 
+// PDFDocument is the text of a PDF file, page by page, with the document
+// information from its trailer.
+type PDFDocument struct {
+	Title   string
+	Author  string
+	Created time.Time // zero if unknown
+	Pages   []string  // text of each page; Pages[0] is page 1
+}
+
 // ReadPDF extracts plain text from a PDF file at the given path.
 // It uses github.com/ledongthuc/pdf for parsing.
 func ReadPDF(path string) (string, error) {
@@ -17,15 +29,121 @@ func ReadPDF(path string) (string, error) {
 		return "", err
 	}
 
-	reader, err := r.GetPlainText()
+	doc, err := readPDFDocument(r)
 	if err != nil {
 		return "", err
 	}
+	return doc.Text(), nil
+}
 
-	bytes, err := io.ReadAll(reader)
+// ReadPDFBytes extracts plain text from PDF data held in memory.
+func ReadPDFBytes(data []byte) (string, error) {
+	doc, err := ReadPDFDocument(data)
 	if err != nil {
 		return "", err
 	}
+	return doc.Text(), nil
+}
 
-	return string(bytes), nil
+// ReadPDFDocument extracts the text of each page and the document information
+// of PDF data held in memory.
+func ReadPDFDocument(data []byte) (PDFDocument, error) {
+	r, err := pdf.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return PDFDocument{}, err
+	}
+	return readPDFDocument(r)
+}
+
+// Text returns the text of every page, separated by blank lines.
+func (d PDFDocument) Text() string {
+	return strings.Join(d.Pages, "\n\n")
+}
+
+// readPDFDocument returns the pages and document information of r.
+func readPDFDocument(r *pdf.Reader) (PDFDocument, error) {
+	var doc PDFDocument
+	info := r.Trailer().Key("Info")
+	doc.Title = strings.TrimSpace(info.Key("Title").Text())
+	doc.Author = strings.TrimSpace(info.Key("Author").Text())
+	doc.Created, _ = parsePDFDate(info.Key("CreationDate").Text())
+
+	// Cache fonts across pages so that their character maps are parsed once.
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= r.NumPage(); i++ {
+		p := r.Page(i)
+		for _, name := range p.Fonts() {
+			if _, ok := fonts[name]; !ok {
+				f := p.Font(name)
+				fonts[name] = &f
+			}
+		}
+		text, err := p.GetPlainText(fonts)
+		if err != nil {
+			return PDFDocument{}, fmt.Errorf("page %d: %w", i, err)
+		}
+		doc.Pages = append(doc.Pages, strings.TrimSpace(text))
+	}
+	return doc, nil
+}
+
+// parsePDFDate parses a PDF date string such as "D:20230115093000+01'00'".
+// Fields after the year are optional.
+func parsePDFDate(s string) (time.Time, bool) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "D:")
+	s = strings.ReplaceAll(s, "'", "")
+	digits := len(s)
+	for i, c := range s {
+		if c < '0' || c > '9' {
+			digits = i
+			break
+		}
+	}
+	layouts := map[int]string{4: "2006", 6: "200601", 8: "20060102", 10: "2006010215", 12: "200601021504", 14: "20060102150405"}
+	layout, ok := layouts[digits]
+	if !ok {
+		return time.Time{}, false
+	}
+	switch zone := s[digits:]; {
+	case zone == "" || zone == "Z" || zone == "Z00" || zone == "Z0000":
+		s = s[:digits]
+	case len(zone) == 5 && (zone[0] == '+' || zone[0] == '-'):
+		layout += "-0700"
+	case len(zone) == 3 && (zone[0] == '+' || zone[0] == '-'):
+		layout += "-07"
+	default:
+		return time.Time{}, false
+	}
+	t, err := time.Parse(layout, s)
+	return t, err == nil
+}
+
+// pdfSection returns the text of doc as a single section that records where
+// each page starts, so that chunks can be labelled with their pages.
+func pdfSection(doc PDFDocument) section {
+	var b strings.Builder
+	starts := make([]int, len(doc.Pages))
+	for i, page := range doc.Pages {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		starts[i] = b.Len()
+		b.WriteString(page)
+	}
+	return section{text: b.String(), pageStarts: starts}
+}
+
+// pdfMetadata returns the document information of doc as metadata.
+func pdfMetadata(doc PDFDocument) map[string]string {
+	m := make(map[string]string)
+	if doc.Title != "" {
+		m["title"] = doc.Title
+	}
+	if doc.Author != "" {
+		m["author"] = doc.Author
+	}
+	if !doc.Created.IsZero() {
+		m["created"] = doc.Created.Format(time.RFC3339)
+	}
+	return m
 }
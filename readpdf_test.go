@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// buildPDF returns a PDF with one page per entry of pages, each showing its
+// text in Helvetica, and an Info dictionary with the given entries.
+func buildPDF(t *testing.T, pages []string, info string) []byte {
+	t.Helper()
+	var objs []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objs = append(objs,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< "+info+" >>",
+	)
+	for i, text := range pages {
+		stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		objs = append(objs,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream),
+		)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objs))
+	for i, obj := range objs {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objs)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 4 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objs)+1, xref)
+	return buf.Bytes()
+}
+
+func TestReadPDFDocument(t *testing.T) {
+	data := buildPDF(t, []string{"First page", "Second page"},
+		"/Title (Spec) /Author (Standards Team) /CreationDate (D:20230115093000+01'00')")
+
+	doc, err := ReadPDFDocument(data)
+	if err != nil {
+		t.Fatalf("ReadPDFDocument failed: %v", err)
+	}
+	if !reflect.DeepEqual(doc.Pages, []string{"First page", "Second page"}) {
+		t.Errorf("pages = %q", doc.Pages)
+	}
+	if doc.Title != "Spec" || doc.Author != "Standards Team" {
+		t.Errorf("title %q, author %q", doc.Title, doc.Author)
+	}
+	want := time.Date(2023, 1, 15, 9, 30, 0, 0, time.FixedZone("", 3600))
+	if !doc.Created.Equal(want) {
+		t.Errorf("created = %v, want %v", doc.Created, want)
+	}
+
+	text, err := ReadPDFBytes(data)
+	if err != nil || text != "First page\n\nSecond page" {
+		t.Errorf("ReadPDFBytes = %q, %v", text, err)
+	}
+}
+
+func TestParsePDFDate(t *testing.T) {
+	tests := map[string]time.Time{
+		"D:2021":                  time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC),
+		"D:20210304":              time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+		"D:20210304050607Z":       time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		"D:20210304050607Z00'00'": time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		"20210304050607-05'00'":   time.Date(2021, 3, 4, 5, 6, 7, 0, time.FixedZone("", -5*3600)),
+	}
+	for s, want := range tests {
+		got, ok := parsePDFDate(s)
+		if !ok || !got.Equal(want) {
+			t.Errorf("parsePDFDate(%q) = %v, %v, want %v", s, got, ok, want)
+		}
+	}
+	for _, s := range []string{"", "yesterday", "D:202"} {
+		if _, ok := parsePDFDate(s); ok {
+			t.Errorf("parsePDFDate(%q) succeeded", s)
+		}
+	}
+}
+
+func TestChunkSections_PageRanges(t *testing.T) {
+	pages := []string{strings.Repeat("alpha ", 10), strings.Repeat("beta ", 10), strings.Repeat("gamma ", 10)}
+	for i := range pages {
+		pages[i] = strings.TrimSpace(pages[i])
+	}
+	sections, metadata, err := extractSections("spec.pdf", "", buildPDF(t, pages, "/Title (Spec)"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata["title"] != "Spec" {
+		t.Errorf("metadata = %v", metadata)
+	}
+
+	chunks := chunkSections(sections, 130, 0)
+	var got []string
+	for _, c := range chunks {
+		got = append(got, c.Metadata["pages"])
+	}
+	if !reflect.DeepEqual(got, []string{"1-2", "3"}) {
+		t.Errorf("pages = %q for chunks %q", got, chunks)
+	}
+}
+
+func TestPageRange(t *testing.T) {
+	starts := []int{0, 10, 20}
+	tests := []struct {
+		start, end int
+		want       string
+	}{
+		{0, 5, "1"}, {0, 10, "1"}, {5, 15, "1-2"}, {10, 30, "2-3"}, {25, 26, "3"},
+	}
+	for _, tt := range tests {
+		if got := pageRange(starts, tt.start, tt.end); got != tt.want {
+			t.Errorf("pageRange(%d, %d) = %q, want %q", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestSearchResultCitation(t *testing.T) {
+	tests := []struct {
+		meta map[string]string
+		want string
+	}{
+		{nil, ""},
+		{map[string]string{"source": "/docs/spec.pdf", "pages": "42"}, "spec.pdf p.42"},
+		{map[string]string{"source": "spec.pdf", "pages": "42-43"}, "spec.pdf pp.42-43"},
+		{map[string]string{"source": "notes.md"}, "notes.md"},
+	}
+	for _, tt := range tests {
+		if got := (SearchResult{Metadata: tt.meta}).Citation(); got != tt.want {
+			t.Errorf("Citation(%v) = %q, want %q", tt.meta, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/peterh/liner"
+)
+
+// lineReader reads input lines for the shell, as liner.State does.
+type lineReader interface {
+	Prompt(prompt string) (string, error)
+	AppendHistory(item string)
+}
+
+// shellBlocked lists commands that cannot run inside the shell.
+var shellBlocked = map[string]string{
+	"shell": "already in the shell",
+	"serve": "run the MCP server with 'ydrag serve'",
+}
+
+// shellBuiltins lists the commands handled by the shell itself, in the order
+// shown by its help.
+var shellBuiltins = [][2]string{
+	{"set", "show settings; 'set top_k N' or 'set collection NAME' to change them"},
+	{"help [command]", "list commands, or show the help for a command"},
+	{"exit, quit", "leave the shell"},
+}
+
+// Shell is an interactive session that keeps a RAGSystem loaded and runs
+// registered commands against it, one per input line.
+type Shell struct {
+	rag  *RAGSystem
+	topK int
+	out  io.Writer
+}
+
+// newShell returns a shell over rag that writes its own messages to out.
+func newShell(rag *RAGSystem, out io.Writer) *Shell {
+	return &Shell{rag: rag, topK: 5, out: out}
+}
+
+// Run reads lines from in until end of input or an exit command, executing
+// each one. Errors from commands are reported and do not end the session.
+func (s *Shell) Run(in lineReader) error {
+	for {
+		line, err := in.Prompt(s.prompt())
+		if errors.Is(err, liner.ErrPromptAborted) {
+			continue
+		}
+		if errors.Is(err, io.EOF) {
+			fmt.Fprintln(s.out)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read input: %w", err)
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		in.AppendHistory(line)
+
+		done, err := s.Exec(line)
+		if err != nil {
+			fmt.Fprintf(s.out, "Error: %v\n", err)
+		}
+		if done {
+			return nil
+		}
+	}
+}
+
+// prompt returns the input prompt, naming the collection when it is not the
+// default.
+func (s *Shell) prompt() string {
+	if c := s.rag.Collection(); c != defaultCollection {
+		return fmt.Sprintf("ydrag [%s]> ", c)
+	}
+	return "ydrag> "
+}
+
+// Exec runs a single input line and reports whether the session should end.
+// The query command takes the rest of the line after any flags as its text
+// and the shell's top_k setting as its result count; similar also defaults to
+// that setting.
+func (s *Shell) Exec(line string) (bool, error) {
+	args, err := splitArgs(line)
+	if err != nil || len(args) == 0 {
+		return false, err
+	}
+
+	name, args := args[0], args[1:]
+	switch name {
+	case "exit", "quit":
+		return true, nil
+	case "set":
+		return false, s.set(args)
+	case "help":
+		if len(args) == 0 {
+			s.printHelp()
+			return false, nil
+		}
+	case "query":
+		// Flags, such as --where, are kept apart from the text.
+		if cmd, ok := GetCommand(name); ok {
+			fs := cmd.Flags()
+			fs.SetOutput(io.Discard)
+			if fs.Parse(args) == nil && fs.NArg() > 0 {
+				n := len(args) - fs.NArg()
+				args = append(args[:n:n], strings.Join(fs.Args(), " "), strconv.Itoa(s.topK))
+			}
+		}
+	case "similar":
+		if len(args) == 1 {
+			args = append(args, strconv.Itoa(s.topK))
+		}
+	}
+
+	if reason, ok := shellBlocked[name]; ok {
+		return false, fmt.Errorf("%s is not available in the shell: %s", name, reason)
+	}
+	cmd, ok := GetCommand(name)
+	if !ok {
+		return false, fmt.Errorf("unknown command: %s (type 'help' for a list)", name)
+	}
+	if readsStdin(cmd, args) {
+		return false, fmt.Errorf("%s cannot read standard input in the shell", name)
+	}
+	return false, RunCommand(cmd, s.rag, args)
+}
+
+// readsStdin reports whether cmd would read standard input when run with
+// args, as "add <id> -" and "ingest --stdin" do. In the shell, standard input
+// belongs to the line editor, and reading it to the end would end the
+// session.
+func readsStdin(cmd Command, args []string) bool {
+	fs := cmd.Flags()
+	fs.SetOutput(io.Discard)
+	if fs.Parse(args) != nil {
+		return false
+	}
+	if f := fs.Lookup("stdin"); f != nil && f.Value.String() == "true" {
+		return true
+	}
+	return cmd.Name() == "add" && fs.NArg() == 2 && fs.Arg(1) == "-"
+}
+
+// set changes a shell setting, or prints the current settings when called
+// without arguments.
+func (s *Shell) set(args []string) error {
+	if len(args) == 0 {
+		fmt.Fprintf(s.out, "top_k = %d\ncollection = %s\n", s.topK, s.rag.Collection())
+		return nil
+	}
+	if len(args) != 2 {
+		return fmt.Errorf("usage: set top_k N | set collection NAME")
+	}
+
+	switch args[0] {
+	case "top_k":
+		k, err := strconv.Atoi(args[1])
+		if err != nil || k <= 0 {
+			return fmt.Errorf("top_k must be a positive integer, got %q", args[1])
+		}
+		s.topK = k
+	case "collection":
+		s.rag.SetCollection(args[1])
+	default:
+		return fmt.Errorf("unknown setting %q (use top_k or collection)", args[0])
+	}
+	return nil
+}
+
+// printHelp lists the shell's built-in commands followed by the registered
+// commands available in the shell.
+func (s *Shell) printHelp() {
+	fmt.Fprintln(s.out, "Shell commands:")
+	for _, b := range shellBuiltins {
+		fmt.Fprintf(s.out, "  %-25s %s\n", b[0], b[1])
+	}
+	fmt.Fprintln(s.out, "\nCommands:")
+	for _, name := range s.commandNames() {
+		cmd, _ := GetCommand(name)
+		fmt.Fprintf(s.out, "  %-25s %s\n", cmd.Usage(), cmd.Description())
+	}
+}
+
+// commandNames returns the sorted names of the registered commands that can
+// run in the shell.
+func (s *Shell) commandNames() []string {
+	var names []string
+	for _, cmd := range ListCommands() {
+		if _, blocked := shellBlocked[cmd.Name()]; !blocked && cmd.Name() != "help" {
+			names = append(names, cmd.Name())
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// complete returns completions for the command name at the start of line.
+func (s *Shell) complete(line string) []string {
+	if strings.ContainsAny(line, " \t") {
+		return nil
+	}
+	var out []string
+	for _, name := range append(s.commandNames(), "set", "help", "exit", "quit") {
+		if strings.HasPrefix(name, line) {
+			out = append(out, name)
+		}
+	}
+	return out
+}
+
+// splitArgs splits line into words separated by whitespace. Single and double
+// quotes group words, and a backslash outside single quotes escapes the next
+// character.
+func splitArgs(line string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped = true
+			inWord = true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inWord = true
+		case r == ' ' || r == '\t':
+			if inWord {
+				args = append(args, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash")
+	}
+	if inWord {
+		args = append(args, cur.String())
+	}
+	return args, nil
+}
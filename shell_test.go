@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// scriptedInput feeds fixed lines to a Shell and records its history.
+type scriptedInput struct {
+	lines   []string
+	prompts []string
+	history []string
+}
+
+func (s *scriptedInput) Prompt(prompt string) (string, error) {
+	s.prompts = append(s.prompts, prompt)
+	if len(s.lines) == 0 {
+		return "", io.EOF
+	}
+	line := s.lines[0]
+	s.lines = s.lines[1:]
+	return line, nil
+}
+
+func (s *scriptedInput) AppendHistory(item string) {
+	s.history = append(s.history, item)
+}
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"", nil},
+		{"  list  ", []string{"list"}},
+		{`add doc1 "hello world"`, []string{"add", "doc1", "hello world"}},
+		{`add doc1 'it\'s'`, nil},
+		{`get "" x`, []string{"get", "", "x"}},
+		{`add a\ b c`, []string{"add", "a b", "c"}},
+		{`query "say \"hi\""`, []string{"query", `say "hi"`}},
+	}
+	for _, tt := range tests {
+		got, err := splitArgs(tt.line)
+		if tt.want == nil && tt.line != "" {
+			if err == nil {
+				t.Errorf("splitArgs(%q) = %q, want error", tt.line, got)
+			}
+			continue
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitArgs(%q) = %q, %v; want %q", tt.line, got, err, tt.want)
+		}
+	}
+}
+
+func TestShell_DispatchesCommands(t *testing.T) {
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "doc1", "first document", "", "", []float32{1, 0, 0})
+	insertTestDoc(t, rag, "doc2", "second document", "", "", []float32{0, 1, 0})
+	buf := captureOutput(t, formatJSONL)
+
+	var msgs bytes.Buffer
+	in := &scriptedInput{lines: []string{"get doc1", "", "delete doc2", "list", "exit", "list"}}
+	if err := newShell(rag, &msgs).Run(in); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 output lines, got %q", buf.String())
+	}
+	var doc Document
+	if err := json.Unmarshal([]byte(lines[0]), &doc); err != nil || doc.Content != "first document" {
+		t.Errorf("get output = %s (%v)", lines[0], err)
+	}
+	if !strings.Contains(lines[1], `"deleted"`) || !strings.Contains(lines[2], `"doc1"`) {
+		t.Errorf("unexpected output: %q", lines[1:])
+	}
+	if len(in.history) != 4 || in.history[3] != "exit" {
+		t.Errorf("history = %q", in.history)
+	}
+	if msgs.Len() != 0 {
+		t.Errorf("unexpected messages: %q", msgs.String())
+	}
+}
+
+func TestShell_ErrorsDoNotEndSession(t *testing.T) {
+	rag := newTestRAG(t)
+	captureOutput(t, formatText)
+
+	var msgs bytes.Buffer
+	in := &scriptedInput{lines: []string{"frobnicate", "shell", "serve", "get missing", `add "x`, "set top_k 0"}}
+	if err := newShell(rag, &msgs).Run(in); err != nil {
+		t.Fatalf("Run failed: %v", err)
+	}
+	if n := strings.Count(msgs.String(), "Error:"); n != 6 {
+		t.Errorf("expected 6 errors, got %d:\n%s", n, msgs.String())
+	}
+	if len(in.prompts) != 7 {
+		t.Errorf("expected the shell to prompt until end of input, got %d prompts", len(in.prompts))
+	}
+}
+
+func TestShell_RejectsStdin(t *testing.T) {
+	rag := newTestRAG(t)
+	captureOutput(t, formatText)
+	setInput(t, "piped text")
+
+	for _, line := range []string{"add doc1 -", "ingest --stdin", "ingest -stdin=true"} {
+		if _, err := newShell(rag, io.Discard).Exec(line); err == nil || !strings.Contains(err.Error(), "standard input") {
+			t.Errorf("Exec(%q) err = %v, want standard input error", line, err)
+		}
+	}
+	if data, _ := io.ReadAll(input); string(data) != "piped text" {
+		t.Errorf("standard input was read: %q left", data)
+	}
+}
+
+func TestShell_Set(t *testing.T) {
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "doc1", "default doc", "", "", []float32{1, 0, 0})
+	buf := captureOutput(t, formatJSON)
+
+	var msgs bytes.Buffer
+	sh := newShell(rag, &msgs)
+	for _, line := range []string{"set top_k 3", "set collection notes", "list", "set"} {
+		if _, err := sh.Exec(line); err != nil {
+			t.Fatalf("%q failed: %v", line, err)
+		}
+	}
+	if sh.topK != 3 || rag.Collection() != "notes" {
+		t.Errorf("topK = %d, collection = %q", sh.topK, rag.Collection())
+	}
+	if strings.TrimSpace(buf.String()) != "[]" {
+		t.Errorf("collection notes should be empty, list = %q", buf.String())
+	}
+	if !strings.Contains(msgs.String(), "collection = notes") || !strings.Contains(sh.prompt(), "[notes]") {
+		t.Errorf("settings = %q, prompt = %q", msgs.String(), sh.prompt())
+	}
+}
+
+func TestShell_Complete(t *testing.T) {
+	sh := newShell(newTestRAG(t), io.Discard)
+	got := sh.complete("de")
+	if !reflect.DeepEqual(got, []string{"dedupe", "delete"}) {
+		t.Errorf("complete(de) = %q", got)
+	}
+	if got := sh.complete("se"); !reflect.DeepEqual(got, []string{"set"}) {
+		t.Errorf("complete(se) = %q, want only set (serve is blocked)", got)
+	}
+	if got := sh.complete("get d"); got != nil {
+		t.Errorf("complete after command = %q, want none", got)
+	}
+}
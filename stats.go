@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// KBStats summarizes the whole knowledge base, across all collections.
+// Documents counts files and standalone documents, with all chunks of a file
+// counted once; Chunks counts every stored row.
+type KBStats struct {
+	Model            string         `json:"model,omitempty"`
+	ModelFingerprint string         `json:"model_fingerprint,omitempty"`
+	EmbeddingDim     int            `json:"embedding_dim"`
+	Documents        int            `json:"documents"`
+	Chunks           int            `json:"chunks"`
+	Unembedded       int            `json:"unembedded"`
+	Sources          int            `json:"sources"`
+	ContentLength    LengthStats    `json:"content_length"`
+	Collections      []GroupStats   `json:"collections"`
+	TopSources       []GroupStats   `json:"top_sources"`
+	Largest          []DocumentSize `json:"largest"`
+	Indexes          []string       `json:"indexes"`
+	VectorIndex      bool           `json:"vector_index"`
+	StorageBytes     int64          `json:"storage_bytes"`
+}
+
+// LengthStats describes the distribution of chunk content lengths in
+// characters, and the total size of the stored text. Words are counted as
+// runs of non-space characters, a rough proxy for tokens.
+type LengthStats struct {
+	TotalChars int64   `json:"total_chars"`
+	TotalWords int64   `json:"total_words"`
+	Min        int     `json:"min"`
+	Mean       float64 `json:"mean"`
+	P50        float64 `json:"p50"`
+	P90        float64 `json:"p90"`
+	P99        float64 `json:"p99"`
+	Max        int     `json:"max"`
+}
+
+// GroupStats counts the documents, chunks, and characters in a collection or
+// from a source file.
+type GroupStats struct {
+	Name      string `json:"name"`
+	Documents int    `json:"documents"`
+	Chunks    int    `json:"chunks"`
+	Chars     int64  `json:"chars"`
+}
+
+// DocumentSize is the size of a document, summed over its chunks.
+type DocumentSize struct {
+	ID         string `json:"id"`
+	Collection string `json:"collection"`
+	Chunks     int    `json:"chunks"`
+	Chars      int64  `json:"chars"`
+}
+
+// Stats computes knowledge base statistics with DuckDB aggregate queries,
+// listing at most top sources and largest documents. The model is described
+// from its file and is not loaded.
+func (r *RAGSystem) Stats(top int) (KBStats, error) {
+	stats := KBStats{
+		EmbeddingDim: int(r.embeddingDim),
+		Collections:  []GroupStats{},
+		TopSources:   []GroupStats{},
+		Largest:      []DocumentSize{},
+		Indexes:      []string{},
+	}
+	if r.modelPath != "" {
+		stats.Model = filepath.Base(r.modelPath)
+		if fp, err := modelFingerprint(r.modelPath); err == nil {
+			stats.ModelFingerprint = fp
+		}
+	}
+	if size, ok := databaseSize(r.dbPath); ok {
+		stats.StorageBytes = size
+	}
+	if !r.hasTable() {
+		return stats, nil
+	}
+
+	defer observeDB("stats", time.Now())
+	l := &stats.ContentLength
+	err := r.db.QueryRow(`
+		SELECT
+			count(DISTINCT `+documentKey+`),
+			count(*),
+			count(*) - count(embedding),
+			count(DISTINCT json_extract_string(metadata, '$.source')),
+			coalesce(sum(length(content)), 0),
+			coalesce(sum(CASE WHEN trim(content) = '' THEN 0
+				ELSE len(regexp_split_to_array(trim(content), '\s+')) END), 0),
+			coalesce(min(length(content)), 0),
+			coalesce(avg(length(content)), 0),
+			coalesce(quantile_cont(length(content), 0.5), 0),
+			coalesce(quantile_cont(length(content), 0.9), 0),
+			coalesce(quantile_cont(length(content), 0.99), 0),
+			coalesce(max(length(content)), 0)
+		FROM documents
+	`).Scan(&stats.Documents, &stats.Chunks, &stats.Unembedded, &stats.Sources,
+		&l.TotalChars, &l.TotalWords, &l.Min, &l.Mean, &l.P50, &l.P90, &l.P99, &l.Max)
+	if err != nil {
+		return stats, fmt.Errorf("failed to compute statistics: %w", err)
+	}
+
+	if stats.Collections, err = r.groupStats(`coalesce(collection, '`+defaultCollection+`')`, "name", -1); err != nil {
+		return stats, err
+	}
+	if stats.TopSources, err = r.groupStats(`json_extract_string(metadata, '$.source')`, "chunks DESC, name", top); err != nil {
+		return stats, err
+	}
+	if stats.Largest, err = r.largestDocuments(top); err != nil {
+		return stats, err
+	}
+	if stats.Indexes, stats.VectorIndex, err = r.indexes(); err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// groupStats counts documents, chunks, and characters grouped by the SQL
+// expression key, in the given order, skipping rows where key is NULL. A
+// negative limit returns every group.
+func (r *RAGSystem) groupStats(key, order string, limit int) ([]GroupStats, error) {
+	rows, err := r.db.Query(`
+		SELECT `+key+` AS name, count(DISTINCT `+documentKey+`) AS docs,
+			count(*) AS chunks, sum(length(content))
+		FROM documents
+		WHERE `+key+` IS NOT NULL
+		GROUP BY name
+		ORDER BY `+order+`
+		LIMIT ?
+	`, limitOrAll(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to group documents: %w", err)
+	}
+	defer rows.Close()
+
+	groups := []GroupStats{}
+	for rows.Next() {
+		var g GroupStats
+		if err := rows.Scan(&g.Name, &g.Documents, &g.Chunks, &g.Chars); err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groups = append(groups, g)
+	}
+	return groups, rows.Err()
+}
+
+// largestDocuments returns the limit documents with the most content, in any
+// collection.
+func (r *RAGSystem) largestDocuments(limit int) ([]DocumentSize, error) {
+	rows, err := r.db.Query(`
+		SELECT coalesce(parent_id, id) AS doc, coalesce(collection, '`+defaultCollection+`') AS coll,
+			count(*), sum(length(content)) AS chars
+		FROM documents
+		GROUP BY coll, doc
+		ORDER BY chars DESC, doc, coll
+		LIMIT ?
+	`, limitOrAll(limit))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find largest documents: %w", err)
+	}
+	defer rows.Close()
+
+	docs := []DocumentSize{}
+	for rows.Next() {
+		var d DocumentSize
+		if err := rows.Scan(&d.ID, &d.Collection, &d.Chunks, &d.Chars); err != nil {
+			return nil, fmt.Errorf("failed to scan document size: %w", err)
+		}
+		docs = append(docs, d)
+	}
+	return docs, rows.Err()
+}
+
+// indexes returns the names of the indexes on the documents table and whether
+// one of them is an HNSW vector index. Without one, searches scan every row.
+func (r *RAGSystem) indexes() ([]string, bool, error) {
+	rows, err := r.db.Query(`
+		SELECT index_name, coalesce(sql, '')
+		FROM duckdb_indexes()
+		WHERE table_name = 'documents'
+		ORDER BY index_name
+	`)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	defer rows.Close()
+
+	names := []string{}
+	vector := false
+	for rows.Next() {
+		var name, def string
+		if err := rows.Scan(&name, &def); err != nil {
+			return nil, false, fmt.Errorf("failed to scan index: %w", err)
+		}
+		names = append(names, name)
+		if strings.Contains(strings.ToUpper(def), "HNSW") {
+			vector = true
+		}
+	}
+	return names, vector, rows.Err()
+}
+
+// limitOrAll converts a negative limit into one that returns every row.
+func limitOrAll(limit int) int64 {
+	if limit < 0 {
+		return math.MaxInt32
+	}
+	return int64(limit)
+}
+
+// printStats writes stats in human-readable form.
+func printStats(w io.Writer, s KBStats) {
+	model := s.Model
+	if model == "" {
+		model = "(not configured)"
+	} else if s.ModelFingerprint != "" {
+		model += " (" + s.ModelFingerprint + ")"
+	}
+	storage := "in memory"
+	if s.StorageBytes > 0 {
+		storage = formatBytes(s.StorageBytes)
+	}
+	index := "none (exact search)"
+	if s.VectorIndex {
+		index = "HNSW"
+	}
+
+	fmt.Fprintf(w, "Model:          %s\n", model)
+	fmt.Fprintf(w, "Embedding dim:  %d\n", s.EmbeddingDim)
+	fmt.Fprintf(w, "Storage:        %s\n", storage)
+	fmt.Fprintf(w, "Vector index:   %s\n", index)
+	fmt.Fprintf(w, "Documents:      %d (%d chunks, %d without embeddings)\n", s.Documents, s.Chunks, s.Unembedded)
+	fmt.Fprintf(w, "Sources:        %d\n", s.Sources)
+
+	l := s.ContentLength
+	fmt.Fprintf(w, "\nContent: %d chars, ~%d words\n", l.TotalChars, l.TotalWords)
+	fmt.Fprintf(w, "Chunk length:   min %d  mean %.0f  p50 %.0f  p90 %.0f  p99 %.0f  max %d\n",
+		l.Min, l.Mean, l.P50, l.P90, l.P99, l.Max)
+
+	printGroups := func(title string, groups []GroupStats) {
+		if len(groups) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "\n%s:\n", title)
+		for _, g := range groups {
+			fmt.Fprintf(w, "  %-30s %6d docs %7d chunks %10d chars\n", g.Name, g.Documents, g.Chunks, g.Chars)
+		}
+	}
+	printGroups("Collections", s.Collections)
+	printGroups("Top sources", s.TopSources)
+
+	if len(s.Largest) > 0 {
+		fmt.Fprintln(w, "\nLargest documents:")
+		for _, d := range s.Largest {
+			name := d.ID
+			if d.Collection != defaultCollection {
+				name = fmt.Sprintf("%s [%s]", d.ID, d.Collection)
+			}
+			fmt.Fprintf(w, "  %-30s %7d chunks %10d chars\n", name, d.Chunks, d.Chars)
+		}
+	}
+}
+
+// formatBytes renders n bytes with a binary unit, such as "1.5 MiB".
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
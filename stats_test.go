@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+)
+
+// newStatsTestRAG returns a knowledge base with a two-chunk file, a
+// standalone note, and a document in a second collection.
+func newStatsTestRAG(t *testing.T) *RAGSystem {
+	t.Helper()
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "file#0", "one two three", "file", `{"source":"a.txt"}`, []float32{1, 0, 0})
+	insertTestDoc(t, rag, "file#1", "four five", "file", `{"source":"a.txt"}`, []float32{0, 1, 0})
+	insertTestDoc(t, rag, "note", "a much longer standalone note", "", "", []float32{0, 0, 1})
+	rag.SetCollection("other")
+	if err := rag.insertDocument(context.Background(), "x", "xy", []float32{1, 0, 0}); err != nil {
+		t.Fatal(err)
+	}
+	rag.SetCollection("")
+	return rag
+}
+
+func TestStats(t *testing.T) {
+	rag := newStatsTestRAG(t)
+
+	stats, err := rag.Stats(2)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Documents != 3 || stats.Chunks != 4 || stats.Sources != 1 || stats.EmbeddingDim != 3 {
+		t.Errorf("counts = %+v", stats)
+	}
+	l := stats.ContentLength
+	if l.TotalChars != 13+9+29+2 || l.TotalWords != 3+2+5+1 || l.Min != 2 || l.Max != 29 {
+		t.Errorf("content length = %+v", l)
+	}
+
+	if len(stats.Collections) != 2 || stats.Collections[0].Name != "default" || stats.Collections[0].Documents != 2 ||
+		stats.Collections[0].Chunks != 3 || stats.Collections[1].Name != "other" {
+		t.Errorf("collections = %+v", stats.Collections)
+	}
+	if len(stats.TopSources) != 1 || stats.TopSources[0].Name != "a.txt" || stats.TopSources[0].Chunks != 2 || stats.TopSources[0].Documents != 1 {
+		t.Errorf("sources = %+v", stats.TopSources)
+	}
+	if len(stats.Largest) != 2 || stats.Largest[0].ID != "note" || stats.Largest[1].ID != "file" || stats.Largest[1].Chunks != 2 {
+		t.Errorf("largest = %+v", stats.Largest)
+	}
+	if stats.VectorIndex || stats.StorageBytes != 0 {
+		t.Errorf("index/storage = %v/%d, want none for an in-memory database", stats.VectorIndex, stats.StorageBytes)
+	}
+}
+
+func TestStats_SameIDInTwoCollections(t *testing.T) {
+	rag := newStatsTestRAG(t)
+	ctx := context.Background()
+	rag.SetCollection("other")
+	if err := rag.insertDocument(ctx, "note", "a note in the other collection", []float32{0, 0, 1}); err != nil {
+		t.Fatal(err)
+	}
+	rag.SetCollection("")
+
+	stats, err := rag.Stats(-1)
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Documents != 4 || stats.Chunks != 5 {
+		t.Errorf("documents = %d, chunks = %d; want 4 and 5", stats.Documents, stats.Chunks)
+	}
+	var notes []DocumentSize
+	for _, d := range stats.Largest {
+		if d.ID == "note" {
+			notes = append(notes, d)
+		}
+	}
+	if len(notes) != 2 || notes[0].Collection != "other" || notes[0].Chunks != 1 || notes[1].Collection != "default" || notes[1].Chunks != 1 {
+		t.Errorf("largest notes = %+v, want one per collection", notes)
+	}
+
+	info, err := rag.Info()
+	if err != nil || info.Documents != 4 {
+		t.Errorf("Info documents = %d, %v; want 4", info.Documents, err)
+	}
+}
+
+func TestStats_NoTable(t *testing.T) {
+	rag, err := NewRAGSystem("", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rag.Close()
+
+	stats, err := rag.Stats(10)
+	if err != nil || stats.Documents != 0 || stats.Collections == nil {
+		t.Errorf("Stats on a new database = %+v, %v", stats, err)
+	}
+}
+
+func TestStatsCommand_JSON(t *testing.T) {
+	rag := newStatsTestRAG(t)
+	buf := captureOutput(t, formatJSON)
+
+	if err := RunCommand(&StatsCommand{}, rag, []string{"-top", "1"}); err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	var stats KBStats
+	if err := json.Unmarshal(buf.Bytes(), &stats); err != nil {
+		t.Fatalf("invalid JSON %q: %v", buf.String(), err)
+	}
+	if stats.Chunks != 4 || len(stats.Largest) != 1 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	buf = captureOutput(t, formatText)
+	if err := RunCommand(&StatsCommand{}, rag, nil); err != nil {
+		t.Fatalf("stats failed: %v", err)
+	}
+	for _, want := range []string{"Documents:      3 (4 chunks", "none (exact search)", "a.txt", "in memory"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("text output missing %q:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestKBStatsTool(t *testing.T) {
+	m := NewMCPServer(newStatsTestRAG(t))
+	session := connectInMemory(t, m.readServer)
+
+	res, err := session.CallTool(context.Background(), &mcp.CallToolParams{Name: "kb_stats", Arguments: map[string]any{}})
+	if err != nil {
+		t.Fatalf("kb_stats failed: %v", err)
+	}
+	if res.IsError {
+		t.Fatalf("kb_stats returned an error: %v", res.Content)
+	}
+	data, _ := json.Marshal(res.StructuredContent)
+	var stats KBStats
+	if err := json.Unmarshal(data, &stats); err != nil || stats.Documents != 3 || len(stats.Collections) != 2 {
+		t.Errorf("structured content = %s (%v)", data, err)
+	}
+}
+
+func TestFormatBytes(t *testing.T) {
+	tests := map[int64]string{0: "0 B", 1023: "1023 B", 1536: "1.5 KiB", 5 << 20: "5.0 MiB"}
+	for n, want := range tests {
+		if got := formatBytes(n); got != want {
+			t.Errorf("formatBytes(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans created by ydrag.
+const tracerName = "ydrag"
+
+// tracer returns the ydrag tracer from the global tracer provider. It is looked
+// up on every use so that a provider installed later, by setupTracing or by
+// tests, takes effect. Without one, spans are no-ops.
+func tracer() trace.Tracer {
+	return otel.Tracer(tracerName)
+}
+
+// setupTracing installs a global tracer provider that exports spans over
+// OTLP/HTTP to c.Endpoint, and the W3C trace-context propagator. When no
+// endpoint is configured tracing stays disabled. The returned function flushes
+// and stops the exporter.
+func setupTracing(ctx context.Context, c TracingConfig) (func(context.Context) error, error) {
+	if c.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return nil, fmt.Errorf("trace sample ratio %v is outside [0, 1]", c.SampleRatio)
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(c.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(
+		semconv.SchemaURL,
+		semconv.ServiceName("ydrag"),
+		semconv.ServiceVersion(version),
+	))
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp.Shutdown, nil
+}
+
+// endSpan records err, if any, on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// traceTools is MCP server middleware that wraps every request in a span named
+// after its method, and tool calls in a span named "tools/call <tool>". The
+// span covers argument validation, the tool handler, and result serialization.
+func traceTools(next mcp.MethodHandler) mcp.MethodHandler {
+	return func(ctx context.Context, method string, req mcp.Request) (mcp.Result, error) {
+		name := method
+		attrs := []attribute.KeyValue{attribute.String("mcp.method", method)}
+		if params, ok := req.GetParams().(*mcp.CallToolParamsRaw); ok {
+			name = method + " " + params.Name
+			attrs = append(attrs, attribute.String("mcp.tool", params.Name))
+		}
+		if ss, ok := req.GetSession().(*mcp.ServerSession); ok && ss != nil {
+			attrs = append(attrs, attribute.String("mcp.session", ss.ID()))
+		}
+
+		ctx, span := tracer().Start(ctx, name, trace.WithAttributes(attrs...))
+		result, err := next(ctx, method, req)
+		// A failed call may return a nil *CallToolResult along with err.
+		if res, ok := result.(*mcp.CallToolResult); err == nil && ok && res != nil && res.IsError {
+			span.SetStatus(codes.Error, "tool returned an error result")
+		}
+		endSpan(span, err)
+		return result, err
+	}
+}
+
+// traceHTTP wraps handler so that each request starts a span continuing any
+// trace context sent by the client, making MCP and database spans children of
+// the caller's trace.
+func traceHTTP(handler http.Handler) http.Handler {
+	return otelhttp.NewHandler(handler, "ydrag",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + r.URL.Path
+		}),
+	)
+}
+
+// shutdownTracing flushes pending spans, giving the exporter a few seconds.
+func shutdownTracing(shutdown func(context.Context) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return shutdown(ctx)
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/modelcontextprotocol/go-sdk/mcp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTestTracer installs a tracer provider that records spans in memory and
+// the W3C trace-context propagator, restoring the previous globals when the
+// test ends.
+func newTestTracer(t *testing.T) *tracetest.InMemoryExporter {
+	t.Helper()
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	prevTP, prevProp := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		tp.Shutdown(context.Background())
+		otel.SetTracerProvider(prevTP)
+		otel.SetTextMapPropagator(prevProp)
+	})
+	return exporter
+}
+
+// findSpan returns the first recorded span with the given name.
+func findSpan(t *testing.T, exporter *tracetest.InMemoryExporter, name string) tracetest.SpanStub {
+	t.Helper()
+	spans := exporter.GetSpans()
+	for _, s := range spans {
+		if s.Name == name {
+			return s
+		}
+	}
+	var names []string
+	for _, s := range spans {
+		names = append(names, s.Name)
+	}
+	t.Fatalf("no span named %q, got %v", name, names)
+	return tracetest.SpanStub{}
+}
+
+// spanAttr returns the value of the attribute key on span, or nil.
+func spanAttr(span tracetest.SpanStub, key string) any {
+	for _, kv := range span.Attributes {
+		if string(kv.Key) == key {
+			return kv.Value.AsInterface()
+		}
+	}
+	return nil
+}
+
+func TestSearchByVector_Span(t *testing.T) {
+	exporter := newTestTracer(t)
+	rag := newTestRAG(t)
+	insertTestDoc(t, rag, "a", "alpha", "", "", []float32{1, 0, 0})
+	insertTestDoc(t, rag, "b", "beta", "", "", []float32{0, 1, 0})
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "parent")
+	results, err := rag.searchByVector(ctx, []float32{1, 0, 0}, 1)
+	parent.End()
+	if err != nil {
+		t.Fatalf("searchByVector failed: %v", err)
+	}
+	if len(results) != 1 || results[0].ID != "a" {
+		t.Fatalf("unexpected results: %+v", results)
+	}
+
+	span := findSpan(t, exporter, "duckdb.search")
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Error("duckdb.search span is not a child of the caller's span")
+	}
+	if got := spanAttr(span, "top_k"); got != int64(1) {
+		t.Errorf("top_k = %v, want 1", got)
+	}
+	if got := spanAttr(span, "results"); got != int64(1) {
+		t.Errorf("results = %v, want 1", got)
+	}
+}
+
+func TestTraceTools(t *testing.T) {
+	exporter := newTestTracer(t)
+	m := NewMCPServer(newTestRAG(t))
+	session := connectInMemory(t, m.server)
+	ctx := context.Background()
+
+	if _, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "list_documents"}); err != nil {
+		t.Fatalf("list_documents failed: %v", err)
+	}
+	res, err := session.CallTool(ctx, &mcp.CallToolParams{
+		Name:      "delete_document",
+		Arguments: map[string]any{"id": "missing"},
+	})
+	if err != nil {
+		t.Fatalf("delete_document failed: %v", err)
+	}
+	if !res.IsError {
+		t.Fatal("expected error result for missing document")
+	}
+	if _, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "no_such_tool"}); err == nil {
+		t.Fatal("expected error for unknown tool")
+	}
+
+	span := findSpan(t, exporter, "tools/call list_documents")
+	if got := spanAttr(span, "mcp.tool"); got != "list_documents" {
+		t.Errorf("mcp.tool = %v, want list_documents", got)
+	}
+	if span.Status.Code != codes.Unset {
+		t.Errorf("list_documents status = %v, want Unset", span.Status.Code)
+	}
+	if span := findSpan(t, exporter, "tools/call delete_document"); span.Status.Code != codes.Error {
+		t.Errorf("delete_document status = %v, want Error", span.Status.Code)
+	}
+	if span := findSpan(t, exporter, "tools/call no_such_tool"); span.Status.Code != codes.Error {
+		t.Errorf("no_such_tool status = %v, want Error", span.Status.Code)
+	}
+}
+
+// traceparentTransport sends a fixed W3C traceparent header with every request.
+type traceparentTransport struct {
+	traceparent string
+}
+
+func (tt traceparentTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	req.Header.Set("traceparent", tt.traceparent)
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestTraceHTTP_PropagatesContext(t *testing.T) {
+	exporter := newTestTracer(t)
+	m := NewMCPServer(newTestRAG(t))
+	handler, err := m.httpHandler("streamable-http")
+	if err != nil {
+		t.Fatalf("httpHandler failed: %v", err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	ctx := context.Background()
+	client := mcp.NewClient(&mcp.Implementation{Name: "test", Version: "1.0.0"}, nil)
+	session, err := client.Connect(ctx, &mcp.StreamableClientTransport{
+		Endpoint:   ts.URL,
+		HTTPClient: &http.Client{Transport: traceparentTransport{"00-" + traceID + "-00f067aa0ba902b7-01"}},
+		MaxRetries: -1,
+	}, nil)
+	if err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	if _, err := session.CallTool(ctx, &mcp.CallToolParams{Name: "list_documents"}); err != nil {
+		t.Fatalf("list_documents failed: %v", err)
+	}
+	session.Close()
+
+	span := findSpan(t, exporter, "tools/call list_documents")
+	if got := span.SpanContext.TraceID().String(); got != traceID {
+		t.Errorf("tool span trace ID = %s, want %s", got, traceID)
+	}
+	if span.Parent.SpanID() == (trace.SpanID{}) {
+		t.Error("tool span has no parent HTTP span")
+	}
+	if span := findSpan(t, exporter, "POST /"); span.SpanContext.TraceID().String() != traceID {
+		t.Errorf("HTTP span trace ID = %s, want %s", span.SpanContext.TraceID(), traceID)
+	}
+}
+
+func TestSetupTracing(t *testing.T) {
+	shutdown, err := setupTracing(context.Background(), TracingConfig{})
+	if err != nil {
+		t.Fatalf("setupTracing without endpoint failed: %v", err)
+	}
+	if err := shutdown(context.Background()); err != nil {
+		t.Errorf("no-op shutdown failed: %v", err)
+	}
+
+	if _, err := setupTracing(context.Background(), TracingConfig{Endpoint: "http://localhost:4318", SampleRatio: 2}); err == nil {
+		t.Error("expected error for sample ratio above 1")
+	}
+}